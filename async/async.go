@@ -0,0 +1,337 @@
+// Copyright © 2017 Walter Scheper <walter.scheper@gmail.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package async runs long-running operations in the background, answering
+// the requests that start them with 202 Accepted and a status monitor that
+// clients can poll.
+package async
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/xml"
+	"errors"
+	"log"
+	"net/http"
+	"runtime/debug"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/wfscheper/mtrest/relations"
+	"github.com/wfscheper/mtrest/render"
+	"github.com/wfscheper/mtrest/router"
+	"github.com/wfscheper/mtrest/uritemplate"
+)
+
+// now is replaced by tests.
+var now = time.Now
+
+// State is the stage a job has reached.
+type State string
+
+// The states of a job. Succeeded and Failed are final.
+const (
+	Pending   State = "pending"
+	Running   State = "running"
+	Succeeded State = "succeeded"
+	Failed    State = "failed"
+)
+
+// Job is the state of a long-running operation.
+type Job struct {
+	ID       string
+	State    State
+	Progress int
+	Message  string
+
+	// Result is the URI of the operation's result, once it has succeeded.
+	Result string
+
+	// Problem describes why the operation failed.
+	Problem *render.Problem
+
+	Created time.Time
+	Updated time.Time
+}
+
+// Done reports whether the job has finished.
+func (j Job) Done() bool {
+	return j.State == Succeeded || j.State == Failed
+}
+
+// Reporter records the progress of a job, as a percentage and a message.
+type Reporter func(percent int, message string)
+
+// Func performs a long-running operation, and returns the URI of its
+// result. It should stop early if ctx is cancelled. Errors that are a
+// *render.Problem are reported to the client as is; others are reported as a
+// 500 Internal Server Error problem that does not reveal their message.
+type Func func(ctx context.Context, report Reporter) (result string, err error)
+
+// Manager starts jobs and serves their status monitors. It implements
+// router.Getter and router.Deleter for the monitor, which must be mounted
+// on a named route with an id variable:
+//
+//	jobs := async.NewManager(async.NewMemoryStore(), "job")
+//	mux.Mount("/jobs/{id}", jobs, router.Name("job"))
+type Manager struct {
+	Store Store
+
+	// MonitorRoute is the name of the route the Manager is mounted on.
+	MonitorRoute string
+
+	// RetryAfter is sent to clients polling an unfinished job. It defaults
+	// to one second.
+	RetryAfter time.Duration
+
+	// MaxWait caps the time a client may ask Start to wait for a job to
+	// finish with the Prefer: wait preference.
+	MaxWait time.Duration
+
+	// ErrorLog logs the errors the Store returns while a job runs, and the
+	// panics of jobs. If nil, they are logged with the log package's
+	// standard logger.
+	ErrorLog *log.Logger
+
+	mu      sync.Mutex
+	cancels map[string]context.CancelFunc
+}
+
+// NewManager returns a Manager that keeps jobs in store, and whose monitor
+// is mounted on the route called monitorRoute.
+func NewManager(store Store, monitorRoute string) *Manager {
+	return &Manager{
+		Store:        store,
+		MonitorRoute: monitorRoute,
+		RetryAfter:   time.Second,
+		MaxWait:      10 * time.Second,
+	}
+}
+
+// Start runs fn in the background and answers r with 202 Accepted, a
+// Location header pointing at the job's monitor, and the job's status.
+// If the client sent Prefer: wait and the job finishes in time, the client
+// is sent straight to the result instead, or answered with the job's problem
+// and a monitor link if it failed. A Prefer: respond-async preference is
+// listed in the Preference-Applied header of a 202 Accepted response.
+func (m *Manager) Start(w http.ResponseWriter, r *http.Request, fn Func) (Job, error) {
+	id, err := newID()
+	if err != nil {
+		return Job{}, err
+	}
+	href, err := router.Links(r).Href(m.MonitorRoute, uritemplate.Values{"id": id})
+	if err != nil {
+		return Job{}, err
+	}
+	t := now()
+	job := Job{ID: id, State: Pending, Created: t, Updated: t}
+	if err := m.Store.Create(r.Context(), job); err != nil {
+		return Job{}, err
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	m.mu.Lock()
+	if m.cancels == nil {
+		m.cancels = map[string]context.CancelFunc{}
+	}
+	m.cancels[id] = cancel
+	m.mu.Unlock()
+	done := make(chan Job, 1)
+	go func() {
+		done <- m.run(ctx, job, fn)
+	}()
+
+	if wait, ok := render.Preferences(r).Wait(); ok {
+		if wait > m.MaxWait {
+			wait = m.MaxWait
+		}
+		timer := time.NewTimer(wait)
+		defer timer.Stop()
+		select {
+		case job = <-done:
+			if job.State == Failed {
+				render.Links{{Rel: relations.Monitor, Href: href}}.Header().AddTo(w.Header())
+				return job, render.Error(w, r, job.Problem)
+			}
+			w.Header().Set("Content-Location", href)
+			return job, m.respond(w, r, job, href)
+		case <-timer.C:
+		}
+	}
+	w.Header().Set("Location", href)
+	if render.Preferences(r).RespondAsync() {
+		w.Header().Add("Preference-Applied", "respond-async")
+	}
+	m.retryAfter(w)
+	return job, render.Render(w, r, http.StatusAccepted, m.status(job, href))
+}
+
+// run performs fn, recording its progress and outcome in the Store. A panic
+// in fn fails the job with a 500 Internal Server Error problem.
+func (m *Manager) run(ctx context.Context, job Job, fn Func) Job {
+	defer func() {
+		m.mu.Lock()
+		if cancel, ok := m.cancels[job.ID]; ok {
+			cancel()
+			delete(m.cancels, job.ID)
+		}
+		m.mu.Unlock()
+	}()
+
+	var mu sync.Mutex
+	update := func(f func(*Job)) Job {
+		mu.Lock()
+		defer mu.Unlock()
+		f(&job)
+		job.Updated = now()
+		// A job that is deleted while it runs is no longer in the Store.
+		if err := m.Store.Update(context.Background(), job); err != nil && !errors.Is(err, ErrNotFound) {
+			m.logf("async: updating job %s: %v", job.ID, err)
+		}
+		return job
+	}
+	update(func(j *Job) { j.State = Running })
+	result, err := m.perform(ctx, job.ID, fn, func(percent int, message string) {
+		update(func(j *Job) {
+			if !j.Done() {
+				j.Progress, j.Message = percent, message
+			}
+		})
+	})
+	return update(func(j *Job) {
+		if err != nil {
+			var p *render.Problem
+			if !errors.As(err, &p) {
+				p = render.NewProblem(http.StatusInternalServerError, "")
+			}
+			j.State, j.Problem = Failed, p
+			return
+		}
+		j.State, j.Progress, j.Result = Succeeded, 100, result
+	})
+}
+
+// perform calls fn, turning a panic into an error.
+func (m *Manager) perform(ctx context.Context, id string, fn Func, report Reporter) (result string, err error) {
+	defer func() {
+		if v := recover(); v != nil {
+			m.logf("async: job %s panicked: %v\n%s", id, v, debug.Stack())
+			err = render.NewProblem(http.StatusInternalServerError, "")
+		}
+	}()
+	return fn(ctx, report)
+}
+
+func (m *Manager) logf(format string, args ...interface{}) {
+	if m.ErrorLog != nil {
+		m.ErrorLog.Printf(format, args...)
+	} else {
+		log.Printf(format, args...)
+	}
+}
+
+// Get renders the status of the job named by the id route variable. A job
+// that has succeeded redirects to its result with 303 See Other.
+func (m *Manager) Get(w http.ResponseWriter, r *http.Request) {
+	job, err := m.Store.Get(r.Context(), router.Vars(r)["id"])
+	if err != nil {
+		if errors.Is(err, ErrNotFound) {
+			err = render.NewProblem(http.StatusNotFound, err.Error())
+		}
+		render.Error(w, r, err)
+		return
+	}
+	href := router.Links(r).URL(r.URL)
+	if !job.Done() {
+		m.retryAfter(w)
+	}
+	m.respond(w, r, job, href)
+}
+
+// Delete cancels the job named by the id route variable, if it is still
+// running in this process, and forgets it.
+func (m *Manager) Delete(w http.ResponseWriter, r *http.Request) {
+	id := router.Vars(r)["id"]
+	m.mu.Lock()
+	if cancel, ok := m.cancels[id]; ok {
+		cancel()
+	}
+	m.mu.Unlock()
+	if err := m.Store.Delete(r.Context(), id); err != nil {
+		if errors.Is(err, ErrNotFound) {
+			err = render.NewProblem(http.StatusNotFound, err.Error())
+		}
+		render.Error(w, r, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// respond renders the status of job, redirecting to its result if it has
+// succeeded.
+func (m *Manager) respond(w http.ResponseWriter, r *http.Request, job Job, href string) error {
+	status := http.StatusOK
+	if job.State == Succeeded && job.Result != "" {
+		w.Header().Set("Location", job.Result)
+		status = http.StatusSeeOther
+	}
+	return render.Render(w, r, status, m.status(job, href))
+}
+
+func (m *Manager) retryAfter(w http.ResponseWriter) {
+	if m.RetryAfter > 0 {
+		secs := int((m.RetryAfter + time.Second - 1) / time.Second)
+		w.Header().Set("Retry-After", strconv.Itoa(secs))
+	}
+}
+
+// Status is the body of a status monitor response.
+type Status struct {
+	XMLName  xml.Name        `json:"-" xml:"job" yaml:"-"`
+	Links    render.Links    `json:"_links" xml:"link" yaml:"_links"`
+	ID       string          `json:"id" xml:"id,attr" yaml:"id"`
+	State    State           `json:"state" xml:"state" yaml:"state"`
+	Progress int             `json:"progress" xml:"progress" yaml:"progress"`
+	Message  string          `json:"message,omitempty" xml:"message,omitempty" yaml:"message,omitempty"`
+	Created  time.Time       `json:"created" xml:"created" yaml:"created"`
+	Updated  time.Time       `json:"updated" xml:"updated" yaml:"updated"`
+	Problem  *render.Problem `json:"problem,omitempty" xml:"problem,omitempty" yaml:"problem,omitempty"`
+}
+
+func (m *Manager) status(job Job, href string) *Status {
+	s := &Status{
+		Links:    render.Links{{Rel: relations.Self, Href: href}},
+		ID:       job.ID,
+		State:    job.State,
+		Progress: job.Progress,
+		Message:  job.Message,
+		Created:  job.Created.UTC(),
+		Updated:  job.Updated.UTC(),
+		Problem:  job.Problem,
+	}
+	if job.State == Succeeded && job.Result != "" {
+		s.Links = append(s.Links, render.Link{Rel: relations.Related, Href: job.Result})
+	}
+	return s
+}
+
+func newID() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
@@ -0,0 +1,294 @@
+// Copyright © 2017 Walter Scheper <walter.scheper@gmail.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package async
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/wfscheper/mtrest/render"
+	"github.com/wfscheper/mtrest/router"
+)
+
+type starter struct {
+	jobs *Manager
+	fn   Func
+}
+
+func (s starter) Post(w http.ResponseWriter, r *http.Request) {
+	if _, err := s.jobs.Start(w, r, s.fn); err != nil {
+		render.Error(w, r, err)
+	}
+}
+
+func setup(fn Func) (*router.Router, *Manager) {
+	jobs := NewManager(NewMemoryStore(), "job")
+	mux := router.New()
+	mux.Mount("/jobs/{id}", jobs, router.Name("job"))
+	mux.Mount("/reports", starter{jobs, fn})
+	return mux, jobs
+}
+
+func do(mux http.Handler, method, target string, headers map[string]string) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(method, target, nil)
+	for k, v := range headers {
+		r.Header.Set(k, v)
+	}
+	mux.ServeHTTP(w, r)
+	return w
+}
+
+func decode(t *testing.T, w *httptest.ResponseRecorder) map[string]interface{} {
+	t.Helper()
+	var v map[string]interface{}
+	if err := json.Unmarshal(w.Body.Bytes(), &v); err != nil {
+		t.Fatalf("%q: %s", err, w.Body)
+	}
+	return v
+}
+
+// poll fetches the monitor at href until the job is done.
+func poll(t *testing.T, mux http.Handler, href string) *httptest.ResponseRecorder {
+	t.Helper()
+	for i := 0; i < 1000; i++ {
+		w := do(mux, "GET", href, nil)
+		if state := decode(t, w)["state"]; state != string(Pending) && state != string(Running) {
+			return w
+		}
+		time.Sleep(time.Millisecond)
+	}
+	t.Fatal("job did not finish")
+	return nil
+}
+
+func TestStart(t *testing.T) {
+	step := make(chan struct{})
+	mux, _ := setup(func(ctx context.Context, report Reporter) (string, error) {
+		<-step
+		report(50, "halfway")
+		step <- struct{}{}
+		<-step
+		return "http://example.com/reports/7", nil
+	})
+
+	w := do(mux, "POST", "/reports", map[string]string{"Prefer": "respond-async"})
+	if w.Code != 202 {
+		t.Fatalf("expected 202, got %d: %s", w.Code, w.Body)
+	}
+	href := w.Header().Get("Location")
+	if !strings.HasPrefix(href, "http://example.com/jobs/") {
+		t.Fatalf("expected a monitor Location, got '%s'", href)
+	}
+	if w.Header().Get("Retry-After") != "1" || w.Header().Get("Preference-Applied") != "respond-async" {
+		t.Errorf("unexpected headers: %q", w.Header())
+	}
+	body := decode(t, w)
+	if body["state"] != string(Pending) && body["state"] != string(Running) {
+		t.Errorf("expected a pending job, got %v", body)
+	}
+	if links := body["_links"].(map[string]interface{}); links["self"].(map[string]interface{})["href"] != href {
+		t.Errorf("expected self link to the monitor, got %v", links)
+	}
+
+	step <- struct{}{}
+	<-step
+	w = do(mux, "GET", href, map[string]string{"Accept": "application/xml"})
+	if w.Code != 200 || !strings.Contains(w.Body.String(), "<state>running</state><progress>50</progress><message>halfway</message>") {
+		t.Errorf("expected running job at 50%%, got %d: %s", w.Code, w.Body)
+	}
+	if w.Header().Get("Retry-After") != "1" {
+		t.Errorf("expected Retry-After, got %q", w.Header())
+	}
+
+	step <- struct{}{}
+	w = poll(t, mux, href)
+	if w.Code != 303 || w.Header().Get("Location") != "http://example.com/reports/7" {
+		t.Errorf("expected 303 to the result, got %d %q", w.Code, w.Header())
+	}
+	if body := decode(t, w); body["state"] != string(Succeeded) || body["progress"] != 100.0 {
+		t.Errorf("expected a finished job, got %v", body)
+	}
+	if w.Header().Get("Retry-After") != "" {
+		t.Errorf("expected no Retry-After for a finished job, got %q", w.Header())
+	}
+
+	if w = do(mux, "DELETE", href, nil); w.Code != 204 {
+		t.Errorf("expected 204, got %d", w.Code)
+	}
+	if w = do(mux, "GET", href, nil); w.Code != 404 {
+		t.Errorf("expected 404, got %d", w.Code)
+	}
+}
+
+func TestFailedJob(t *testing.T) {
+	tests := []struct {
+		err      error
+		expected string
+	}{
+		{render.NewProblem(http.StatusConflict, "Report already running"), `{"detail":"Report already running","status":409,"title":"Conflict"}`},
+		{errors.New("disk full"), `{"status":500,"title":"Internal Server Error"}`},
+	}
+	for idx, test := range tests {
+		err := test.err
+		mux, _ := setup(func(ctx context.Context, report Reporter) (string, error) {
+			return "", err
+		})
+		w := do(mux, "POST", "/reports", nil)
+		w = poll(t, mux, w.Header().Get("Location"))
+		if w.Code != 200 {
+			t.Errorf("%d: expected 200, got %d", idx, w.Code)
+		}
+		problem, _ := json.Marshal(decode(t, w)["problem"])
+		if body := decode(t, w); body["state"] != string(Failed) || string(problem) != test.expected {
+			t.Errorf("%d: expected failed job with %s, got %s", idx, test.expected, w.Body)
+		}
+	}
+}
+
+func TestPanickingJob(t *testing.T) {
+	var logged bytes.Buffer
+	mux, jobs := setup(func(ctx context.Context, report Reporter) (string, error) {
+		panic("out of range")
+	})
+	jobs.ErrorLog = log.New(&logged, "", 0)
+	w := do(mux, "POST", "/reports", nil)
+	w = poll(t, mux, w.Header().Get("Location"))
+	problem, _ := json.Marshal(decode(t, w)["problem"])
+	if body := decode(t, w); body["state"] != string(Failed) || string(problem) != `{"status":500,"title":"Internal Server Error"}` {
+		t.Errorf("expected failed job with a 500 problem, got %s", w.Body)
+	}
+	if !strings.Contains(logged.String(), "panicked: out of range") {
+		t.Errorf("expected the panic to be logged, got '%s'", logged.String())
+	}
+}
+
+// failingStore is a Store whose updates fail.
+type failingStore struct {
+	*MemoryStore
+}
+
+func (failingStore) Update(ctx context.Context, job Job) error {
+	return errors.New("connection refused")
+}
+
+func TestUpdateError(t *testing.T) {
+	var logged bytes.Buffer
+	jobs := NewManager(failingStore{NewMemoryStore()}, "job")
+	jobs.ErrorLog = log.New(&logged, "", 0)
+	mux := router.New()
+	mux.Mount("/jobs/{id}", jobs, router.Name("job"))
+	mux.Mount("/reports", starter{jobs, func(ctx context.Context, report Reporter) (string, error) {
+		return "http://example.com/reports/7", nil
+	}})
+	if w := do(mux, "POST", "/reports", map[string]string{"Prefer": "wait=5"}); w.Code != 303 {
+		t.Errorf("expected 303, got %d", w.Code)
+	}
+	if n := strings.Count(logged.String(), ": connection refused\n"); n != 2 {
+		t.Errorf("expected 2 logged errors, got '%s'", logged.String())
+	}
+}
+
+func TestPreferWait(t *testing.T) {
+	mux, _ := setup(func(ctx context.Context, report Reporter) (string, error) {
+		return "http://example.com/reports/7", nil
+	})
+	w := do(mux, "POST", "/reports", map[string]string{"Prefer": "respond-async, wait=5"})
+	if w.Code != 303 || w.Header().Get("Location") != "http://example.com/reports/7" {
+		t.Errorf("expected 303 to the result, got %d %q", w.Code, w.Header())
+	}
+	if !strings.HasPrefix(w.Header().Get("Content-Location"), "http://example.com/jobs/") {
+		t.Errorf("expected Content-Location of the monitor, got %q", w.Header())
+	}
+
+	block := make(chan struct{})
+	defer close(block)
+	mux, jobs := setup(func(ctx context.Context, report Reporter) (string, error) {
+		<-block
+		return "", nil
+	})
+	jobs.MaxWait = 10 * time.Millisecond
+	if w = do(mux, "POST", "/reports", map[string]string{"Prefer": "wait=60"}); w.Code != 202 {
+		t.Errorf("expected 202 after MaxWait, got %d", w.Code)
+	}
+	if applied := w.Header().Get("Preference-Applied"); applied != "" {
+		t.Errorf("expected no Preference-Applied without respond-async, got '%s'", applied)
+	}
+
+	tests := []struct {
+		err    error
+		status int
+	}{
+		{render.NewProblem(http.StatusConflict, "Report exists"), 409},
+		{errors.New("boom"), 500},
+	}
+	for idx, test := range tests {
+		err := test.err
+		mux, _ := setup(func(ctx context.Context, report Reporter) (string, error) {
+			return "", err
+		})
+		w := do(mux, "POST", "/reports", map[string]string{"Prefer": "wait=5"})
+		if w.Code != test.status || w.Header().Get("Content-Type") != "application/problem+json" {
+			t.Errorf("%d: expected a %d problem, got %d %q", idx, test.status, w.Code, w.Header())
+		}
+		if link := w.Header().Get("Link"); !strings.HasPrefix(link, "<http://example.com/jobs/") || !strings.HasSuffix(link, `>; rel="monitor"`) {
+			t.Errorf("%d: expected a monitor link, got '%s'", idx, link)
+		}
+	}
+}
+
+func TestHrefError(t *testing.T) {
+	store := NewMemoryStore()
+	jobs := NewManager(store, "missing")
+	mux := router.New()
+	mux.Mount("/reports", starter{jobs, func(ctx context.Context, report Reporter) (string, error) {
+		return "", nil
+	}})
+	if w := do(mux, "POST", "/reports", nil); w.Code != 500 {
+		t.Errorf("expected 500, got %d", w.Code)
+	}
+	if n := len(store.jobs); n != 0 {
+		t.Errorf("expected no orphaned jobs, got %d", n)
+	}
+}
+
+func TestDeleteCancels(t *testing.T) {
+	cancelled := make(chan struct{})
+	mux, _ := setup(func(ctx context.Context, report Reporter) (string, error) {
+		<-ctx.Done()
+		close(cancelled)
+		return "", ctx.Err()
+	})
+	w := do(mux, "POST", "/reports", nil)
+	if w = do(mux, "DELETE", w.Header().Get("Location"), nil); w.Code != 204 {
+		t.Errorf("expected 204, got %d", w.Code)
+	}
+	select {
+	case <-cancelled:
+	case <-time.After(time.Second):
+		t.Error("expected job to be cancelled")
+	}
+	if w = do(mux, "DELETE", "/jobs/missing", nil); w.Code != 404 {
+		t.Errorf("expected 404, got %d", w.Code)
+	}
+}
@@ -0,0 +1,47 @@
+// Copyright © 2017 Walter Scheper <walter.scheper@gmail.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package async
+
+import (
+	"context"
+	"testing"
+)
+
+func TestMemoryStore(t *testing.T) {
+	ctx := context.Background()
+	s := NewMemoryStore()
+	if _, err := s.Get(ctx, "a"); err != ErrNotFound {
+		t.Errorf("expected ErrNotFound, got %v", err)
+	}
+	if err := s.Update(ctx, Job{ID: "a"}); err != ErrNotFound {
+		t.Errorf("expected ErrNotFound, got %v", err)
+	}
+	if err := s.Create(ctx, Job{ID: "a", State: Pending}); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Update(ctx, Job{ID: "a", State: Running, Progress: 50}); err != nil {
+		t.Fatal(err)
+	}
+	job, err := s.Get(ctx, "a")
+	if err != nil || job.State != Running || job.Progress != 50 {
+		t.Errorf("expected running job at 50%%, got %+v and %v", job, err)
+	}
+	if err := s.Delete(ctx, "a"); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Delete(ctx, "a"); err != ErrNotFound {
+		t.Errorf("expected ErrNotFound, got %v", err)
+	}
+}
@@ -0,0 +1,405 @@
+// Copyright © 2017 Walter Scheper <walter.scheper@gmail.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package batch serves batch requests: multipart/mixed bodies of
+// application/http requests that are dispatched in-process, and answered
+// together in one multipart/mixed response.
+package batch
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/textproto"
+
+	"github.com/wfscheper/mtrest"
+	"github.com/wfscheper/mtrest/multipart"
+	"github.com/wfscheper/mtrest/render"
+)
+
+// ApplicationHTTP is the RFC 7230 media type for HTTP messages, which make
+// up the parts of a batch.
+var ApplicationHTTP = mtrest.MediaType{Type: "application", SubType: "http", Params: map[string]string{}, Weight: 1.0}
+
+// Limits of a new Batch.
+const (
+	DefaultMaxRequests = 100
+	DefaultMaxBytes    = 10 << 20
+)
+
+// TxFunc begins a transaction for the requests of a changeset. It returns
+// the context to dispatch them with, through which handlers join the
+// transaction, and a function that ends it: committing it if commit is true,
+// or rolling it back.
+type TxFunc func(ctx context.Context) (txCtx context.Context, end func(commit bool) error, err error)
+
+// Batch is a resource that serves batch requests. Each part of a batch is an
+// application/http request, or a changeset: a nested multipart/mixed part of
+// requests that succeed or fail together. Requests are dispatched in order,
+// each with its own headers and so its own content negotiation, and the
+// response holds their responses in the same order. A part's Content-ID is
+// repeated on the part of its response.
+type Batch struct {
+	// Handler dispatches the requests of a batch, usually the Router the
+	// batch resource is mounted on.
+	Handler http.Handler
+
+	// MaxRequests is the most requests a batch may hold, counting those in
+	// changesets. Larger batches are refused with 413 Payload Too Large.
+	MaxRequests int
+
+	// MaxBytes is the largest batch request body, in bytes. Larger bodies
+	// are refused with 413 Payload Too Large. Zero means no limit.
+	MaxBytes int64
+
+	// MaxDepth is how deep batches may nest: at most MaxDepth levels of
+	// batches may be nested inside the outermost one. Deeper batches, and
+	// any nested batch when MaxDepth is zero, are refused with 400 Bad
+	// Request. The requests of nested batches count against the
+	// MaxRequests of the outermost one.
+	MaxDepth int
+
+	// Inherit lists the headers that each request of a batch inherits from
+	// the batch request, unless it sets them itself.
+	Inherit []string
+
+	// Begin, if not nil, begins a transaction for each changeset. A
+	// changeset stops at the first request that fails, with a status of 400
+	// or more, and its transaction is rolled back. Without Begin, the
+	// requests of a failed changeset that came before the failure are not
+	// undone. Either way, a failed changeset is answered with the failed
+	// response alone.
+	Begin TxFunc
+}
+
+// New returns a Batch that dispatches requests to h. Requests inherit the
+// Authorization header of the batch.
+func New(h http.Handler) *Batch {
+	return &Batch{
+		Handler:     h,
+		MaxRequests: DefaultMaxRequests,
+		MaxBytes:    DefaultMaxBytes,
+		Inherit:     []string{"Authorization"},
+	}
+}
+
+// Consumes implements router.Consumer.
+func (b *Batch) Consumes(method string) []*mtrest.MediaType {
+	return []*mtrest.MediaType{&multipart.MultipartMixed}
+}
+
+// request is a request of a batch, or a changeset of requests.
+type request struct {
+	contentID string
+	req       *http.Request
+	changeset []request
+}
+
+// response is the response to a request, or the responses to a changeset.
+type response struct {
+	contentID string
+	rec       *recorder
+	changeset []response
+}
+
+// stateKey is the context key of the state of the batch that dispatched a
+// request.
+type stateKey struct{}
+
+// state is carried through the context of the requests of a batch, so that
+// a nested batch knows how deep it is and how many requests came before it.
+type state struct {
+	depth       int
+	maxRequests int
+	requests    *int
+}
+
+// Post serves a batch request.
+func (b *Batch) Post(w http.ResponseWriter, r *http.Request) {
+	st, nested := r.Context().Value(stateKey{}).(*state)
+	if !nested {
+		st = &state{maxRequests: b.MaxRequests, requests: new(int)}
+	} else if st.depth > b.MaxDepth {
+		detail := "Batches cannot be nested"
+		if b.MaxDepth > 0 {
+			detail = fmt.Sprintf("Batches cannot be nested more than %d deep", b.MaxDepth)
+		}
+		render.Error(w, r, render.NewProblem(http.StatusBadRequest, detail))
+		return
+	}
+
+	var body *limitedBody
+	if b.MaxBytes > 0 {
+		body = &limitedBody{ReadCloser: http.MaxBytesReader(w, r.Body, b.MaxBytes), limit: b.MaxBytes}
+		r.Body = body
+	}
+	rd, err := multipart.NewReader(r)
+	var reqs []request
+	if err == nil {
+		reqs, err = b.read(r, rd, st, false)
+	}
+	if body != nil && body.exceeded {
+		err = render.NewProblem(http.StatusRequestEntityTooLarge, fmt.Sprintf("A batch may be at most %d bytes", b.MaxBytes))
+	}
+	if err != nil {
+		render.Error(w, r, err)
+		return
+	}
+
+	ctx := context.WithValue(r.Context(), stateKey{}, &state{depth: st.depth + 1, maxRequests: st.maxRequests, requests: st.requests})
+
+	responses := make([]response, len(reqs))
+	for i, req := range reqs {
+		if req.changeset != nil {
+			responses[i] = b.changeset(ctx, req)
+		} else {
+			responses[i] = response{contentID: req.contentID, rec: b.dispatch(ctx, req.req)}
+		}
+	}
+
+	mw, err := multipart.NewWriter(w, &multipart.MultipartMixed)
+	if err != nil {
+		render.Error(w, r, err)
+		return
+	}
+	w.Header().Set("Content-Type", mw.MediaType().String())
+	w.WriteHeader(http.StatusOK)
+	for _, resp := range responses {
+		if err := resp.write(mw); err != nil {
+			return
+		}
+	}
+	mw.Close()
+}
+
+// read reads the requests of a batch from rd, counting them in st. Nested
+// multipart/mixed parts are changesets, unless they are already in one.
+func (b *Batch) read(r *http.Request, rd *multipart.Reader, st *state, inChangeset bool) ([]request, error) {
+	parts, err := rd.ReadAll()
+	if err != nil {
+		return nil, err
+	}
+	defer parts.RemoveAll()
+
+	reqs := make([]request, len(parts))
+	for i, p := range parts {
+		reqs[i].contentID = p.Header.Get("Content-Id")
+		switch {
+		case !inChangeset && multipart.MultipartMixed.Includes(p.MediaType):
+			f, err := p.Open()
+			if err != nil {
+				return nil, err
+			}
+			nested, err := multipart.NewBodyReader(p.MediaType.String(), f)
+			if err == nil {
+				reqs[i].changeset, err = b.read(r, nested, st, true)
+			}
+			f.Close()
+			if err != nil {
+				return nil, err
+			}
+			if reqs[i].changeset == nil {
+				reqs[i].changeset = []request{}
+			}
+		case ApplicationHTTP.Includes(p.MediaType):
+			if *st.requests++; st.maxRequests > 0 && *st.requests > st.maxRequests {
+				return nil, render.NewProblem(http.StatusRequestEntityTooLarge, fmt.Sprintf("A batch may hold at most %d requests", st.maxRequests))
+			}
+			if reqs[i].req, err = b.parse(r, p); err != nil {
+				return nil, render.NewProblem(http.StatusBadRequest, fmt.Sprintf("Invalid request in part %d: %s", i, err))
+			}
+		default:
+			return nil, render.NewProblem(http.StatusBadRequest, fmt.Sprintf("Part %d is not an application/http request", i))
+		}
+	}
+	return reqs, nil
+}
+
+// parse parses the request in p, a part of the batch request r. The request
+// is read in full, so that the part can be removed.
+func (b *Batch) parse(r *http.Request, p *multipart.Part) (*http.Request, error) {
+	f, err := p.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	req, err := http.ReadRequest(bufio.NewReader(f))
+	if err != nil {
+		return nil, err
+	}
+	body, err := ioutil.ReadAll(req.Body)
+	if err != nil {
+		return nil, err
+	}
+	req.Body = ioutil.NopCloser(bytes.NewReader(body))
+	req.ContentLength = int64(len(body))
+
+	if req.Host == "" {
+		req.Host = r.Host
+	}
+	req.RemoteAddr = r.RemoteAddr
+	req.TLS = r.TLS
+	for _, k := range b.Inherit {
+		k = textproto.CanonicalMIMEHeaderKey(k)
+		if _, ok := req.Header[k]; !ok && len(r.Header[k]) > 0 {
+			req.Header[k] = r.Header[k]
+		}
+	}
+	return req, nil
+}
+
+// changeset dispatches the requests of a changeset.
+func (b *Batch) changeset(ctx context.Context, req request) response {
+	resp := response{contentID: req.contentID, changeset: []response{}}
+	if len(req.changeset) == 0 {
+		return resp
+	}
+	var end func(bool) error
+	if b.Begin != nil {
+		var err error
+		if ctx, end, err = b.Begin(ctx); err != nil {
+			return failure(req, err)
+		}
+	}
+	var failed *response
+	for _, r := range req.changeset {
+		rec := b.dispatch(ctx, r.req)
+		resp.changeset = append(resp.changeset, response{contentID: r.contentID, rec: rec})
+		if rec.status >= 400 {
+			failed = &resp.changeset[len(resp.changeset)-1]
+			break
+		}
+	}
+	if end != nil {
+		if err := end(failed == nil); err != nil && failed == nil {
+			return failure(req, err)
+		}
+	}
+	if failed != nil {
+		return response{contentID: req.contentID, changeset: []response{*failed}}
+	}
+	return resp
+}
+
+// failure answers the changeset req with err, as a problem, when its
+// transaction cannot begin or commit.
+func failure(req request, err error) response {
+	rec := newRecorder()
+	render.Error(rec, req.changeset[0].req, err)
+	return response{contentID: req.contentID, changeset: []response{{rec: rec}}}
+}
+
+func (b *Batch) dispatch(ctx context.Context, req *http.Request) *recorder {
+	rec := newRecorder()
+	b.Handler.ServeHTTP(rec, req.WithContext(ctx))
+	if rec.status == 0 {
+		rec.status = http.StatusOK
+	}
+	if req.Method == http.MethodHead {
+		rec.body.Reset()
+	}
+	return rec
+}
+
+// write writes resp as a part of a batch response.
+func (resp response) write(mw *multipart.Writer) error {
+	header := textproto.MIMEHeader{}
+	if resp.contentID != "" {
+		header.Set("Content-Id", resp.contentID)
+	}
+	var body bytes.Buffer
+	if resp.rec != nil {
+		header.Set("Content-Type", "application/http")
+		hr := &http.Response{
+			StatusCode:    resp.rec.status,
+			ProtoMajor:    1,
+			ProtoMinor:    1,
+			Header:        resp.rec.header,
+			ContentLength: int64(resp.rec.body.Len()),
+		}
+		if resp.rec.body.Len() > 0 {
+			hr.Body = ioutil.NopCloser(&resp.rec.body)
+		}
+		if err := hr.Write(&body); err != nil {
+			return err
+		}
+	} else {
+		nested, err := multipart.NewWriter(&body, &multipart.MultipartMixed)
+		if err != nil {
+			return err
+		}
+		for _, r := range resp.changeset {
+			if err := r.write(nested); err != nil {
+				return err
+			}
+		}
+		nested.Close()
+		header.Set("Content-Type", nested.MediaType().String())
+	}
+	part, err := mw.CreatePart(header)
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(part, &body)
+	return err
+}
+
+// limitedBody is a body read through http.MaxBytesReader, which notes
+// whether the body was larger than its limit.
+type limitedBody struct {
+	io.ReadCloser
+	limit    int64
+	read     int64
+	exceeded bool
+}
+
+func (lb *limitedBody) Read(p []byte) (int, error) {
+	n, err := lb.ReadCloser.Read(p)
+	lb.read += int64(n)
+	if err != nil && err != io.EOF && lb.read >= lb.limit {
+		lb.exceeded = true
+	}
+	return n, err
+}
+
+// recorder is an http.ResponseWriter that records a response to a request
+// of a batch.
+type recorder struct {
+	header http.Header
+	status int
+	body   bytes.Buffer
+}
+
+func newRecorder() *recorder {
+	return &recorder{header: http.Header{}}
+}
+
+func (rec *recorder) Header() http.Header {
+	return rec.header
+}
+
+func (rec *recorder) WriteHeader(status int) {
+	if rec.status == 0 {
+		rec.status = status
+	}
+}
+
+func (rec *recorder) Write(p []byte) (int, error) {
+	rec.WriteHeader(http.StatusOK)
+	return rec.body.Write(p)
+}
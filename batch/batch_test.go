@@ -0,0 +1,372 @@
+// Copyright © 2017 Walter Scheper <walter.scheper@gmail.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package batch
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/wfscheper/mtrest/multipart"
+	"github.com/wfscheper/mtrest/render"
+	"github.com/wfscheper/mtrest/router"
+)
+
+type txKey struct{}
+
+type widget struct {
+	Name string `json:"name" xml:"name" yaml:"name"`
+}
+
+type widgets struct {
+	store map[string]widget
+}
+
+func (res widgets) Get(w http.ResponseWriter, r *http.Request) {
+	wdg, ok := res.store[router.Vars(r)["id"]]
+	if !ok {
+		render.Error(w, r, render.NewProblem(http.StatusNotFound, ""))
+		return
+	}
+	w.Header().Set("X-Authorization", r.Header.Get("Authorization"))
+	render.Render(w, r, http.StatusOK, wdg)
+}
+
+func (res widgets) Put(w http.ResponseWriter, r *http.Request) {
+	var wdg widget
+	if err := router.Bind(r, &wdg); err != nil || wdg.Name == "" {
+		render.Error(w, r, render.NewProblem(http.StatusBadRequest, "Invalid widget"))
+		return
+	}
+	if tx, ok := r.Context().Value(txKey{}).(map[string]widget); ok {
+		tx[router.Vars(r)["id"]] = wdg
+	} else {
+		res.store[router.Vars(r)["id"]] = wdg
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func setup() (*router.Router, *Batch, map[string]widget) {
+	store := map[string]widget{"1": {"a"}}
+	mux := router.New()
+	mux.Mount("/widgets/{id}", widgets{store})
+	b := New(mux)
+	mux.Mount("/batch", b)
+	return mux, b, store
+}
+
+// message joins lines with CRLF, as HTTP messages and multipart bodies
+// require.
+func message(lines ...string) string {
+	return strings.Join(lines, "\r\n")
+}
+
+func post(mux http.Handler, body string) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest("POST", "http://example.com/batch", strings.NewReader(body))
+	r.Header.Set("Content-Type", "multipart/mixed; boundary=batch")
+	r.Header.Set("Authorization", "Bearer abc")
+	mux.ServeHTTP(w, r)
+	return w
+}
+
+type result struct {
+	contentID string
+	status    int
+	header    http.Header
+	body      string
+	changeset []result
+}
+
+func read(t *testing.T, contentType string, body string) []result {
+	t.Helper()
+	rd, err := multipart.NewBodyReader(contentType, strings.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	parts, err := rd.ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var results []result
+	for _, p := range parts {
+		f, _ := p.Open()
+		data, _ := ioutil.ReadAll(f)
+		res := result{contentID: p.Header.Get("Content-Id")}
+		if p.MediaType.Type == "multipart" {
+			res.changeset = read(t, p.MediaType.String(), string(data))
+		} else {
+			resp, err := http.ReadResponse(bufio.NewReader(strings.NewReader(string(data))), nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			b, _ := ioutil.ReadAll(resp.Body)
+			res.status, res.header, res.body = resp.StatusCode, resp.Header, string(b)
+		}
+		results = append(results, res)
+	}
+	return results
+}
+
+func TestBatch(t *testing.T) {
+	mux, _, store := setup()
+	w := post(mux, message(
+		"--batch",
+		"Content-Type: application/http",
+		"Content-ID: <1>",
+		"",
+		"GET /widgets/1 HTTP/1.1",
+		"Accept: application/xml",
+		"",
+		"",
+		"--batch",
+		"Content-Type: application/http",
+		"Content-ID: <2>",
+		"",
+		"PUT /widgets/2 HTTP/1.1",
+		"Content-Type: application/json",
+		"Content-Length: 12",
+		"",
+		`{"name":"b"}`,
+		"--batch",
+		"Content-Type: application/http",
+		"",
+		"GET /widgets/2 HTTP/1.1",
+		"Authorization: Bearer xyz",
+		"",
+		"",
+		"--batch",
+		"Content-Type: application/http",
+		"",
+		"GET /widgets/3 HTTP/1.1",
+		"",
+		"",
+		"--batch--",
+		"",
+	))
+	if w.Code != 200 {
+		t.Fatalf("expected 200, got %d: %s", w.Code, w.Body)
+	}
+	results := read(t, w.Header().Get("Content-Type"), w.Body.String())
+	expected := []struct {
+		contentID, contentType, body, auth string
+		status                             int
+	}{
+		{"<1>", "application/xml", "<widget><name>a</name></widget>", "Bearer abc", 200},
+		{"<2>", "", "", "", 204},
+		{"", "application/json", `{"name":"b"}`, "Bearer xyz", 200},
+		{"", "application/problem+json", `{"status":404,"title":"Not Found"}`, "", 404},
+	}
+	if len(results) != len(expected) {
+		t.Fatalf("expected %d responses, got %d", len(expected), len(results))
+	}
+	for idx, e := range expected {
+		res := results[idx]
+		if res.contentID != e.contentID || res.status != e.status || res.header.Get("Content-Type") != e.contentType || res.body != e.body || res.header.Get("X-Authorization") != e.auth {
+			t.Errorf("%d: expected %+v, got %+v", idx, e, res)
+		}
+	}
+	if store["2"].Name != "b" {
+		t.Errorf("expected widget 2 to be stored, got %v", store)
+	}
+}
+
+// changeset returns a batch of one changeset that puts widgets called
+// names, numbered from 1.
+func changeset(names ...string) string {
+	lines := []string{"--batch", "Content-Type: multipart/mixed; boundary=cs", ""}
+	for i, name := range names {
+		body := `{"name":"` + name + `"}`
+		lines = append(lines,
+			"--cs",
+			"Content-Type: application/http",
+			"Content-ID: <"+strconv.Itoa(i+1)+">",
+			"",
+			"PUT /widgets/"+strconv.Itoa(i+1)+" HTTP/1.1",
+			"Content-Type: application/json",
+			"Content-Length: "+strconv.Itoa(len(body)),
+			"",
+			body,
+		)
+	}
+	return message(append(lines, "--cs--", "", "--batch--", "")...)
+}
+
+func TestChangeset(t *testing.T) {
+	tests := []struct {
+		names     []string
+		commit    bool
+		statuses  []int
+		contentID string
+		stored    string
+	}{
+		{[]string{"x", "y"}, true, []int{204, 204}, "<2>", "x"},
+		{[]string{"x", "", "z"}, false, []int{400}, "<2>", "a"},
+	}
+	for idx, test := range tests {
+		mux, b, store := setup()
+		var committed []bool
+		b.Begin = func(ctx context.Context) (context.Context, func(bool) error, error) {
+			tx := map[string]widget{}
+			return context.WithValue(ctx, txKey{}, tx), func(commit bool) error {
+				committed = append(committed, commit)
+				if commit {
+					for k, v := range tx {
+						store[k] = v
+					}
+				}
+				return nil
+			}, nil
+		}
+		w := post(mux, changeset(test.names...))
+		if w.Code != 200 {
+			t.Fatalf("%d: expected 200, got %d: %s", idx, w.Code, w.Body)
+		}
+		results := read(t, w.Header().Get("Content-Type"), w.Body.String())
+		if len(results) != 1 || len(results[0].changeset) != len(test.statuses) {
+			t.Fatalf("%d: expected one changeset of %d responses, got %+v", idx, len(test.statuses), results)
+		}
+		for i, status := range test.statuses {
+			if results[0].changeset[i].status != status {
+				t.Errorf("%d: expected %d, got %d", idx, status, results[0].changeset[i].status)
+			}
+		}
+		if results[0].changeset[len(test.statuses)-1].contentID != test.contentID {
+			t.Errorf("%d: expected Content-ID %s, got %+v", idx, test.contentID, results[0].changeset)
+		}
+		if len(committed) != 1 || committed[0] != test.commit {
+			t.Errorf("%d: expected commit %t, got %v", idx, test.commit, committed)
+		}
+		if store["1"].Name != test.stored {
+			t.Errorf("%d: expected widget 1 to be '%s', got %v", idx, test.stored, store)
+		}
+	}
+}
+
+func TestChangesetBeginFails(t *testing.T) {
+	mux, b, _ := setup()
+	b.Begin = func(ctx context.Context) (context.Context, func(bool) error, error) {
+		return nil, nil, errors.New("database unavailable")
+	}
+	w := post(mux, changeset("x"))
+	results := read(t, w.Header().Get("Content-Type"), w.Body.String())
+	if len(results) != 1 || len(results[0].changeset) != 1 || results[0].changeset[0].status != 500 {
+		t.Errorf("expected a changeset of a 500 response, got %+v", results)
+	}
+}
+
+func TestBatchErrors(t *testing.T) {
+	request := message("--batch", "Content-Type: application/http", "", "GET /widgets/1 HTTP/1.1", "", "", "")
+	tests := []struct {
+		title  string
+		body   string
+		status int
+		detail string
+	}{
+		{"Too many requests", strings.Repeat(request, 3) + "--batch--", 413, "A batch may hold at most 2 requests"},
+		{"Too many requests in changesets", message("--batch", "Content-Type: multipart/mixed; boundary=cs", "", strings.ReplaceAll(strings.Repeat(request, 3), "--batch", "--cs")+"--cs--", "--batch--"), 413, "A batch may hold at most 2 requests"},
+		{"Not application/http", message("--batch", "Content-Type: application/json", "", "{}", "--batch--"), 400, "Part 0 is not an application/http request"},
+		{"Malformed request", message("--batch", "Content-Type: application/http", "", "GET", "--batch--"), 400, "Invalid request in part 0: malformed HTTP request"},
+		{"Nested changeset", message("--batch", "Content-Type: multipart/mixed; boundary=cs", "", "--cs", "Content-Type: multipart/mixed; boundary=x", "", "--x--", "--cs--", "--batch--"), 400, "Part 0 is not an application/http request"},
+		{"Unterminated part", message("--batch", "Content-Type: application/http", "", "GET /widgets/1 HTTP/1.1", ""), 400, `"status":400`},
+	}
+	for idx, test := range tests {
+		mux, b, _ := setup()
+		b.MaxRequests = 2
+		w := post(mux, test.body)
+		if w.Code != test.status || !strings.Contains(w.Body.String(), test.detail) {
+			t.Errorf("%d: (%s) expected %d with '%s', got %d: %s", idx, test.title, test.status, test.detail, w.Code, w.Body)
+		}
+	}
+
+	mux, b, _ := setup()
+	b.MaxBytes = 64
+	if w := post(mux, strings.Repeat(request, 3)+"--batch--"); w.Code != 413 || !strings.Contains(w.Body.String(), "A batch may be at most 64 bytes") {
+		t.Errorf("expected 413 for a batch over MaxBytes, got %d: %s", w.Code, w.Body)
+	}
+
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, httptest.NewRequest("OPTIONS", "/batch", nil))
+	if actual := w.Header().Get("Accept-Post"); actual != "multipart/mixed" {
+		t.Errorf("expected Accept-Post 'multipart/mixed', got '%s'", actual)
+	}
+}
+
+// nested returns a batch, with the given boundary, that nests depth batches
+// of which the innermost holds count requests.
+func nested(boundary string, depth, count int) string {
+	if depth == 0 {
+		return strings.Repeat(message("--"+boundary, "Content-Type: application/http", "", "GET /widgets/1 HTTP/1.1", "", "", ""), count) + "--" + boundary + "--"
+	}
+	inner := nested("inner"+strconv.Itoa(depth), depth-1, count)
+	return message(
+		"--"+boundary,
+		"Content-Type: application/http",
+		"",
+		"POST /batch HTTP/1.1",
+		"Content-Type: multipart/mixed; boundary=inner"+strconv.Itoa(depth),
+		"Content-Length: "+strconv.Itoa(len(inner)),
+		"",
+		inner,
+		"--"+boundary+"--",
+		"",
+	)
+}
+
+func TestNestedBatch(t *testing.T) {
+	tests := []struct {
+		maxDepth int
+		depth    int
+		count    int
+		status   int
+		detail   string
+	}{
+		{0, 1, 1, 400, "Batches cannot be nested"},
+		{1, 1, 1, 200, ""},
+		{1, 1, 2, 413, "A batch may hold at most 2 requests"},
+		{1, 2, 1, 400, "Batches cannot be nested more than 1 deep"},
+		{2, 2, 1, 413, "A batch may hold at most 2 requests"},
+	}
+	for idx, test := range tests {
+		mux, b, _ := setup()
+		b.MaxDepth = test.maxDepth
+		b.MaxRequests = 2
+		w := post(mux, nested("batch", test.depth, test.count))
+		if w.Code != 200 {
+			t.Fatalf("%d: expected 200, got %d: %s", idx, w.Code, w.Body)
+		}
+		// Walk down to the response of the innermost batch.
+		results := read(t, w.Header().Get("Content-Type"), w.Body.String())
+		for i := 1; i < test.depth && len(results) == 1 && results[0].status == 200; i++ {
+			results = read(t, results[0].header.Get("Content-Type"), results[0].body)
+		}
+		if len(results) != 1 || results[0].status != test.status || !strings.Contains(results[0].body, test.detail) {
+			t.Errorf("%d: expected %d with '%s', got %+v", idx, test.status, test.detail, results)
+			continue
+		}
+		if test.status == 200 {
+			inner := read(t, results[0].header.Get("Content-Type"), results[0].body)
+			if len(inner) != test.count || inner[0].status != 200 || inner[0].body != `{"name":"a"}` {
+				t.Errorf("%d: expected %d widget responses, got %+v", idx, test.count, inner)
+			}
+		}
+	}
+}
@@ -0,0 +1,509 @@
+// Copyright © 2017 Walter Scheper <walter.scheper@gmail.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cache
+
+import (
+	"bytes"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/wfscheper/mtrest"
+	"github.com/wfscheper/mtrest/conditional"
+	"github.com/wfscheper/mtrest/headers"
+	"github.com/wfscheper/mtrest/render"
+)
+
+// notModifiedHeaders are the stored headers sent with a 304 Not Modified
+// response, per RFC 7232, section 4.1.
+var notModifiedHeaders = []string{"Cache-Control", "Content-Location", "Date", "ETag", "Expires", "Last-Modified", "Vary"}
+
+// streamedTypes are the media types of responses that are written as a
+// stream, which the cache passes through without keeping a copy.
+var streamedTypes = []*mtrest.MediaType{&render.TextEventStream, &render.ApplicationNDJSON, &render.ApplicationJSONSeq}
+
+// Cache is an in-process HTTP cache that sits in front of a handler, and
+// follows the rules of RFC 7234 for a shared cache. Responses are stored by
+// method and URL, and by the request headers named in their Vary header.
+// Accept headers are compared after normalizing them, so clients that
+// accept the same media types share entries however they write the header.
+type Cache struct {
+	// MaxEntries is the most responses the cache holds. Zero means no
+	// limit.
+	MaxEntries int
+
+	mu      sync.Mutex
+	entries map[string][]*entry
+	size    int
+}
+
+// New returns an empty Cache.
+func New() *Cache {
+	return &Cache{}
+}
+
+type entry struct {
+	status int
+	header http.Header
+	body   []byte
+
+	// vary holds the normalized request headers named by the response's
+	// Vary header.
+	vary map[string]string
+
+	// stored is when the response was received or last revalidated, and
+	// lifetime is how long it is fresh for after that.
+	stored   time.Time
+	lifetime time.Duration
+	control  headers.CacheControl
+}
+
+// Handler returns a handler that answers GET and HEAD requests from the
+// cache where it can, revalidating stale responses with their ETag or
+// Last-Modified date, and otherwise passes them to h. Successful responses
+// to other methods invalidate the entries for their URL. Streamed
+// responses, which have a streaming media type such as text/event-stream or
+// are flushed by h, are never stored.
+func (c *Cache) Handler(h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		key := r.Host + r.URL.RequestURI()
+		if r.Method != http.MethodGet && r.Method != http.MethodHead {
+			buf := &buffer{w: w}
+			h.ServeHTTP(buf, r)
+			if buf.status == 0 || buf.status < 400 {
+				c.invalidate(key)
+			}
+			return
+		}
+
+		reqCC := requestCacheControl(r)
+		e := c.lookup(key, r)
+		switch {
+		case e != nil && e.usable(reqCC):
+			c.serve(w, r, e)
+			return
+		case reqCC.OnlyIfCached:
+			render.Error(w, r, render.NewProblem(http.StatusGatewayTimeout, "The response is not cached"))
+			return
+		case e != nil && e.validator() != "":
+			e, buf := c.revalidate(h, r, key, e)
+			if e != nil {
+				c.serve(w, r, e)
+			} else {
+				buf.writeTo(w)
+			}
+			return
+		}
+
+		// A miss: pass the response straight to the client, keeping a copy
+		// of responses to GET. Responses to HEAD have no body to store.
+		buf := &buffer{w: w, record: r.Method == http.MethodGet}
+		h.ServeHTTP(buf, r)
+		if buf.record && !reqCC.NoStore {
+			c.store(key, r, buf)
+		}
+	})
+}
+
+func requestCacheControl(r *http.Request) headers.CacheControl {
+	cc, _ := headers.NewCacheControl(strings.Join(r.Header["Cache-Control"], ","))
+	if len(r.Header["Cache-Control"]) == 0 && strings.Contains(strings.ToLower(r.Header.Get("Pragma")), "no-cache") {
+		cc.NoCache = true
+	}
+	return cc
+}
+
+func (c *Cache) lookup(key string, r *http.Request) *entry {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, e := range c.entries[key] {
+		if e.matches(r) {
+			return e
+		}
+	}
+	return nil
+}
+
+func (c *Cache) invalidate(key string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.size -= len(c.entries[key])
+	delete(c.entries, key)
+}
+
+// remove drops e from the entries for key.
+func (c *Cache) remove(key string, e *entry) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	variants := c.entries[key]
+	for i, v := range variants {
+		if v == e {
+			c.entries[key] = append(variants[:i:i], variants[i+1:]...)
+			c.size--
+			return
+		}
+	}
+}
+
+// store keeps the response in buf, if it may be stored.
+func (c *Cache) store(key string, r *http.Request, buf *buffer) {
+	status := buf.status
+	if status == 0 {
+		status = http.StatusOK
+	}
+	if buf.streamed || !cacheableStatus[status] || headers.Varies(http.Header{"Vary": buf.Header()["Vary"]}, "*") {
+		return
+	}
+	cc, err := headers.NewCacheControl(strings.Join(buf.Header()["Cache-Control"], ","))
+	if err != nil || cc.NoStore || (cc.Private && len(cc.PrivateFields) == 0) {
+		return
+	}
+	if r.Header.Get("Authorization") != "" && !cc.Public && cc.SMaxAge == nil && !cc.MustRevalidate {
+		return
+	}
+	// Cookies are usually meant for one client, so they are only shared
+	// when the response says it is fit for a shared cache.
+	if len(buf.Header()["Set-Cookie"]) > 0 && !cc.Public && cc.SMaxAge == nil {
+		return
+	}
+	e := &entry{
+		status:  status,
+		header:  buf.Header().Clone(),
+		body:    append([]byte(nil), buf.body.Bytes()...),
+		stored:  now(),
+		control: cc,
+	}
+	e.lifetime = e.freshnessLifetime()
+	if e.lifetime <= 0 && e.validator() == "" {
+		return
+	}
+	e.vary = map[string]string{}
+	for _, field := range varyFields(e.header) {
+		e.vary[field] = normalize(field, r.Header[field])
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.entries == nil {
+		c.entries = map[string][]*entry{}
+	}
+	variants := c.entries[key]
+	for i, v := range variants {
+		if v.sameVariant(e) {
+			variants = append(variants[:i], variants[i+1:]...)
+			c.size--
+			break
+		}
+	}
+	c.entries[key] = append(variants, e)
+	c.size++
+	for c.MaxEntries > 0 && c.size > c.MaxEntries {
+		c.evict()
+	}
+}
+
+// evict removes the entry that was stored longest ago.
+func (c *Cache) evict() {
+	var oldestKey string
+	oldest := -1
+	for key, variants := range c.entries {
+		for i, e := range variants {
+			if oldest < 0 || e.stored.Before(c.entries[oldestKey][oldest].stored) {
+				oldestKey, oldest = key, i
+			}
+		}
+	}
+	variants := c.entries[oldestKey]
+	if len(variants) == 1 {
+		delete(c.entries, oldestKey)
+	} else {
+		c.entries[oldestKey] = append(variants[:oldest], variants[oldest+1:]...)
+	}
+	c.size--
+}
+
+// revalidate asks h whether e is still current, and returns the refreshed
+// entry. If h sends a new response instead, it replaces e and is returned
+// in a buffer.
+func (c *Cache) revalidate(h http.Handler, r *http.Request, key string, e *entry) (*entry, *buffer) {
+	up := r.Clone(r.Context())
+	up.Method = http.MethodGet
+	for _, k := range []string{"If-Match", "If-None-Match", "If-Modified-Since", "If-Unmodified-Since", "If-Range"} {
+		up.Header.Del(k)
+	}
+	if etag := e.header.Get("ETag"); etag != "" {
+		up.Header.Set("If-None-Match", etag)
+	} else {
+		up.Header.Set("If-Modified-Since", e.header.Get("Last-Modified"))
+	}
+
+	buf := &buffer{record: true}
+	h.ServeHTTP(buf, up)
+	if buf.status != http.StatusNotModified {
+		c.remove(key, e)
+		c.store(key, r, buf)
+		return nil, buf
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	refreshed := *e
+	refreshed.header = e.header.Clone()
+	for _, k := range []string{"Cache-Control", "Date", "ETag", "Expires", "Last-Modified"} {
+		if v, ok := buf.Header()[http.CanonicalHeaderKey(k)]; ok {
+			refreshed.header[http.CanonicalHeaderKey(k)] = v
+		}
+	}
+	refreshed.control, _ = headers.NewCacheControl(strings.Join(refreshed.header["Cache-Control"], ","))
+	refreshed.stored = now()
+	refreshed.lifetime = refreshed.freshnessLifetime()
+	for i, v := range c.entries[key] {
+		if v == e {
+			c.entries[key][i] = &refreshed
+		}
+	}
+	return &refreshed, nil
+}
+
+// serve writes e, or a 304 Not Modified response if the client's own
+// preconditions allow it.
+func (c *Cache) serve(w http.ResponseWriter, r *http.Request, e *entry) {
+	v := conditional.Validators{Exists: true}
+	if etag, err := headers.NewETag(e.header.Get("ETag")); err == nil {
+		v.ETag = &etag
+	}
+	if t, err := http.ParseTime(e.header.Get("Last-Modified")); err == nil {
+		v.LastModified = t
+	}
+	age := strconv.Itoa(int(e.age() / time.Second))
+	if conditional.Evaluate(r, v) == http.StatusNotModified {
+		for _, k := range notModifiedHeaders {
+			k = http.CanonicalHeaderKey(k)
+			if values, ok := e.header[k]; ok {
+				w.Header()[k] = values
+			}
+		}
+		w.Header().Set("Age", age)
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+	for k, values := range e.header {
+		w.Header()[k] = values
+	}
+	w.Header().Set("Age", age)
+	w.WriteHeader(e.status)
+	if r.Method != http.MethodHead {
+		w.Write(e.body)
+	}
+}
+
+// matches reports whether r selects e, per RFC 7234, section 4.1.
+func (e *entry) matches(r *http.Request) bool {
+	for field, value := range e.vary {
+		if normalize(field, r.Header[field]) != value {
+			return false
+		}
+	}
+	return true
+}
+
+func (e *entry) sameVariant(o *entry) bool {
+	if len(e.vary) != len(o.vary) {
+		return false
+	}
+	for field, value := range e.vary {
+		if ov, ok := o.vary[field]; !ok || ov != value {
+			return false
+		}
+	}
+	return true
+}
+
+func (e *entry) age() time.Duration {
+	return now().Sub(e.stored)
+}
+
+// usable reports whether e may be sent without revalidating it, given the
+// request's cache directives.
+func (e *entry) usable(req headers.CacheControl) bool {
+	if req.NoCache || (e.control.NoCache && len(e.control.NoCacheFields) == 0) {
+		return false
+	}
+	age := e.age()
+	if req.MaxAge != nil && age > *req.MaxAge {
+		return false
+	}
+	if req.MinFresh != nil && age+*req.MinFresh > e.lifetime {
+		return false
+	}
+	if age < e.lifetime {
+		return true
+	}
+	if e.control.MustRevalidate || e.control.ProxyRevalidate || e.control.SMaxAge != nil {
+		return false
+	}
+	return req.MaxStaleAny || (req.MaxStale != nil && age-e.lifetime <= *req.MaxStale)
+}
+
+// freshnessLifetime follows RFC 7234, section 4.2.1. No heuristic lifetime
+// is used.
+func (e *entry) freshnessLifetime() time.Duration {
+	switch {
+	case e.control.SMaxAge != nil:
+		return *e.control.SMaxAge
+	case e.control.MaxAge != nil:
+		return *e.control.MaxAge
+	}
+	expires, err := http.ParseTime(e.header.Get("Expires"))
+	if err != nil {
+		return 0
+	}
+	date, err := http.ParseTime(e.header.Get("Date"))
+	if err != nil {
+		date = e.stored
+	}
+	return expires.Sub(date)
+}
+
+func (e *entry) validator() string {
+	if etag := e.header.Get("ETag"); etag != "" {
+		return etag
+	}
+	return e.header.Get("Last-Modified")
+}
+
+// varyFields returns the canonical names of the fields listed by the Vary
+// header of h.
+func varyFields(h http.Header) []string {
+	var fields []string
+	for _, v := range h["Vary"] {
+		for _, f := range strings.Split(v, ",") {
+			if f = strings.TrimSpace(f); f != "" {
+				fields = append(fields, http.CanonicalHeaderKey(f))
+			}
+		}
+	}
+	return fields
+}
+
+// normalize returns a form of the values of a request header in which
+// semantically equal values are identical. Accept headers are parsed, and
+// their media ranges canonicalized and sorted, since their order carries no
+// meaning. Other headers have the whitespace around their list items
+// removed.
+func normalize(field string, values []string) string {
+	v := strings.Join(values, ",")
+	if field == "Accept" && strings.TrimSpace(v) != "" {
+		if accepts, err := headers.NewAccepts(v); err == nil {
+			s := make([]string, len(accepts))
+			for i, a := range accepts {
+				s[i] = a.Canonical().AcceptString()
+			}
+			sort.Strings(s)
+			return strings.Join(s, ",")
+		}
+	}
+	parts := strings.Split(v, ",")
+	for i, p := range parts {
+		parts[i] = strings.TrimSpace(p)
+	}
+	return strings.Join(parts, ",")
+}
+
+// buffer records the status of a response, and its body if record is set.
+// If w is not nil, the response is passed through to it as well, and a
+// streamed response stops being recorded.
+type buffer struct {
+	w        http.ResponseWriter
+	header   http.Header
+	status   int
+	record   bool
+	streamed bool
+	body     bytes.Buffer
+}
+
+func (b *buffer) Header() http.Header {
+	if b.w != nil {
+		return b.w.Header()
+	}
+	if b.header == nil {
+		b.header = http.Header{}
+	}
+	return b.header
+}
+
+func (b *buffer) WriteHeader(status int) {
+	if b.status != 0 {
+		return
+	}
+	b.status = status
+	if m, err := mtrest.NewMediaType(b.Header().Get("Content-Type")); err == nil {
+		for _, t := range streamedTypes {
+			if t.Includes(m) {
+				b.stream()
+				break
+			}
+		}
+	}
+	if b.w != nil {
+		b.w.WriteHeader(status)
+	}
+}
+
+// Flush implements http.Flusher. A flushed response is taken to be
+// streamed.
+func (b *buffer) Flush() {
+	b.WriteHeader(http.StatusOK)
+	b.stream()
+	if f, ok := b.w.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// stream marks the response as streamed. A response that is passed through
+// is no longer recorded, since it may not end.
+func (b *buffer) stream() {
+	b.streamed = true
+	if b.w != nil {
+		b.record = false
+		b.body = bytes.Buffer{}
+	}
+}
+
+// writeTo sends a recorded response to w.
+func (b *buffer) writeTo(w http.ResponseWriter) {
+	for k, v := range b.Header() {
+		w.Header()[k] = v
+	}
+	if b.status == 0 {
+		b.status = http.StatusOK
+	}
+	w.WriteHeader(b.status)
+	w.Write(b.body.Bytes())
+}
+
+func (b *buffer) Write(p []byte) (int, error) {
+	b.WriteHeader(http.StatusOK)
+	if b.record {
+		b.body.Write(p)
+	}
+	if b.w != nil {
+		return b.w.Write(p)
+	}
+	return len(p), nil
+}
@@ -0,0 +1,261 @@
+// Copyright © 2017 Walter Scheper <walter.scheper@gmail.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cache
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/wfscheper/mtrest/conditional"
+	"github.com/wfscheper/mtrest/render"
+)
+
+// origin counts the requests that reach it, and renders a widget with the
+// given Cache-Control header.
+type origin struct {
+	calls        int
+	cacheControl string
+	name         string
+}
+
+func (o *origin) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	o.calls++
+	if r.Method == http.MethodPost {
+		o.name += "+"
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+	if o.cacheControl != "" {
+		w.Header().Set("Cache-Control", o.cacheControl)
+	}
+	render.Render(w, r, http.StatusOK, widget{o.name})
+}
+
+type step struct {
+	title   string
+	method  string
+	headers map[string]string
+	advance time.Duration
+	status  int
+	calls   int
+	body    string
+	age     string
+}
+
+func run(t *testing.T, h http.Handler, o *origin, steps []step) {
+	t.Helper()
+	for idx, s := range steps {
+		clock = clock.Add(s.advance)
+		method := s.method
+		if method == "" {
+			method = "GET"
+		}
+		r := httptest.NewRequest(method, "http://example.com/widgets/1", nil)
+		for k, v := range s.headers {
+			r.Header.Set(k, v)
+		}
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+		if w.Code != s.status || o.calls != s.calls {
+			t.Errorf("%d: (%s) expected %d after %d calls, got %d after %d", idx, s.title, s.status, s.calls, w.Code, o.calls)
+		}
+		if s.body != "" && w.Body.String() != s.body {
+			t.Errorf("%d: (%s) expected body '%s', got '%s'", idx, s.title, s.body, w.Body)
+		}
+		if actual := w.Header().Get("Age"); actual != s.age {
+			t.Errorf("%d: (%s) expected Age '%s', got '%s'", idx, s.title, s.age, actual)
+		}
+	}
+}
+
+func TestCacheFreshness(t *testing.T) {
+	o := &origin{cacheControl: "max-age=60", name: "a"}
+	run(t, New().Handler(o), o, []step{
+		{title: "Miss", status: 200, calls: 1, body: `{"name":"a"}`},
+		{title: "Hit", advance: 10 * time.Second, status: 200, calls: 1, body: `{"name":"a"}`, age: "10"},
+		{title: "HEAD hit", method: "HEAD", status: 200, calls: 1, age: "10"},
+		{title: "Request max-age", headers: map[string]string{"Cache-Control": "max-age=5"}, status: 200, calls: 2},
+		{title: "Stale", advance: 61 * time.Second, status: 200, calls: 3},
+		{title: "max-stale", advance: 70 * time.Second, headers: map[string]string{"Cache-Control": "max-stale=20"}, status: 200, calls: 3, age: "70"},
+		{title: "min-fresh", headers: map[string]string{"Cache-Control": "min-fresh=30"}, status: 200, calls: 4},
+		{title: "only-if-cached hit", headers: map[string]string{"Cache-Control": "only-if-cached"}, status: 200, calls: 4, age: "0"},
+		{title: "Request no-cache", headers: map[string]string{"Cache-Control": "no-cache"}, status: 200, calls: 5},
+		{title: "Pragma no-cache", headers: map[string]string{"Pragma": "no-cache"}, status: 200, calls: 6},
+		{title: "POST invalidates", method: "POST", status: 204, calls: 7},
+		{title: "only-if-cached miss", headers: map[string]string{"Cache-Control": "only-if-cached"}, status: 504, calls: 7},
+		{title: "Refetched", status: 200, calls: 8, body: `{"name":"a+"}`},
+	})
+}
+
+func TestCacheVary(t *testing.T) {
+	o := &origin{cacheControl: "public, max-age=60", name: "a"}
+	run(t, New().Handler(o), o, []step{
+		{title: "JSON miss", headers: map[string]string{"Accept": "application/json, application/xml; q=0.5"}, status: 200, calls: 1, body: `{"name":"a"}`},
+		{title: "Equivalent Accept hits", headers: map[string]string{"Accept": "Application/XML;q=0.5,application/json;q=1"}, status: 200, calls: 1, body: `{"name":"a"}`, age: "0"},
+		{title: "XML miss", headers: map[string]string{"Accept": "application/xml"}, status: 200, calls: 2, body: `<widget><name>a</name></widget>`},
+		{title: "XML hit", headers: map[string]string{"Accept": "application/xml"}, status: 200, calls: 2, body: `<widget><name>a</name></widget>`, age: "0"},
+		{title: "JSON still cached", headers: map[string]string{"Accept": "application/json,application/xml;q=0.5"}, status: 200, calls: 2, body: `{"name":"a"}`, age: "0"},
+		{title: "No Accept is another variant", status: 200, calls: 3},
+	})
+}
+
+func TestCacheRevalidation(t *testing.T) {
+	o := &origin{cacheControl: "max-age=60", name: "a"}
+	h := New().Handler(conditional.Handler(o, nil))
+	r := httptest.NewRequest("GET", "http://example.com/widgets/1", nil)
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	etag := w.Header().Get("ETag")
+
+	run(t, h, o, []step{
+		{title: "Client revalidates against the cache", headers: map[string]string{"If-None-Match": etag}, status: 304, calls: 1, age: "0"},
+		{title: "Client has an old tag", headers: map[string]string{"If-None-Match": `"old"`}, status: 200, calls: 1, body: `{"name":"a"}`, age: "0"},
+		{title: "Stale entry is revalidated", advance: 90 * time.Second, status: 200, calls: 2, body: `{"name":"a"}`, age: "0"},
+		{title: "Revalidated entry is fresh", advance: 30 * time.Second, status: 200, calls: 2, body: `{"name":"a"}`, age: "30"},
+	})
+
+	o.name = "b"
+	run(t, h, o, []step{
+		{title: "Changed representation replaces the entry", advance: 60 * time.Second, status: 200, calls: 3, body: `{"name":"b"}`},
+		{title: "New entry is cached", status: 200, calls: 3, body: `{"name":"b"}`, age: "0"},
+	})
+
+	o.cacheControl = "no-cache"
+	run(t, h, o, []step{
+		{title: "Revalidation picks up no-cache", advance: 60 * time.Second, status: 200, calls: 4, body: `{"name":"b"}`, age: "0"},
+		{title: "no-cache response is always revalidated", status: 200, calls: 5, body: `{"name":"b"}`, age: "0"},
+	})
+}
+
+func TestCacheNotStored(t *testing.T) {
+	tests := []struct {
+		title, cacheControl string
+		response, headers   map[string]string
+		flush               bool
+	}{
+		{"no-store response", "max-age=60, no-store", nil, nil, false},
+		{"private response", "private, max-age=60", nil, nil, false},
+		{"No freshness or validators", "", nil, nil, false},
+		{"Vary *", "max-age=60", map[string]string{"Vary": "*"}, nil, false},
+		{"no-store request", "max-age=60", nil, map[string]string{"Cache-Control": "no-store"}, false},
+		{"Authorized request", "max-age=60", nil, map[string]string{"Authorization": "Bearer x"}, false},
+		{"Set-Cookie", "max-age=60", map[string]string{"Set-Cookie": "id=1"}, nil, false},
+		{"Event stream", "max-age=60", map[string]string{"Content-Type": "text/event-stream"}, nil, false},
+		{"Flushed response", "max-age=60", nil, nil, true},
+	}
+	for idx, test := range tests {
+		var calls int
+		h := New().Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			calls++
+			if test.cacheControl != "" {
+				w.Header().Set("Cache-Control", test.cacheControl)
+			}
+			for k, v := range test.response {
+				w.Header().Set(k, v)
+			}
+			w.Write([]byte("a"))
+			if test.flush {
+				w.(http.Flusher).Flush()
+			}
+		}))
+		for i := 0; i < 2; i++ {
+			r := httptest.NewRequest("GET", "/", nil)
+			for k, v := range test.headers {
+				r.Header.Set(k, v)
+			}
+			h.ServeHTTP(httptest.NewRecorder(), r)
+		}
+		if calls != 2 {
+			t.Errorf("%d: (%s) expected 2 calls, got %d", idx, test.title, calls)
+		}
+	}
+
+	// an authorized request, or a response that sets a cookie, may be
+	// cached if the response is fit for a shared cache
+	shared := []struct {
+		title, cacheControl string
+		response, headers   map[string]string
+	}{
+		{"Public response to an authorized request", "public, max-age=60", nil, map[string]string{"Authorization": "Bearer x"}},
+		{"Public Set-Cookie", "public, max-age=60", map[string]string{"Set-Cookie": "id=1"}, nil},
+		{"s-maxage Set-Cookie", "s-maxage=60", map[string]string{"Set-Cookie": "id=1"}, nil},
+	}
+	for idx, test := range shared {
+		var calls int
+		h := New().Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			calls++
+			w.Header().Set("Cache-Control", test.cacheControl)
+			for k, v := range test.response {
+				w.Header().Set(k, v)
+			}
+			w.Write([]byte("a"))
+		}))
+		for i := 0; i < 2; i++ {
+			r := httptest.NewRequest("GET", "/", nil)
+			for k, v := range test.headers {
+				r.Header.Set(k, v)
+			}
+			h.ServeHTTP(httptest.NewRecorder(), r)
+		}
+		if calls != 1 {
+			t.Errorf("%d: (%s) expected 1 call, got %d", idx, test.title, calls)
+		}
+	}
+}
+
+func TestCacheMaxEntries(t *testing.T) {
+	var calls int
+	c := New()
+	c.MaxEntries = 2
+	h := c.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.Header().Set("Cache-Control", "max-age=600")
+		w.Write([]byte(r.URL.Path))
+	}))
+	for _, path := range strings.Fields("/a /b /a /c /b /c /a") {
+		clock = clock.Add(time.Second)
+		h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", path, nil))
+	}
+	// /a, /b, (a hit), /c evicts /a, (b hit), (c hit), /a evicts /b
+	if calls != 4 {
+		t.Errorf("expected 4 calls, got %d", calls)
+	}
+	if c.size != 2 {
+		t.Errorf("expected 2 entries, got %d", c.size)
+	}
+}
+
+func TestNormalize(t *testing.T) {
+	tests := []struct {
+		field    string
+		values   []string
+		expected string
+	}{
+		{"Accept", []string{"text/html, application/json;q=0.5"}, "application/json; q=0.5,text/html"},
+		{"Accept", []string{"application/JSON; q=0.50", "TEXT/html;q=1"}, "application/json; q=0.5,text/html"},
+		{"Accept", nil, ""},
+		{"Accept", []string{"text/"}, "text/"},
+		{"Accept-Language", []string{"en ,de"}, "en,de"},
+		{"Authorization", []string{"Bearer ABC"}, "Bearer ABC"},
+	}
+	for idx, test := range tests {
+		if actual := normalize(test.field, test.values); actual != test.expected {
+			t.Errorf("%d: expected '%s', got '%s'", idx, test.expected, actual)
+		}
+	}
+}
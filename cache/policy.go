@@ -0,0 +1,136 @@
+// Copyright © 2017 Walter Scheper <walter.scheper@gmail.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package cache controls how responses are cached by clients and shared
+// caches.
+package cache
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/wfscheper/mtrest/headers"
+)
+
+// now is replaced by tests.
+var now = time.Now
+
+// cacheableStatus lists the status codes that are cacheable by default, per
+// RFC 7231, section 6.1, and RFC 7538.
+var cacheableStatus = map[int]bool{
+	http.StatusOK:                   true,
+	http.StatusNonAuthoritativeInfo: true,
+	http.StatusNoContent:            true,
+	http.StatusPartialContent:       true,
+	http.StatusMultipleChoices:      true,
+	http.StatusMovedPermanently:     true,
+	http.StatusPermanentRedirect:    true,
+	http.StatusNotFound:             true,
+	http.StatusMethodNotAllowed:     true,
+	http.StatusGone:                 true,
+	http.StatusRequestURITooLong:    true,
+	http.StatusNotImplemented:       true,
+}
+
+// Policy sets the caching headers of a resource's responses to GET and HEAD
+// requests with a cacheable status.
+type Policy struct {
+	// CacheControl is sent with responses that do not already have a
+	// Cache-Control header.
+	CacheControl headers.CacheControl
+
+	// Vary lists any request headers the responses vary by, beyond those
+	// used to negotiate the representation.
+	Vary []string
+}
+
+// Handler returns a handler that applies the policy to the responses of h.
+// Besides Cache-Control, it sets an Expires header that agrees with the
+// response's max-age, or that has already passed if the response must not
+// be reused without revalidation. The Vary header lists the policy's
+// fields, and Accept-Encoding or Accept-Language if the response has a
+// Content-Encoding or Content-Language, alongside the Accept added by
+// render.Render. Its signature suits router.With.
+func (p *Policy) Handler(h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet && r.Method != http.MethodHead {
+			h.ServeHTTP(w, r)
+			return
+		}
+		h.ServeHTTP(&policyWriter{ResponseWriter: w, policy: p}, r)
+	})
+}
+
+func (p *Policy) apply(h http.Header, status int) {
+	if !cacheableStatus[status] {
+		return
+	}
+	if h.Get("Cache-Control") == "" {
+		if s := p.CacheControl.String(); s != "" {
+			h.Set("Cache-Control", s)
+		}
+	}
+	if h.Get("Expires") == "" {
+		if cc, err := headers.NewCacheControl(h.Get("Cache-Control")); err == nil {
+			t := now()
+			switch {
+			case cc.NoStore || (cc.NoCache && len(cc.NoCacheFields) == 0):
+				h.Set("Expires", t.UTC().Format(http.TimeFormat))
+			case cc.MaxAge != nil:
+				h.Set("Expires", t.Add(*cc.MaxAge).UTC().Format(http.TimeFormat))
+			}
+		}
+	}
+	headers.AddVary(h, p.Vary...)
+	if h.Get("Content-Encoding") != "" {
+		headers.AddVary(h, "Accept-Encoding")
+	}
+	if h.Get("Content-Language") != "" {
+		headers.AddVary(h, "Accept-Language")
+	}
+}
+
+// policyWriter applies a Policy to the headers of a response just before
+// they are written.
+type policyWriter struct {
+	http.ResponseWriter
+	policy      *Policy
+	wroteHeader bool
+}
+
+func (w *policyWriter) WriteHeader(status int) {
+	if !w.wroteHeader {
+		w.wroteHeader = true
+		w.policy.apply(w.Header(), status)
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *policyWriter) Write(p []byte) (int, error) {
+	if !w.wroteHeader {
+		w.WriteHeader(http.StatusOK)
+	}
+	return w.ResponseWriter.Write(p)
+}
+
+// Flush implements http.Flusher, so that streamed responses still reach the
+// client as they are written.
+func (w *policyWriter) Flush() {
+	if !w.wroteHeader {
+		w.WriteHeader(http.StatusOK)
+	}
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
@@ -0,0 +1,125 @@
+// Copyright © 2017 Walter Scheper <walter.scheper@gmail.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cache
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/wfscheper/mtrest/headers"
+	"github.com/wfscheper/mtrest/render"
+)
+
+var (
+	start = time.Date(2017, 6, 1, 12, 0, 0, 0, time.UTC)
+	clock = start
+)
+
+func init() {
+	now = func() time.Time { return clock }
+}
+
+type widget struct {
+	Name string `json:"name" xml:"name"`
+}
+
+func TestPolicy(t *testing.T) {
+	clock = start
+	public := &Policy{
+		CacheControl: headers.CacheControl{Public: true, MaxAge: headers.Seconds(60)},
+		Vary:         []string{"Authorization"},
+	}
+	private := &Policy{CacheControl: headers.CacheControl{Private: true, NoCache: true}}
+
+	tests := []struct {
+		title        string
+		policy       *Policy
+		method       string
+		handler      http.HandlerFunc
+		cacheControl string
+		expires      string
+		vary         []string
+	}{
+		{"Rendered response", public, "GET", func(w http.ResponseWriter, r *http.Request) {
+			render.Render(w, r, http.StatusOK, widget{"a"})
+		}, "public, max-age=60", "Thu, 01 Jun 2017 12:01:00 GMT", []string{"Accept", "Authorization"}},
+		{"HEAD", public, "HEAD", func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusOK)
+		}, "public, max-age=60", "Thu, 01 Jun 2017 12:01:00 GMT", []string{"Authorization"}},
+		{"Implicit 200", public, "GET", func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte("a"))
+		}, "public, max-age=60", "Thu, 01 Jun 2017 12:01:00 GMT", []string{"Authorization"}},
+		{"Handler's Cache-Control wins", public, "GET", func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Cache-Control", "max-age=3600")
+			w.WriteHeader(http.StatusOK)
+		}, "max-age=3600", "Thu, 01 Jun 2017 13:00:00 GMT", []string{"Authorization"}},
+		{"Handler's Expires wins", public, "GET", func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Expires", "Fri, 02 Jun 2017 00:00:00 GMT")
+			w.WriteHeader(http.StatusOK)
+		}, "public, max-age=60", "Fri, 02 Jun 2017 00:00:00 GMT", []string{"Authorization"}},
+		{"no-cache expires immediately", private, "GET", func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusOK)
+		}, "private, no-cache", "Thu, 01 Jun 2017 12:00:00 GMT", nil},
+		{"Negotiated encoding and language", private, "GET", func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Encoding", "gzip")
+			w.Header().Set("Content-Language", "de")
+			w.WriteHeader(http.StatusOK)
+		}, "private, no-cache", "Thu, 01 Jun 2017 12:00:00 GMT", []string{"Accept-Encoding", "Accept-Language"}},
+		{"Uncacheable status", public, "GET", func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusInternalServerError)
+		}, "", "", nil},
+		{"Unsafe method", public, "POST", func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusOK)
+		}, "", "", nil},
+		{"Empty policy", &Policy{}, "GET", func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusOK)
+		}, "", "", nil},
+	}
+	for idx, test := range tests {
+		w := httptest.NewRecorder()
+		test.policy.Handler(test.handler).ServeHTTP(w, httptest.NewRequest(test.method, "/", nil))
+		if actual := w.Header().Get("Cache-Control"); actual != test.cacheControl {
+			t.Errorf("%d: (%s) expected Cache-Control '%s', got '%s'", idx, test.title, test.cacheControl, actual)
+		}
+		if actual := w.Header().Get("Expires"); actual != test.expires {
+			t.Errorf("%d: (%s) expected Expires '%s', got '%s'", idx, test.title, test.expires, actual)
+		}
+		if actual := w.Header()["Vary"]; !reflect.DeepEqual(actual, test.vary) {
+			t.Errorf("%d: (%s) expected Vary %q, got %q", idx, test.title, test.vary, actual)
+		}
+	}
+}
+
+func TestPolicyFlush(t *testing.T) {
+	clock = start
+	p := &Policy{CacheControl: headers.CacheControl{Public: true, MaxAge: headers.Seconds(60)}}
+	w := httptest.NewRecorder()
+	p.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		f, ok := w.(http.Flusher)
+		if !ok {
+			t.Fatal("expected the response to be an http.Flusher")
+		}
+		f.Flush()
+	})).ServeHTTP(w, httptest.NewRequest("GET", "/", nil))
+	if !w.Flushed {
+		t.Error("expected the response to be flushed")
+	}
+	if actual := w.Header().Get("Cache-Control"); actual != "public, max-age=60" {
+		t.Errorf("expected Cache-Control 'public, max-age=60', got '%s'", actual)
+	}
+}
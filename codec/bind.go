@@ -0,0 +1,138 @@
+// Copyright © 2017 Walter Scheper <walter.scheper@gmail.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package codec
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+
+	"github.com/wfscheper/mtrest"
+)
+
+// UnsupportedMediaTypeError is returned by Bind when there is no Codec for a
+// request body's media type, or the media type could not be determined.
+type UnsupportedMediaTypeError struct {
+	MediaType *mtrest.MediaType
+}
+
+func (e *UnsupportedMediaTypeError) Error() string {
+	if e.MediaType == nil {
+		return "Unsupported media type"
+	}
+	return "Unsupported media type: '" + e.MediaType.String() + "'"
+}
+
+// TooLargeError is returned by Bind when a request body is larger than the
+// Binder allows.
+type TooLargeError struct {
+	Limit int64
+}
+
+func (e *TooLargeError) Error() string {
+	return fmt.Sprintf("A request body may be at most %d bytes", e.Limit)
+}
+
+// DefaultMaxBytes is the largest request body a Binder reads when its
+// MaxBytes is zero.
+const DefaultMaxBytes = 10 << 20
+
+// Binder decodes request bodies using the Codec registered for the request's
+// Content-Type. The zero value trusts the Content-Type header.
+type Binder struct {
+	// Sniff enables content sniffing with mtrest.Sniff when a request has no
+	// Content-Type, or has the generic application/octet-stream.
+	Sniff bool
+
+	// MinConfidence is the lowest sniffing confidence Bind accepts. A
+	// sniffed type with mtrest.ConfidenceNone is never accepted.
+	MinConfidence mtrest.Confidence
+
+	// MaxBytes is the largest request body Bind reads, in bytes. Larger
+	// bodies are refused with a TooLargeError. Zero means DefaultMaxBytes,
+	// and a negative MaxBytes means no limit.
+	MaxBytes int64
+}
+
+// DefaultBinder is the Binder used by Bind.
+var DefaultBinder = Binder{}
+
+// Bind decodes the body of r into v using DefaultBinder.
+func Bind(r *http.Request, v interface{}) error {
+	return DefaultBinder.Bind(r, v)
+}
+
+// Bind decodes the body of r into v using the Codec for the request's
+// Content-Type. If the Binder allows it, and the request has no useful
+// Content-Type, the media type is sniffed from the body instead.
+func (b Binder) Bind(r *http.Request, v interface{}) error {
+	var data []byte
+	if r.Body != nil {
+		var err error
+		if data, err = b.read(r.Body); err != nil {
+			return err
+		}
+	}
+	m, err := b.mediaType(r.Header.Get("Content-Type"), data)
+	if err != nil {
+		return err
+	}
+	c, ok := For(m)
+	if !ok {
+		return &UnsupportedMediaTypeError{m}
+	}
+	return c.Unmarshal(data, v)
+}
+
+// read reads body, up to the Binder's MaxBytes.
+func (b Binder) read(body io.Reader) ([]byte, error) {
+	limit := b.MaxBytes
+	if limit == 0 {
+		limit = DefaultMaxBytes
+	}
+	if limit < 0 {
+		return ioutil.ReadAll(body)
+	}
+	data, err := ioutil.ReadAll(io.LimitReader(body, limit+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(data)) > limit {
+		return nil, &TooLargeError{limit}
+	}
+	return data, nil
+}
+
+func (b Binder) mediaType(contentType string, data []byte) (*mtrest.MediaType, error) {
+	var m *mtrest.MediaType
+	if contentType != "" {
+		var err error
+		if m, err = mtrest.NewMediaType(contentType); err != nil {
+			return nil, err
+		}
+		if !b.Sniff || !mtrest.ApplicationOctetStream.Includes(m) {
+			return m, nil
+		}
+	}
+	if !b.Sniff {
+		return nil, &UnsupportedMediaTypeError{}
+	}
+	sniffed, confidence := mtrest.Sniff(data)
+	if confidence == mtrest.ConfidenceNone || confidence < b.MinConfidence {
+		return nil, &UnsupportedMediaTypeError{m}
+	}
+	return sniffed, nil
+}
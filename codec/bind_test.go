@@ -0,0 +1,81 @@
+// Copyright © 2017 Walter Scheper <walter.scheper@gmail.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package codec
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/wfscheper/mtrest"
+)
+
+func TestBind(t *testing.T) {
+	tests := []struct {
+		title       string
+		binder      Binder
+		contentType string
+		body        string
+		expected    widget
+		err         string
+	}{
+		{"JSON", Binder{}, "application/json", `{"name":"a","count":1}`, widget{"a", 1}, ""},
+		{"Vendor JSON", Binder{}, "application/vnd.foo+json; version=1", `{"name":"a"}`, widget{"a", 0}, ""},
+		{"YAML", Binder{}, "application/yaml", "name: a\ncount: 2\n", widget{"a", 2}, ""},
+		{"XML", Binder{}, "application/xml", "<widget><name>a</name></widget>", widget{"a", 0}, ""},
+		{"Unsupported type", Binder{}, "text/plain", "a", widget{}, "Unsupported media type: 'text/plain'"},
+		{"Malformed Content-Type", Binder{}, "text/", "a", widget{}, "mime: expected token after slash"},
+		{"Missing Content-Type", Binder{}, "", `{"name":"a"}`, widget{}, "Unsupported media type"},
+		{"Octet stream without sniffing", Binder{}, "application/octet-stream", `{"name":"a"}`, widget{}, "Unsupported media type: 'application/octet-stream'"},
+		{"Missing Content-Type with sniffing", Binder{Sniff: true}, "", `{"name":"a"}`, widget{"a", 0}, ""},
+		{"Octet stream with sniffing", Binder{Sniff: true}, "application/octet-stream", "<widget><count>3</count></widget>", widget{"", 3}, ""},
+		{"Sniffing ignores specific types", Binder{Sniff: true}, "text/plain", `{"name":"a"}`, widget{}, "Unsupported media type: 'text/plain'"},
+		{"Sniffed type without a codec", Binder{Sniff: true}, "", "\x89PNG\x0D\x0A\x1A\x0A", widget{}, "Unsupported media type: 'image/png'"},
+		{"Unrecognized content", Binder{Sniff: true}, "application/octet-stream", "\x00\x01", widget{}, "Unsupported media type: 'application/octet-stream'"},
+		{"Confidence too low", Binder{Sniff: true, MinConfidence: mtrest.ConfidenceMedium}, "", "name: a\n", widget{}, "Unsupported media type"},
+		{"Confidence high enough", Binder{Sniff: true, MinConfidence: mtrest.ConfidenceMedium}, "", "---\nname: a\n", widget{"a", 0}, ""},
+		{"Body at the limit", Binder{MaxBytes: 12}, "application/json", `{"name":"a"}`, widget{"a", 0}, ""},
+		{"Body too large", Binder{MaxBytes: 11}, "application/json", `{"name":"a"}`, widget{}, "A request body may be at most 11 bytes"},
+		{"No limit", Binder{MaxBytes: -1}, "application/json", `{"name":"a"}`, widget{"a", 0}, ""},
+	}
+	for idx, test := range tests {
+		r := httptest.NewRequest("POST", "/", strings.NewReader(test.body))
+		if test.contentType != "" {
+			r.Header.Set("Content-Type", test.contentType)
+		}
+		var actual widget
+		err := test.binder.Bind(r, &actual)
+		if test.err == "" && err != nil {
+			t.Errorf("%d: (%s) expected nil, got %q", idx, test.title, err)
+		} else if test.err != "" && (err == nil || err.Error() != test.err) {
+			t.Errorf("%d: (%s) expected '%s', got '%v'", idx, test.title, test.err, err)
+		}
+		if actual != test.expected {
+			t.Errorf("%d: (%s) expected %+v, got %+v", idx, test.title, test.expected, actual)
+		}
+	}
+}
+
+func TestBindDefault(t *testing.T) {
+	r := httptest.NewRequest("POST", "/", strings.NewReader(`{"name":"a"}`))
+	r.Header.Set("Content-Type", "application/json")
+	var actual widget
+	if err := Bind(r, &actual); err != nil {
+		t.Fatal(err)
+	}
+	if actual.Name != "a" {
+		t.Errorf("expected 'a', got '%s'", actual.Name)
+	}
+}
@@ -0,0 +1,50 @@
+// Copyright © 2017 Walter Scheper <walter.scheper@gmail.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package cbor registers a codec for CBOR, RFC 8949, as the "cbor" encoding
+// of application/cbor and the +cbor suffix. It is built on
+// github.com/fxamacker/cbor and honors the struct tags of encoding/json, so
+// that one type serves text and binary clients alike. Import it for its side
+// effect:
+//
+//	import _ "github.com/wfscheper/mtrest/codec/cbor"
+//
+// Render offers CBOR for values that implement render.Offerer with it, or
+// for every value once mtrest.ApplicationCBOR is added to render.Offers.
+package cbor
+
+import (
+	"reflect"
+
+	"github.com/fxamacker/cbor/v2"
+	"github.com/wfscheper/mtrest/codec"
+)
+
+func init() {
+	codec.Register("cbor", Codec{})
+}
+
+var (
+	// Times are encoded as RFC 3339 strings under tag 0, which keeps their
+	// precision and offset.
+	encMode, _ = cbor.EncOptions{Time: cbor.TimeRFC3339Nano, TimeTag: cbor.EncTagRequired}.EncMode()
+	// Maps decode to map[string]interface{}, as they do from JSON.
+	decMode, _ = cbor.DecOptions{DefaultMapType: reflect.TypeOf(map[string]interface{}(nil))}.DecMode()
+)
+
+// Codec marshals and unmarshals CBOR.
+type Codec struct{}
+
+func (Codec) Marshal(v interface{}) ([]byte, error)      { return encMode.Marshal(v) }
+func (Codec) Unmarshal(data []byte, v interface{}) error { return decMode.Unmarshal(data, v) }
@@ -0,0 +1,103 @@
+// Copyright © 2017 Walter Scheper <walter.scheper@gmail.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package codec maps media type encodings to the marshalers that read and
+// write them.
+package codec
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"sort"
+	"sync"
+
+	"github.com/wfscheper/mtrest"
+	yaml "gopkg.in/yaml.v2"
+)
+
+// Codec marshals and unmarshals values in a single encoding.
+type Codec interface {
+	Marshal(v interface{}) ([]byte, error)
+	Unmarshal(data []byte, v interface{}) error
+}
+
+var (
+	mu     sync.RWMutex
+	codecs = map[string]Codec{
+		"json": jsonCodec{},
+		"xml":  xmlCodec{},
+		"yaml": yamlCodec{},
+	}
+)
+
+// Register makes a Codec available for media types with the given encoding,
+// as returned by mtrest.MediaType.Encoding. Registering an encoding a second
+// time replaces the previous Codec.
+func Register(encoding string, c Codec) {
+	mu.Lock()
+	defer mu.Unlock()
+	codecs[encoding] = c
+}
+
+// For returns the Codec registered for m's encoding.
+func For(m *mtrest.MediaType) (Codec, bool) {
+	if m == nil {
+		return nil, false
+	}
+	mu.RLock()
+	defer mu.RUnlock()
+	c, ok := codecs[m.Encoding()]
+	return c, ok
+}
+
+// Encodings returns the registered encodings in sorted order.
+func Encodings() []string {
+	mu.RLock()
+	defer mu.RUnlock()
+	encodings := make([]string, 0, len(codecs))
+	for k := range codecs {
+		encodings = append(encodings, k)
+	}
+	sort.Strings(encodings)
+	return encodings
+}
+
+type jsonCodec struct{}
+
+func (jsonCodec) Marshal(v interface{}) ([]byte, error)      { return MarshalJSON(v) }
+func (jsonCodec) Unmarshal(data []byte, v interface{}) error { return json.Unmarshal(data, v) }
+
+// MarshalJSON encodes v like json.Marshal, but without escaping HTML
+// characters, which would otherwise mangle every URI with a query string.
+// It is how the JSON Codec encodes values.
+func MarshalJSON(v interface{}) ([]byte, error) {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(v); err != nil {
+		return nil, err
+	}
+	return bytes.TrimSuffix(buf.Bytes(), []byte("\n")), nil
+}
+
+type xmlCodec struct{}
+
+func (xmlCodec) Marshal(v interface{}) ([]byte, error)      { return xml.Marshal(v) }
+func (xmlCodec) Unmarshal(data []byte, v interface{}) error { return xml.Unmarshal(data, v) }
+
+type yamlCodec struct{}
+
+func (yamlCodec) Marshal(v interface{}) ([]byte, error)      { return yaml.Marshal(v) }
+func (yamlCodec) Unmarshal(data []byte, v interface{}) error { return yaml.Unmarshal(data, v) }
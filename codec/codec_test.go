@@ -0,0 +1,103 @@
+// Copyright © 2017 Walter Scheper <walter.scheper@gmail.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package codec
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/wfscheper/mtrest"
+)
+
+type widget struct {
+	Name  string `json:"name" xml:"name" yaml:"name"`
+	Count int    `json:"count" xml:"count" yaml:"count"`
+}
+
+func TestFor(t *testing.T) {
+	tests := []struct {
+		mt       string
+		expected string
+	}{
+		{"application/json", `{"name":"a","count":1}`},
+		{"application/vnd.foo+json", `{"name":"a","count":1}`},
+		{"application/xml", `<widget><name>a</name><count>1</count></widget>`},
+		{"application/vnd.foo+xml", `<widget><name>a</name><count>1</count></widget>`},
+		{"application/yaml", "name: a\ncount: 1\n"},
+	}
+	for idx, test := range tests {
+		m, err := mtrest.NewMediaType(test.mt)
+		if err != nil {
+			t.Fatalf("%d: %q", idx, err)
+		}
+		c, ok := For(m)
+		if !ok {
+			t.Fatalf("%d: expected a codec for %s", idx, test.mt)
+		}
+		data, err := c.Marshal(widget{"a", 1})
+		if err != nil {
+			t.Fatalf("%d: %q", idx, err)
+		}
+		if string(data) != test.expected {
+			t.Errorf("%d: expected '%s', got '%s'", idx, test.expected, data)
+		}
+		var actual widget
+		if err := c.Unmarshal(data, &actual); err != nil {
+			t.Fatalf("%d: %q", idx, err)
+		}
+		if actual != (widget{"a", 1}) {
+			t.Errorf("%d: expected %+v, got %+v", idx, widget{"a", 1}, actual)
+		}
+	}
+
+	m, _ := mtrest.NewMediaType("text/plain")
+	if _, ok := For(m); ok {
+		t.Error("expected no codec for text/plain")
+	}
+	if _, ok := For(nil); ok {
+		t.Error("expected no codec for nil")
+	}
+
+	c, _ := For(&mtrest.ApplicationJSON)
+	if data, _ := c.Marshal("/widgets?page=1&size=<2>"); string(data) != `"/widgets?page=1&size=<2>"` {
+		t.Errorf("expected HTML characters to be left alone, got '%s'", data)
+	}
+}
+
+type textCodec struct{}
+
+func (textCodec) Marshal(v interface{}) ([]byte, error)      { return []byte(v.(string)), nil }
+func (textCodec) Unmarshal(data []byte, v interface{}) error { *v.(*string) = string(data); return nil }
+
+func TestRegister(t *testing.T) {
+	Register("plain", textCodec{})
+	defer func() {
+		mu.Lock()
+		delete(codecs, "plain")
+		mu.Unlock()
+	}()
+
+	if actual := Encodings(); !reflect.DeepEqual(actual, []string{"json", "plain", "xml", "yaml"}) {
+		t.Errorf("expected [json plain xml yaml], got %v", actual)
+	}
+	m, _ := mtrest.NewMediaType("text/plain")
+	c, ok := For(m)
+	if !ok {
+		t.Fatal("expected a codec for text/plain")
+	}
+	if data, _ := c.Marshal("hello"); string(data) != "hello" {
+		t.Errorf("expected 'hello', got '%s'", data)
+	}
+}
@@ -0,0 +1,60 @@
+// Copyright © 2017 Walter Scheper <walter.scheper@gmail.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package msgpack registers a codec for MessagePack as the "msgpack"
+// encoding of application/msgpack and the +msgpack suffix. It is built on
+// github.com/vmihailenco/msgpack and honors the struct tags of
+// encoding/json, so that one type serves text and binary clients alike.
+// Import it for its side effect:
+//
+//	import _ "github.com/wfscheper/mtrest/codec/msgpack"
+//
+// Render offers MessagePack for values that implement render.Offerer with
+// it, or for every value once mtrest.ApplicationMsgpack is added to
+// render.Offers.
+package msgpack
+
+import (
+	"bytes"
+
+	"github.com/vmihailenco/msgpack/v5"
+	"github.com/wfscheper/mtrest/codec"
+)
+
+func init() {
+	codec.Register("msgpack", Codec{})
+}
+
+// Codec marshals and unmarshals MessagePack. Integers are written in their
+// smallest representation, and decode into interface{} values as int64,
+// uint64 or float64, as they would from JSON.
+type Codec struct{}
+
+func (Codec) Marshal(v interface{}) ([]byte, error) {
+	var buf bytes.Buffer
+	enc := msgpack.NewEncoder(&buf)
+	enc.SetCustomStructTag("json")
+	enc.UseCompactInts(true)
+	if err := enc.Encode(v); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func (Codec) Unmarshal(data []byte, v interface{}) error {
+	dec := msgpack.NewDecoder(bytes.NewReader(data))
+	dec.SetCustomStructTag("json")
+	dec.UseLooseInterfaceDecoding(true)
+	return dec.Decode(v)
+}
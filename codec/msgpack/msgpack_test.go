@@ -0,0 +1,93 @@
+// Copyright © 2017 Walter Scheper <walter.scheper@gmail.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package msgpack
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/wfscheper/mtrest"
+	"github.com/wfscheper/mtrest/codec"
+)
+
+type widget struct {
+	Name    string            `json:"name"`
+	Count   int               `json:"count"`
+	Tags    []string          `json:"tags,omitempty"`
+	Labels  map[string]string `json:"labels,omitempty"`
+	Data    []byte            `json:"data,omitempty"`
+	Created *time.Time        `json:"created,omitempty"`
+	Secret  string            `json:"-"`
+}
+
+func TestCodec(t *testing.T) {
+	created := time.Date(2017, 6, 1, 12, 0, 0, 5, time.FixedZone("", 3600))
+	tests := []struct {
+		mt       string
+		value    widget
+		expected string
+	}{
+		{"application/msgpack", widget{Name: "a", Count: 1, Secret: "s"}, "\x82\xa4name\xa1a\xa5count\x01"},
+		{"application/vnd.foo+msgpack", widget{Name: "a", Count: 1}, "\x82\xa4name\xa1a\xa5count\x01"},
+		{"application/msgpack", widget{Name: "b", Tags: []string{"x"}, Labels: map[string]string{"k": "v"}, Data: []byte{1, 2}, Created: &created}, ""},
+	}
+	for idx, test := range tests {
+		m, err := mtrest.NewMediaType(test.mt)
+		if err != nil {
+			t.Fatal(err)
+		}
+		c, ok := codec.For(m)
+		if !ok {
+			t.Fatalf("%d: expected a codec for %s", idx, test.mt)
+		}
+		data, err := c.Marshal(test.value)
+		if err != nil {
+			t.Fatalf("%d: %q", idx, err)
+		}
+		if test.expected != "" && string(data) != test.expected {
+			t.Errorf("%d: expected %q, got %q", idx, test.expected, data)
+		}
+		var actual widget
+		if err := c.Unmarshal(data, &actual); err != nil {
+			t.Fatalf("%d: %q", idx, err)
+		}
+		test.value.Secret = ""
+		if actual.Created != nil && !actual.Created.Equal(created) {
+			t.Errorf("%d: expected %v, got %v", idx, created, actual.Created)
+		}
+		actual.Created = test.value.Created
+		if !reflect.DeepEqual(actual, test.value) {
+			t.Errorf("%d: expected %+v, got %+v", idx, test.value, actual)
+		}
+	}
+}
+
+func TestUnmarshalInterface(t *testing.T) {
+	var actual interface{}
+	if err := (Codec{}).Unmarshal([]byte("\x82\xa4name\xa1a\xa5count\x01"), &actual); err != nil {
+		t.Fatal(err)
+	}
+	if m, ok := actual.(map[string]interface{}); !ok || m["name"] != "a" {
+		t.Errorf("expected a map[string]interface{}, got %#v", actual)
+	}
+}
+
+func TestUnmarshalError(t *testing.T) {
+	var v widget
+	if err := (Codec{}).Unmarshal([]byte("\xcd\x01"), &v); err == nil {
+		t.Error("expected an error for truncated data")
+	}
+}
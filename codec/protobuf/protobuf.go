@@ -0,0 +1,240 @@
+// Copyright © 2017 Walter Scheper <walter.scheper@gmail.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package protobuf reads and writes Protocol Buffers messages. Importing it
+// registers a Codec for the binary encoding, as the "protobuf" and
+// "x-protobuf" encodings. Messages are rendered with Render, which offers
+// them in the binary encoding and as canonical protobuf JSON, negotiated on
+// the proto or messageType parameter that names a message:
+//
+//	protobuf.Render(w, r, http.StatusOK, msg)
+//
+// and bound with Bind, which refuses a body that names another message.
+package protobuf
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/wfscheper/mtrest"
+	"github.com/wfscheper/mtrest/codec"
+	"github.com/wfscheper/mtrest/headers"
+	"github.com/wfscheper/mtrest/render"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+)
+
+// Media types of canonical protobuf JSON, named after the binary encoding.
+var (
+	ApplicationProtobufJSON  = mtrest.MediaType{Type: "application", SubType: "protobuf+json", Params: map[string]string{}, Weight: 1.0}
+	ApplicationXProtobufJSON = mtrest.MediaType{Type: "application", SubType: "x-protobuf+json", Params: map[string]string{}, Weight: 1.0}
+)
+
+// Default is the Codec registered for the protobuf encodings. It encodes
+// deterministically, so that entity tags are stable.
+var Default = &Codec{MarshalOptions: proto.MarshalOptions{Deterministic: true}}
+
+func init() {
+	codec.Register("protobuf", Default)
+	codec.Register("x-protobuf", Default)
+}
+
+// Codec reads and writes proto.Message values in the binary encoding, and
+// as canonical protobuf JSON, with the options it holds. A Message that
+// names its own Codec is read and written with that one instead.
+type Codec struct {
+	MarshalOptions       proto.MarshalOptions
+	UnmarshalOptions     proto.UnmarshalOptions
+	JSONMarshalOptions   protojson.MarshalOptions
+	JSONUnmarshalOptions protojson.UnmarshalOptions
+}
+
+// use returns the Codec of v if it is a Message that names one, or c.
+func (c *Codec) use(v interface{}) *Codec {
+	if m, ok := v.(interface{ codec() *Codec }); ok && m.codec() != nil {
+		return m.codec()
+	}
+	return c
+}
+
+func (c *Codec) Marshal(v interface{}) ([]byte, error) {
+	msg, ok := v.(proto.Message)
+	if !ok {
+		return nil, fmt.Errorf("Error encoding protobuf: %T is not a proto.Message", v)
+	}
+	return c.use(v).MarshalOptions.Marshal(msg)
+}
+
+func (c *Codec) Unmarshal(data []byte, v interface{}) error {
+	msg, ok := v.(proto.Message)
+	if !ok {
+		return fmt.Errorf("Error decoding protobuf: %T is not a proto.Message", v)
+	}
+	return c.use(v).UnmarshalOptions.Unmarshal(data, msg)
+}
+
+// EncodeJSON encodes msg as canonical protobuf JSON. protojson varies its
+// whitespace from build to build, so the output is compacted to keep it,
+// and its entity tag, stable.
+func (c *Codec) EncodeJSON(msg proto.Message) ([]byte, error) {
+	data, err := c.JSONMarshalOptions.Marshal(msg)
+	if err != nil {
+		return nil, err
+	}
+	var buf bytes.Buffer
+	if err := json.Compact(&buf, data); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// DecodeJSON decodes canonical protobuf JSON into msg.
+func (c *Codec) DecodeJSON(data []byte, msg proto.Message) error {
+	return c.JSONUnmarshalOptions.Unmarshal(data, msg)
+}
+
+// Message is a proto.Message as render.Render and codec.Bind see it: it
+// implements render.Offerer, and is read and written as canonical protobuf
+// JSON by the json codec.
+type Message struct {
+	proto.Message
+
+	// Codec reads and writes the message. If nil, Default does.
+	Codec *Codec
+
+	// Types are media types to offer after those of the protobuf
+	// encodings, such as a vendor type for the message. Types with a +json
+	// suffix are written as canonical protobuf JSON.
+	Types []*mtrest.MediaType
+}
+
+func (m Message) codec() *Codec {
+	return m.Codec
+}
+
+// Offers implements render.Offerer. It offers JSON, protobuf JSON and the
+// binary encoding, the latter two with a proto parameter naming the message,
+// followed by Types.
+func (m Message) Offers() []*mtrest.MediaType {
+	offers := []*mtrest.MediaType{&mtrest.ApplicationJSON}
+	for _, t := range []*mtrest.MediaType{&ApplicationXProtobufJSON, &ApplicationProtobufJSON, &mtrest.ApplicationXProtobuf, &mtrest.ApplicationProtobuf} {
+		offers = append(offers, MediaType(t, m.Message))
+	}
+	return append(offers, m.Types...)
+}
+
+// MarshalJSON implements json.Marshaler with the Codec of m.
+func (m Message) MarshalJSON() ([]byte, error) {
+	return Default.use(m).EncodeJSON(m.Message)
+}
+
+// UnmarshalJSON implements json.Unmarshaler with the Codec of m.
+func (m Message) UnmarshalJSON(data []byte) error {
+	return Default.use(m).DecodeJSON(data, m.Message)
+}
+
+// negotiated is a Message that offers only the media type Render negotiated
+// for it, or none at all.
+type negotiated struct {
+	Message
+	offer *mtrest.MediaType
+}
+
+func (n negotiated) Offers() []*mtrest.MediaType {
+	if n.offer == nil {
+		return nil
+	}
+	return []*mtrest.MediaType{n.offer}
+}
+
+// Render writes msg with render.Render, as a Message unless it already is
+// one. Accept ranges whose proto or messageType parameter names another
+// message are ignored, so that a client pinned to a different message is
+// answered 406 Not Acceptable.
+func Render(w http.ResponseWriter, r *http.Request, status int, msg proto.Message) error {
+	m := wrap(msg)
+	headers.AddVary(w.Header(), "Accept")
+	return render.Render(w, r, status, negotiated{m, negotiate(r, m.Offers(), fullName(m.Message))})
+}
+
+// negotiate is render.Negotiate, ignoring Accept ranges that name a message
+// other than name.
+func negotiate(r *http.Request, offers []*mtrest.MediaType, name string) *mtrest.MediaType {
+	accepts, err := headers.NewAccepts(strings.Join(r.Header["Accept"], ","))
+	if err != nil || len(accepts) == 0 {
+		return render.Negotiate(r, offers)
+	}
+	ranges := headers.Accepts{}
+	for _, a := range accepts {
+		if n := MessageName(a); n == "" || n == name {
+			ranges = append(ranges, a)
+		}
+	}
+	return ranges.BestMatch(offers)
+}
+
+// Bind decodes the body of r into msg with codec.Bind, as canonical
+// protobuf JSON if the body is JSON. msg may be a Message to decode it with
+// a particular Codec. A Content-Type naming a message other than msg is
+// unsupported.
+func Bind(r *http.Request, msg proto.Message) error {
+	m := wrap(msg)
+	if ct, err := mtrest.NewMediaType(r.Header.Get("Content-Type")); err == nil {
+		if name := MessageName(ct); name != "" && name != fullName(m.Message) {
+			return &codec.UnsupportedMediaTypeError{MediaType: ct}
+		}
+	}
+	return codec.Bind(r, &m)
+}
+
+// wrap returns msg as a Message.
+func wrap(msg proto.Message) Message {
+	switch m := msg.(type) {
+	case Message:
+		return m
+	case *Message:
+		return *m
+	}
+	return Message{Message: msg}
+}
+
+// params are the media type parameters that name a protobuf message, in
+// order of preference. Parameter names are parsed in lower case.
+var params = []string{"proto", "messagetype"}
+
+// MessageName returns the fully-qualified name of the protobuf message
+// described by m's proto or messageType parameter, or "" if it has neither.
+func MessageName(m *mtrest.MediaType) string {
+	for _, p := range params {
+		if name := m.Params[p]; name != "" {
+			return name
+		}
+	}
+	return ""
+}
+
+// MediaType returns a copy of m whose proto parameter names the message
+// type of msg, such as application/x-protobuf; proto=example.Widget.
+func MediaType(m *mtrest.MediaType, msg proto.Message) *mtrest.MediaType {
+	c := m.Clone()
+	c.Params["proto"] = fullName(msg)
+	return c
+}
+
+func fullName(msg proto.Message) string {
+	return string(msg.ProtoReflect().Descriptor().FullName())
+}
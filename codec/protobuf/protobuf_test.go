@@ -0,0 +1,185 @@
+// Copyright © 2017 Walter Scheper <walter.scheper@gmail.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package protobuf
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/wfscheper/mtrest"
+	"github.com/wfscheper/mtrest/codec"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+type widget struct {
+	Name string `json:"name"`
+}
+
+func enumValue(name string, number int32) *descriptorpb.EnumValueDescriptorProto {
+	return &descriptorpb.EnumValueDescriptorProto{Name: proto.String(name), Number: proto.Int32(number)}
+}
+
+func TestCodec(t *testing.T) {
+	for idx, mt := range []string{"application/x-protobuf", "application/protobuf; proto=google.protobuf.EnumValueDescriptorProto", "application/vnd.foo+protobuf"} {
+		m, err := mtrest.NewMediaType(mt)
+		if err != nil {
+			t.Fatalf("%d: %q", idx, err)
+		}
+		c, ok := codec.For(m)
+		if !ok {
+			t.Fatalf("%d: expected a codec for %s", idx, mt)
+		}
+		data, err := c.Marshal(enumValue("a", 1))
+		if err != nil {
+			t.Fatalf("%d: %q", idx, err)
+		}
+		if string(data) != "\x0a\x01a\x10\x01" {
+			t.Errorf("%d: expected %q, got %q", idx, "\x0a\x01a\x10\x01", data)
+		}
+		actual := &descriptorpb.EnumValueDescriptorProto{}
+		if err := c.Unmarshal(data, actual); err != nil {
+			t.Fatalf("%d: %q", idx, err)
+		}
+		if !proto.Equal(actual, enumValue("a", 1)) {
+			t.Errorf("%d: expected %v, got %v", idx, enumValue("a", 1), actual)
+		}
+	}
+
+	if _, err := Default.Marshal(widget{}); err == nil || err.Error() != "Error encoding protobuf: protobuf.widget is not a proto.Message" {
+		t.Errorf("expected an error encoding a widget, got %q", err)
+	}
+	if err := Default.Unmarshal(nil, &widget{}); err == nil || err.Error() != "Error decoding protobuf: *protobuf.widget is not a proto.Message" {
+		t.Errorf("expected an error decoding a widget, got %q", err)
+	}
+}
+
+func TestMediaType(t *testing.T) {
+	m := MediaType(&mtrest.ApplicationXProtobuf, enumValue("a", 1))
+	if actual := m.String(); actual != "application/x-protobuf; proto=google.protobuf.EnumValueDescriptorProto" {
+		t.Errorf("expected proto parameter, got '%s'", actual)
+	}
+	if len(mtrest.ApplicationXProtobuf.Params) != 0 {
+		t.Errorf("expected application/x-protobuf to be left alone, got %v", mtrest.ApplicationXProtobuf.Params)
+	}
+
+	tests := []struct {
+		mt       string
+		expected string
+	}{
+		{"application/x-protobuf", ""},
+		{"application/x-protobuf; proto=a.B", "a.B"},
+		{"application/x-protobuf; messageType=a.C", "a.C"},
+		{"application/x-protobuf; messageType=a.C; proto=a.B", "a.B"},
+	}
+	for idx, test := range tests {
+		m, err := mtrest.NewMediaType(test.mt)
+		if err != nil {
+			t.Fatalf("%d: %q", idx, err)
+		}
+		if actual := MessageName(m); actual != test.expected {
+			t.Errorf("%d: expected '%s', got '%s'", idx, test.expected, actual)
+		}
+	}
+}
+
+func TestRender(t *testing.T) {
+	msg := enumValue("A", 1)
+	vendor := &mtrest.MediaType{Type: "application", SubType: "vnd.acme.enum+json", Params: map[string]string{}, Weight: 1.0}
+	tests := []struct {
+		title, accept string
+		msg           proto.Message
+		status        int
+		contentType   string
+		body          string
+	}{
+		{"No Accept header", "", msg, 200, "application/json", `{"name":"A","number":1}`},
+		{"Binary", "application/x-protobuf", msg, 200, "application/x-protobuf; proto=google.protobuf.EnumValueDescriptorProto", "\x0a\x01A\x10\x01"},
+		{"Binary without x-", "application/protobuf", msg, 200, "application/protobuf; proto=google.protobuf.EnumValueDescriptorProto", "\x0a\x01A\x10\x01"},
+		{"Binary naming the message", "application/x-protobuf; proto=google.protobuf.EnumValueDescriptorProto", msg, 200, "application/x-protobuf; proto=google.protobuf.EnumValueDescriptorProto", "\x0a\x01A\x10\x01"},
+		{"Binary naming the message type", "application/protobuf; messageType=google.protobuf.EnumValueDescriptorProto", msg, 200, "application/protobuf; proto=google.protobuf.EnumValueDescriptorProto", "\x0a\x01A\x10\x01"},
+		{"Binary naming another message", "application/x-protobuf; proto=google.protobuf.FieldDescriptorProto", msg, 406, "application/problem+json", `{"status":406,"title":"Not Acceptable"}`},
+		{"Another message, or JSON", "application/x-protobuf; proto=google.protobuf.FieldDescriptorProto, application/json; q=0.5", msg, 200, "application/json", `{"name":"A","number":1}`},
+		{"Protobuf JSON", "application/x-protobuf+json", msg, 200, "application/x-protobuf+json; proto=google.protobuf.EnumValueDescriptorProto", `{"name":"A","number":1}`},
+		{"Protobuf JSON without x-", "application/protobuf+json; proto=google.protobuf.EnumValueDescriptorProto", msg, 200, "application/protobuf+json; proto=google.protobuf.EnumValueDescriptorProto", `{"name":"A","number":1}`},
+		{"Vendor type", "application/vnd.acme.enum+json", Message{Message: msg, Types: []*mtrest.MediaType{vendor}}, 200, "application/vnd.acme.enum+json", `{"name":"A","number":1}`},
+		{"Vendor type not offered", "application/vnd.acme.enum+json", msg, 406, "application/problem+json", `{"status":406,"title":"Not Acceptable"}`},
+		{"Not acceptable as XML", "application/xml", msg, 406, "application/problem+json", `{"status":406,"title":"Not Acceptable"}`},
+		{"JSON options of the codec", "", Message{Message: &descriptorpb.FieldDescriptorProto{JsonName: proto.String("a")}, Codec: &Codec{JSONMarshalOptions: protojson.MarshalOptions{UseProtoNames: true}}}, 200, "application/json", `{"json_name":"a"}`},
+		{"Default JSON options", "", &descriptorpb.FieldDescriptorProto{JsonName: proto.String("a")}, 200, "application/json", `{"jsonName":"a"}`},
+	}
+	for idx, test := range tests {
+		r := httptest.NewRequest("GET", "/", nil)
+		if test.accept != "" {
+			r.Header.Set("Accept", test.accept)
+		}
+		w := httptest.NewRecorder()
+		if err := Render(w, r, 200, test.msg); err != nil {
+			t.Fatalf("%d: (%s) %q", idx, test.title, err)
+		}
+		if w.Code != test.status {
+			t.Errorf("%d: (%s) expected status %d, got %d", idx, test.title, test.status, w.Code)
+		}
+		if actual := w.Header().Get("Content-Type"); actual != test.contentType {
+			t.Errorf("%d: (%s) expected Content-Type '%s', got '%s'", idx, test.title, test.contentType, actual)
+		}
+		if actual := w.Body.String(); actual != test.body {
+			t.Errorf("%d: (%s) expected body %q, got %q", idx, test.title, test.body, actual)
+		}
+		if actual := w.Header()["Vary"]; len(actual) != 1 || actual[0] != "Accept" {
+			t.Errorf("%d: (%s) expected Vary 'Accept', got %q", idx, test.title, actual)
+		}
+	}
+}
+
+func TestBind(t *testing.T) {
+	lenient := &Codec{JSONUnmarshalOptions: protojson.UnmarshalOptions{DiscardUnknown: true}}
+	tests := []struct {
+		contentType string
+		body        string
+		codec       *Codec
+		err         string
+	}{
+		{"application/x-protobuf", "\x0a\x01a\x10\x01", nil, ""},
+		{"application/x-protobuf; proto=google.protobuf.EnumValueDescriptorProto", "\x0a\x01a\x10\x01", nil, ""},
+		{"application/x-protobuf; messageType=google.protobuf.EnumValueDescriptorProto", "\x0a\x01a\x10\x01", nil, ""},
+		{"application/x-protobuf; proto=google.protobuf.FieldDescriptorProto", "\x0a\x01a\x10\x01", nil, "Unsupported media type: 'application/x-protobuf; proto=google.protobuf.FieldDescriptorProto'"},
+		{"application/json", `{"name":"a","number":1}`, nil, ""},
+		{"application/protobuf+json; proto=google.protobuf.EnumValueDescriptorProto", `{"name":"a","number":1}`, nil, ""},
+		{"application/json", `{"name":"a","number":1,"unknown":true}`, lenient, ""},
+		{"application/json", `{"name":"a","number":1,"unknown":true}`, nil, "unknown field"},
+	}
+	for idx, test := range tests {
+		r := httptest.NewRequest("POST", "/", strings.NewReader(test.body))
+		r.Header.Set("Content-Type", test.contentType)
+		actual := &descriptorpb.EnumValueDescriptorProto{}
+		var err error
+		if test.codec != nil {
+			err = Bind(r, &Message{Message: actual, Codec: test.codec})
+		} else {
+			err = Bind(r, actual)
+		}
+		switch {
+		case test.err == "" && err != nil:
+			t.Errorf("%d: unexpected error %q", idx, err)
+		case test.err == "" && !proto.Equal(actual, enumValue("a", 1)):
+			t.Errorf("%d: expected %v, got %v", idx, enumValue("a", 1), actual)
+		case test.err != "" && (err == nil || !strings.Contains(err.Error(), test.err)):
+			t.Errorf("%d: expected '%s', got %q", idx, test.err, err)
+		}
+	}
+}
@@ -0,0 +1,265 @@
+// Copyright © 2017 Walter Scheper <walter.scheper@gmail.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package collection serves paginated collection resources.
+package collection
+
+import (
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"net/url"
+	"strconv"
+
+	"github.com/wfscheper/mtrest/relations"
+	"github.com/wfscheper/mtrest/render"
+	"github.com/wfscheper/mtrest/router"
+)
+
+// Pagination is the strategy a Collection uses to divide its items into
+// pages.
+type Pagination int
+
+const (
+	// Offset pages through a collection with the page and size query
+	// parameters. Pages are numbered from 1.
+	Offset Pagination = iota
+
+	// Cursor pages through a collection with the cursor and size query
+	// parameters. Cursors are opaque strings produced by the Fetcher.
+	Cursor
+)
+
+// ErrInvalidCursor may be returned by a Fetcher that cannot decode the
+// cursor it was given. The client receives a 400 Bad Request problem.
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// Query describes the page of a collection requested by a client.
+type Query struct {
+	// Page is the number of the requested page, counting from 1. It is
+	// always 1 with Cursor pagination. It is small enough that the offset
+	// of the page's last item fits in an int.
+	Page int
+
+	// Size is the maximum number of items on the page.
+	Size int
+
+	// Cursor is the cursor given by the client, or empty for the first
+	// page. It is always empty with Offset pagination.
+	Cursor string
+}
+
+// Offset returns the index of the first item on the page.
+func (q Query) Offset() int {
+	return (q.Page - 1) * q.Size
+}
+
+// Result is a page of a collection.
+type Result[T any] struct {
+	Items []T
+
+	// Total is the number of items in the whole collection, or nil if it
+	// is not known. It is only used with Offset pagination.
+	Total *int
+
+	// Next and Prev are the cursors of the adjacent pages, or empty if there
+	// is no such page. They are only used with Cursor pagination.
+	Next string
+	Prev string
+}
+
+// Fetcher returns the page of a collection described by q. Errors that are
+// a *render.Problem are written as is.
+type Fetcher[T any] func(r *http.Request, q Query) (Result[T], error)
+
+// Collection is a resource that serves a collection of T one page at a
+// time. It implements router.Getter.
+type Collection[T any] struct {
+	Fetch      Fetcher[T]
+	Pagination Pagination
+
+	// DefaultSize is the page size used when the client does not ask for
+	// one, and MaxSize is the largest page size a client may ask for. Zero
+	// means DefaultSize and MaxSize respectively.
+	DefaultSize int
+	MaxSize     int
+}
+
+// The page sizes used by collections that do not set their own.
+const (
+	DefaultSize = 20
+	MaxSize     = 100
+)
+
+// New returns a Collection using Offset pagination with pages of
+// DefaultSize items, and at most MaxSize.
+func New[T any](fetch Fetcher[T]) *Collection[T] {
+	return &Collection[T]{
+		Fetch:       fetch,
+		Pagination:  Offset,
+		DefaultSize: DefaultSize,
+		MaxSize:     MaxSize,
+	}
+}
+
+func (c *Collection[T]) defaultSize() int {
+	if c.DefaultSize > 0 {
+		return c.DefaultSize
+	}
+	if max := c.maxSize(); max < DefaultSize {
+		return max
+	}
+	return DefaultSize
+}
+
+func (c *Collection[T]) maxSize() int {
+	if c.MaxSize > 0 {
+		return c.MaxSize
+	}
+	return MaxSize
+}
+
+// Page is the body of a collection response.
+type Page[T any] struct {
+	XMLName xml.Name     `json:"-" xml:"collection" yaml:"-"`
+	Links   render.Links `json:"_links" xml:"link" yaml:"_links"`
+	Total   *int         `json:"total,omitempty" xml:"total,attr,omitempty" yaml:"total,omitempty"`
+	Items   []T          `json:"items" xml:"item" yaml:"items"`
+}
+
+// Get renders the requested page, with links to the first, previous, next
+// and last pages in both the Link header and the body. Invalid query
+// parameters are answered with a 400 Bad Request problem.
+func (c *Collection[T]) Get(w http.ResponseWriter, r *http.Request) {
+	q, p := c.query(r.URL.Query())
+	if p != nil {
+		render.Error(w, r, p)
+		return
+	}
+	res, err := c.Fetch(r, q)
+	if errors.Is(err, ErrInvalidCursor) {
+		p = render.NewProblem(http.StatusBadRequest, "Invalid pagination parameters")
+		p.InvalidParams = []render.InvalidParam{{Name: "cursor", Reason: "is not a valid cursor"}}
+		err = p
+	}
+	if err != nil {
+		render.Error(w, r, err)
+		return
+	}
+
+	page := &Page[T]{Items: res.Items, Links: c.links(r, q, res)}
+	if page.Items == nil {
+		page.Items = []T{}
+	}
+	if c.Pagination == Offset {
+		page.Total = res.Total
+	}
+	page.Links.Header().AddTo(w.Header())
+	render.Render(w, r, http.StatusOK, page)
+}
+
+func (c *Collection[T]) query(values url.Values) (Query, *render.Problem) {
+	q := Query{Page: 1, Size: c.defaultSize()}
+	var invalid []render.InvalidParam
+	if v := values.Get("size"); v != "" {
+		size, err := strconv.Atoi(v)
+		if err != nil || size < 1 || size > c.maxSize() {
+			invalid = append(invalid, render.InvalidParam{
+				Name:   "size",
+				Reason: fmt.Sprintf("must be an integer between 1 and %d", c.maxSize()),
+			})
+		}
+		q.Size = size
+	}
+	switch c.Pagination {
+	case Offset:
+		if v := values.Get("page"); v != "" {
+			page, err := strconv.Atoi(v)
+			if err != nil || page < 1 {
+				invalid = append(invalid, render.InvalidParam{Name: "page", Reason: "must be a positive integer"})
+			} else if q.Size > 0 && page > maxPage(q.Size) {
+				invalid = append(invalid, render.InvalidParam{Name: "page", Reason: fmt.Sprintf("must be at most %d", maxPage(q.Size))})
+			}
+			q.Page = page
+		}
+	case Cursor:
+		q.Cursor = values.Get("cursor")
+	}
+	if len(invalid) > 0 {
+		p := render.NewProblem(http.StatusBadRequest, "Invalid pagination parameters")
+		p.InvalidParams = invalid
+		return Query{}, p
+	}
+	return q, nil
+}
+
+// maxPage returns the last page of the given size whose items, and the
+// number of the page after it, fit in an int.
+func maxPage(size int) int {
+	return (math.MaxInt - 1) / size
+}
+
+func (c *Collection[T]) links(r *http.Request, q Query, res Result[T]) render.Links {
+	lb := router.Links(r)
+	href := func(set map[string]string) string {
+		u := *r.URL
+		values := u.Query()
+		for k, v := range set {
+			if v == "" {
+				values.Del(k)
+			} else {
+				values.Set(k, v)
+			}
+		}
+		u.RawQuery = values.Encode()
+		return lb.URL(&u)
+	}
+	size := strconv.Itoa(q.Size)
+
+	links := render.Links{{Rel: relations.Self, Href: lb.URL(r.URL)}}
+	switch c.Pagination {
+	case Offset:
+		links = append(links, render.Link{Rel: relations.First, Href: href(map[string]string{"page": "1", "size": size})})
+		if q.Page > 1 {
+			links = append(links, render.Link{Rel: relations.Prev, Href: href(map[string]string{"page": strconv.Itoa(q.Page - 1), "size": size})})
+		}
+		last := -1
+		if res.Total != nil {
+			last = *res.Total / q.Size
+			if *res.Total%q.Size != 0 {
+				last++
+			}
+			if last < 1 {
+				last = 1
+			}
+		}
+		if last > q.Page || (last < 0 && len(res.Items) >= q.Size) {
+			links = append(links, render.Link{Rel: relations.Next, Href: href(map[string]string{"page": strconv.Itoa(q.Page + 1), "size": size})})
+		}
+		if last > 0 {
+			links = append(links, render.Link{Rel: relations.Last, Href: href(map[string]string{"page": strconv.Itoa(last), "size": size})})
+		}
+	case Cursor:
+		links = append(links, render.Link{Rel: relations.First, Href: href(map[string]string{"cursor": "", "size": size})})
+		if res.Prev != "" {
+			links = append(links, render.Link{Rel: relations.Prev, Href: href(map[string]string{"cursor": res.Prev, "size": size})})
+		}
+		if res.Next != "" {
+			links = append(links, render.Link{Rel: relations.Next, Href: href(map[string]string{"cursor": res.Next, "size": size})})
+		}
+	}
+	return links
+}
@@ -0,0 +1,233 @@
+// Copyright © 2017 Walter Scheper <walter.scheper@gmail.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package collection
+
+import (
+	"encoding/json"
+	"errors"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"github.com/wfscheper/mtrest/router"
+)
+
+type widget struct {
+	ID int `json:"id" xml:"id" yaml:"id"`
+}
+
+// widgets returns a Fetcher over n widgets, reporting the total if known.
+func widgets(n int, known bool) Fetcher[widget] {
+	return func(r *http.Request, q Query) (Result[widget], error) {
+		var res Result[widget]
+		if known {
+			res.Total = &n
+		}
+		for i := q.Offset(); i < n && i < q.Offset()+q.Size; i++ {
+			res.Items = append(res.Items, widget{i + 1})
+		}
+		return res, nil
+	}
+}
+
+func serve(c http.Handler, target string) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest("GET", target, nil)
+	c.ServeHTTP(w, r)
+	return w
+}
+
+func mount[T any](c *Collection[T]) *router.Router {
+	mux := router.New()
+	mux.Mount("/widgets{?page,size,cursor,color}", c)
+	return mux
+}
+
+func TestOffset(t *testing.T) {
+	tests := []struct {
+		title, target string
+		known         bool
+		body          string
+	}{
+		{"First page", "/widgets?size=2", true,
+			`{"_links":{"first":{"href":"http://example.com/widgets?page=1&size=2"},` +
+				`"last":{"href":"http://example.com/widgets?page=3&size=2"},` +
+				`"next":{"href":"http://example.com/widgets?page=2&size=2"},` +
+				`"self":{"href":"http://example.com/widgets?size=2"}},"total":5,"items":[{"id":1},{"id":2}]}`},
+		{"Last page", "/widgets?page=3&size=2", true,
+			`{"_links":{"first":{"href":"http://example.com/widgets?page=1&size=2"},` +
+				`"last":{"href":"http://example.com/widgets?page=3&size=2"},` +
+				`"prev":{"href":"http://example.com/widgets?page=2&size=2"},` +
+				`"self":{"href":"http://example.com/widgets?page=3&size=2"}},"total":5,"items":[{"id":5}]}`},
+		{"Unknown total", "/widgets?page=2&size=2&color=red", false,
+			`{"_links":{"first":{"href":"http://example.com/widgets?color=red&page=1&size=2"},` +
+				`"next":{"href":"http://example.com/widgets?color=red&page=3&size=2"},` +
+				`"prev":{"href":"http://example.com/widgets?color=red&page=1&size=2"},` +
+				`"self":{"href":"http://example.com/widgets?page=2&size=2&color=red"}},"items":[{"id":3},{"id":4}]}`},
+		{"Past the end", "/widgets?page=9", true,
+			`{"_links":{"first":{"href":"http://example.com/widgets?page=1&size=20"},` +
+				`"last":{"href":"http://example.com/widgets?page=1&size=20"},` +
+				`"prev":{"href":"http://example.com/widgets?page=8&size=20"},` +
+				`"self":{"href":"http://example.com/widgets?page=9"}},"total":5,"items":[]}`},
+	}
+	for idx, test := range tests {
+		w := serve(mount(New(widgets(5, test.known))), test.target)
+		if w.Code != 200 {
+			t.Errorf("%d: (%s) expected status 200, got %d", idx, test.title, w.Code)
+		}
+		if actual := w.Body.String(); actual != test.body {
+			t.Errorf("%d: (%s) expected body\n%s\ngot\n%s", idx, test.title, test.body, actual)
+		}
+	}
+
+	w := serve(mount(New(widgets(5, true))), "/widgets?page=2&size=2")
+	expected := []string{
+		`<http://example.com/widgets?page=2&size=2>; rel="self"`,
+		`<http://example.com/widgets?page=1&size=2>; rel="first"`,
+		`<http://example.com/widgets?page=1&size=2>; rel="prev"`,
+		`<http://example.com/widgets?page=3&size=2>; rel="next"`,
+		`<http://example.com/widgets?page=3&size=2>; rel="last"`,
+	}
+	if actual := w.Header()["Link"]; len(actual) != len(expected) {
+		t.Errorf("expected Link headers %q, got %q", expected, actual)
+	} else {
+		for i := range expected {
+			if actual[i] != expected[i] {
+				t.Errorf("%d: expected Link '%s', got '%s'", i, expected[i], actual[i])
+			}
+		}
+	}
+}
+
+func TestZeroValue(t *testing.T) {
+	tests := []struct {
+		c      *Collection[widget]
+		target string
+		status int
+		self   string
+		size   int
+	}{
+		{&Collection[widget]{Fetch: widgets(50, true)}, "/widgets", 200, "/widgets", 20},
+		{&Collection[widget]{Fetch: widgets(50, true)}, "/widgets?size=100", 200, "/widgets?size=100", 50},
+		{&Collection[widget]{Fetch: widgets(50, true)}, "/widgets?size=101", 400, "", 0},
+		{&Collection[widget]{Fetch: widgets(50, true), MaxSize: 5}, "/widgets", 200, "/widgets", 5},
+	}
+	for idx, test := range tests {
+		w := serve(mount(test.c), test.target)
+		if w.Code != test.status {
+			t.Errorf("%d: expected status %d, got %d: %s", idx, test.status, w.Code, w.Body)
+			continue
+		}
+		if test.status != 200 {
+			continue
+		}
+		var page struct {
+			Links map[string]struct{ Href string } `json:"_links"`
+			Items []widget                         `json:"items"`
+		}
+		if err := json.Unmarshal(w.Body.Bytes(), &page); err != nil {
+			t.Fatalf("%d: %q", idx, err)
+		}
+		if actual := page.Links["self"].Href; actual != "http://example.com"+test.self {
+			t.Errorf("%d: expected self '%s', got '%s'", idx, test.self, actual)
+		}
+		if len(page.Items) != test.size {
+			t.Errorf("%d: expected %d items, got %d", idx, test.size, len(page.Items))
+		}
+	}
+}
+
+func TestCursor(t *testing.T) {
+	c := New(func(r *http.Request, q Query) (Result[widget], error) {
+		start := 0
+		if q.Cursor != "" {
+			var err error
+			if start, err = strconv.Atoi(q.Cursor); err != nil {
+				return Result[widget]{}, ErrInvalidCursor
+			}
+		}
+		res := Result[widget]{Items: []widget{{start + 1}}, Next: strconv.Itoa(start + 1)}
+		if start > 0 {
+			res.Prev = strconv.Itoa(start - 1)
+		}
+		return res, nil
+	})
+	c.Pagination = Cursor
+
+	w := serve(mount(c), "/widgets?cursor=4&size=1")
+	expected := `{"_links":{"first":{"href":"http://example.com/widgets?size=1"},` +
+		`"next":{"href":"http://example.com/widgets?cursor=5&size=1"},` +
+		`"prev":{"href":"http://example.com/widgets?cursor=3&size=1"},` +
+		`"self":{"href":"http://example.com/widgets?cursor=4&size=1"}},"items":[{"id":5}]}`
+	if actual := w.Body.String(); actual != expected {
+		t.Errorf("expected body\n%s\ngot\n%s", expected, actual)
+	}
+
+	w = serve(mount(c), "/widgets?cursor=abc")
+	if w.Code != 400 {
+		t.Errorf("expected status 400, got %d", w.Code)
+	}
+	expected = `{"detail":"Invalid pagination parameters","invalid-params":[{"name":"cursor","reason":"is not a valid cursor"}],"status":400,"title":"Bad Request"}`
+	if actual := w.Body.String(); actual != expected {
+		t.Errorf("expected body '%s', got '%s'", expected, actual)
+	}
+}
+
+func TestInvalidParameters(t *testing.T) {
+	tests := []struct {
+		target, body string
+	}{
+		{"/widgets?page=0", `{"detail":"Invalid pagination parameters","invalid-params":[{"name":"page","reason":"must be a positive integer"}],"status":400,"title":"Bad Request"}`},
+		{"/widgets?page=a&size=101", `{"detail":"Invalid pagination parameters","invalid-params":[{"name":"size","reason":"must be an integer between 1 and 100"},{"name":"page","reason":"must be a positive integer"}],"status":400,"title":"Bad Request"}`},
+		{"/widgets?page=5&size=0", `{"detail":"Invalid pagination parameters","invalid-params":[{"name":"size","reason":"must be an integer between 1 and 100"}],"status":400,"title":"Bad Request"}`},
+		{"/widgets?page=" + strconv.Itoa(math.MaxInt/10) + "&size=20", `{"detail":"Invalid pagination parameters","invalid-params":[{"name":"page","reason":"must be at most ` + strconv.Itoa((math.MaxInt-1)/20) + `"}],"status":400,"title":"Bad Request"}`},
+	}
+	for idx, test := range tests {
+		w := serve(mount(New(widgets(5, true))), test.target)
+		if w.Code != 400 {
+			t.Errorf("%d: expected status 400, got %d", idx, w.Code)
+		}
+		if ct := w.Header().Get("Content-Type"); ct != "application/problem+json" {
+			t.Errorf("%d: expected application/problem+json, got '%s'", idx, ct)
+		}
+		if actual := w.Body.String(); actual != test.body {
+			t.Errorf("%d: expected body '%s', got '%s'", idx, test.body, actual)
+		}
+	}
+}
+
+func TestFetchError(t *testing.T) {
+	c := New(func(r *http.Request, q Query) (Result[widget], error) {
+		return Result[widget]{}, errors.New("boom")
+	})
+	if w := serve(mount(c), "/widgets"); w.Code != 500 {
+		t.Errorf("expected status 500, got %d", w.Code)
+	}
+}
+
+func TestXML(t *testing.T) {
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest("GET", "/widgets?size=1", nil)
+	r.Header.Set("Accept", "application/xml")
+	mount(New(widgets(1, true))).ServeHTTP(w, r)
+	expected := `<collection total="1"><link rel="self" href="http://example.com/widgets?size=1"></link>` +
+		`<link rel="first" href="http://example.com/widgets?page=1&amp;size=1"></link>` +
+		`<link rel="last" href="http://example.com/widgets?page=1&amp;size=1"></link><item><id>1</id></item></collection>`
+	if actual := w.Body.String(); actual != expected {
+		t.Errorf("expected body\n%s\ngot\n%s", expected, actual)
+	}
+}
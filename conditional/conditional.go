@@ -0,0 +1,254 @@
+// Copyright © 2017 Walter Scheper <walter.scheper@gmail.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package conditional evaluates RFC 7232 conditional requests, answering
+// them with 304 Not Modified or 412 Precondition Failed where appropriate.
+package conditional
+
+import (
+	"bytes"
+	"net/http"
+	"time"
+
+	"github.com/wfscheper/mtrest/headers"
+	"github.com/wfscheper/mtrest/render"
+)
+
+// Validators describe the current representation of a resource.
+type Validators struct {
+	// ETag is the entity tag of the current representation, or nil if it
+	// has none.
+	ETag *headers.ETag
+
+	// LastModified is when the current representation last changed, or the
+	// zero time if that is not known.
+	LastModified time.Time
+
+	// Exists is false if the resource has no current representation.
+	Exists bool
+}
+
+// ValidatorFunc returns the Validators of the resource targeted by r.
+type ValidatorFunc func(r *http.Request) (Validators, error)
+
+// conditionalHeaders are the request headers that make a request
+// conditional.
+var conditionalHeaders = []string{"If-Match", "If-None-Match", "If-Modified-Since", "If-Unmodified-Since"}
+
+// notModifiedHeaders are the response headers a 304 Not Modified response
+// keeps from the response it replaces, per RFC 7232, section 4.1.
+var notModifiedHeaders = []string{"Cache-Control", "Content-Location", "Date", "ETag", "Expires", "Last-Modified", "Vary"}
+
+// IsConditional reports whether r has any of the If-Match, If-None-Match,
+// If-Modified-Since or If-Unmodified-Since headers.
+func IsConditional(r *http.Request) bool {
+	for _, h := range conditionalHeaders {
+		if r.Header.Get(h) != "" {
+			return true
+		}
+	}
+	return false
+}
+
+// Evaluate checks the preconditions of r against v in the order given by
+// RFC 7232, section 6. It returns http.StatusNotModified or
+// http.StatusPreconditionFailed if the request should be answered with that
+// status, and 0 if it should proceed.
+//
+// A malformed If-Match header fails, since the client clearly meant to
+// guard the request. Malformed dates, and a malformed If-None-Match header,
+// are ignored.
+func Evaluate(r *http.Request, v Validators) int {
+	safe := r.Method == http.MethodGet || r.Method == http.MethodHead
+	if s := r.Header.Get("If-Match"); s != "" {
+		tags, err := headers.NewEntityTags(s)
+		if err != nil || !matches(tags, v, tags.StrongMatch) {
+			return http.StatusPreconditionFailed
+		}
+	} else if t, ok := parseTime(r.Header.Get("If-Unmodified-Since")); ok && v.Exists && !v.LastModified.IsZero() {
+		if v.LastModified.Truncate(time.Second).After(t) {
+			return http.StatusPreconditionFailed
+		}
+	}
+
+	if s := r.Header.Get("If-None-Match"); s != "" {
+		if tags, err := headers.NewEntityTags(s); err == nil && matches(tags, v, tags.WeakMatch) {
+			if safe {
+				return http.StatusNotModified
+			}
+			return http.StatusPreconditionFailed
+		}
+	} else if t, ok := parseTime(r.Header.Get("If-Modified-Since")); ok && safe && v.Exists && !v.LastModified.IsZero() {
+		if !v.LastModified.Truncate(time.Second).After(t) {
+			return http.StatusNotModified
+		}
+	}
+	return 0
+}
+
+func matches(tags headers.EntityTags, v Validators, match func(headers.ETag) bool) bool {
+	if !v.Exists {
+		return false
+	}
+	if tags.Any {
+		return true
+	}
+	return v.ETag != nil && match(*v.ETag)
+}
+
+func parseTime(s string) (time.Time, bool) {
+	if s == "" {
+		return time.Time{}, false
+	}
+	t, err := http.ParseTime(s)
+	return t, err == nil
+}
+
+// Handler returns a handler that evaluates the preconditions of conditional
+// requests before they reach h.
+//
+// If fn is not nil, it supplies the validators for every method. Otherwise
+// GET and HEAD requests are evaluated against the ETag and Last-Modified
+// headers of the response h produces, which is buffered to do so. Requests
+// with other methods are evaluated, before h changes the resource, against
+// the response h gives to a GET request for it; the resource has no current
+// representation unless that response succeeds.
+func Handler(h http.Handler, fn ValidatorFunc) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !IsConditional(r) {
+			h.ServeHTTP(w, r)
+			return
+		}
+		if fn != nil {
+			v, err := fn(r)
+			if err != nil {
+				render.Error(w, r, err)
+				return
+			}
+			if status := Evaluate(r, v); status != 0 {
+				respond(w, r, status, v)
+				return
+			}
+			h.ServeHTTP(w, r)
+			return
+		}
+
+		if r.Method != http.MethodGet && r.Method != http.MethodHead {
+			if status := Evaluate(r, probe(h, r)); status != 0 {
+				respond(w, r, status, Validators{})
+				return
+			}
+			h.ServeHTTP(w, r)
+			return
+		}
+
+		buf := &buffer{header: http.Header{}}
+		h.ServeHTTP(buf, r)
+		if buf.status == 0 {
+			buf.status = http.StatusOK
+		}
+		status := 0
+		if buf.status >= 200 && buf.status < 300 {
+			status = Evaluate(r, buf.validators())
+		}
+		switch status {
+		case http.StatusNotModified:
+			for _, k := range notModifiedHeaders {
+				k = http.CanonicalHeaderKey(k)
+				if v, ok := buf.header[k]; ok {
+					w.Header()[k] = v
+				}
+			}
+			w.WriteHeader(http.StatusNotModified)
+		case http.StatusPreconditionFailed:
+			respond(w, r, status, Validators{})
+		default:
+			for k, v := range buf.header {
+				w.Header()[k] = v
+			}
+			w.WriteHeader(buf.status)
+			w.Write(buf.body.Bytes())
+		}
+	})
+}
+
+// probe returns the validators of the resource targeted by r, taken from
+// the response h gives to an unconditional GET request for it.
+func probe(h http.Handler, r *http.Request) Validators {
+	get := r.Clone(r.Context())
+	get.Method = http.MethodGet
+	get.Body = http.NoBody
+	get.ContentLength = 0
+	for _, k := range append(conditionalHeaders, "Content-Type", "Content-Length", "If-Range", "Range") {
+		get.Header.Del(k)
+	}
+	buf := &buffer{header: http.Header{}}
+	h.ServeHTTP(buf, get)
+	if buf.status != 0 && (buf.status < 200 || buf.status >= 300) {
+		return Validators{}
+	}
+	return buf.validators()
+}
+
+// respond writes a 304 Not Modified response with the validators in v, or a
+// 412 Precondition Failed problem.
+func respond(w http.ResponseWriter, r *http.Request, status int, v Validators) {
+	if status == http.StatusNotModified {
+		if v.ETag != nil {
+			w.Header().Set("ETag", v.ETag.String())
+		}
+		if !v.LastModified.IsZero() {
+			w.Header().Set("Last-Modified", v.LastModified.UTC().Format(http.TimeFormat))
+		}
+		w.WriteHeader(status)
+		return
+	}
+	render.Error(w, r, render.NewProblem(status, ""))
+}
+
+// buffer is an http.ResponseWriter that holds a response until the
+// preconditions have been evaluated against it.
+type buffer struct {
+	header http.Header
+	status int
+	body   bytes.Buffer
+}
+
+func (b *buffer) Header() http.Header {
+	return b.header
+}
+
+func (b *buffer) WriteHeader(status int) {
+	if b.status == 0 {
+		b.status = status
+	}
+}
+
+func (b *buffer) Write(p []byte) (int, error) {
+	b.WriteHeader(http.StatusOK)
+	return b.body.Write(p)
+}
+
+func (b *buffer) validators() Validators {
+	v := Validators{Exists: true}
+	if s := b.header.Get("ETag"); s != "" {
+		if e, err := headers.NewETag(s); err == nil {
+			v.ETag = &e
+		}
+	}
+	if t, ok := parseTime(b.header.Get("Last-Modified")); ok {
+		v.LastModified = t
+	}
+	return v
+}
@@ -0,0 +1,200 @@
+// Copyright © 2017 Walter Scheper <walter.scheper@gmail.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package conditional
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/wfscheper/mtrest/headers"
+	"github.com/wfscheper/mtrest/render"
+)
+
+var (
+	modified = time.Date(2017, 6, 1, 12, 0, 0, 0, time.UTC)
+	before   = modified.Add(-time.Hour).Format(http.TimeFormat)
+	after    = modified.Add(time.Hour).Format(http.TimeFormat)
+	at       = modified.Format(http.TimeFormat)
+	current  = &headers.ETag{Tag: "v2"}
+)
+
+func TestEvaluate(t *testing.T) {
+	exists := Validators{ETag: current, LastModified: modified.Add(time.Millisecond), Exists: true}
+	weak := Validators{ETag: &headers.ETag{Tag: "v2", Weak: true}, Exists: true}
+	tests := []struct {
+		title   string
+		method  string
+		headers map[string]string
+		v       Validators
+		status  int
+	}{
+		{"Unconditional", "GET", nil, exists, 0},
+		{"If-Match matches", "PUT", map[string]string{"If-Match": `"v1", "v2"`}, exists, 0},
+		{"If-Match fails", "PUT", map[string]string{"If-Match": `"v1"`}, exists, 412},
+		{"If-Match needs a strong match", "PUT", map[string]string{"If-Match": `"v2"`}, weak, 412},
+		{"If-Match * with a representation", "PUT", map[string]string{"If-Match": "*"}, exists, 0},
+		{"If-Match * without a representation", "PUT", map[string]string{"If-Match": "*"}, Validators{}, 412},
+		{"Malformed If-Match", "PUT", map[string]string{"If-Match": "v2"}, exists, 412},
+		{"If-Unmodified-Since passes", "PUT", map[string]string{"If-Unmodified-Since": at}, exists, 0},
+		{"If-Unmodified-Since fails", "PUT", map[string]string{"If-Unmodified-Since": before}, exists, 412},
+		{"If-Unmodified-Since without a date", "PUT", map[string]string{"If-Unmodified-Since": before}, Validators{Exists: true}, 0},
+		{"Invalid If-Unmodified-Since", "PUT", map[string]string{"If-Unmodified-Since": "yesterday"}, exists, 0},
+		{"If-Match takes precedence over If-Unmodified-Since", "PUT", map[string]string{"If-Match": `"v2"`, "If-Unmodified-Since": before}, exists, 0},
+		{"If-None-Match matches on GET", "GET", map[string]string{"If-None-Match": `"v1", "v2"`}, exists, 304},
+		{"If-None-Match matches weakly", "HEAD", map[string]string{"If-None-Match": `W/"v2"`}, exists, 304},
+		{"If-None-Match matches on PUT", "PUT", map[string]string{"If-None-Match": `"v2"`}, exists, 412},
+		{"If-None-Match * without a representation", "PUT", map[string]string{"If-None-Match": "*"}, Validators{}, 0},
+		{"If-None-Match * with a representation", "PUT", map[string]string{"If-None-Match": "*"}, exists, 412},
+		{"If-None-Match does not match", "GET", map[string]string{"If-None-Match": `"v1"`}, exists, 0},
+		{"If-Modified-Since not modified", "GET", map[string]string{"If-Modified-Since": at}, exists, 304},
+		{"If-Modified-Since modified", "GET", map[string]string{"If-Modified-Since": before}, exists, 0},
+		{"If-Modified-Since in the future", "GET", map[string]string{"If-Modified-Since": after}, exists, 304},
+		{"If-Modified-Since ignored on PUT", "PUT", map[string]string{"If-Modified-Since": at}, exists, 0},
+		{"If-None-Match takes precedence over If-Modified-Since", "GET", map[string]string{"If-None-Match": `"v1"`, "If-Modified-Since": at}, exists, 0},
+		{"If-Match is checked before If-None-Match", "GET", map[string]string{"If-Match": `"v1"`, "If-None-Match": `"v2"`}, exists, 412},
+	}
+	for idx, test := range tests {
+		r := httptest.NewRequest(test.method, "/", nil)
+		for k, v := range test.headers {
+			r.Header.Set(k, v)
+		}
+		if actual := Evaluate(r, test.v); actual != test.status {
+			t.Errorf("%d: (%s) expected %d, got %d", idx, test.title, test.status, actual)
+		}
+	}
+}
+
+type widget struct {
+	Name string `json:"name"`
+}
+
+func TestHandlerBuffered(t *testing.T) {
+	var writes int
+	h := Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/new" && r.Method == http.MethodGet {
+			render.Error(w, r, render.NewProblem(http.StatusNotFound, ""))
+			return
+		}
+		if r.Method == http.MethodGet {
+			w.Header().Set("Cache-Control", "max-age=60")
+			w.Header().Set("X-Extra", "1")
+			w.Header().Set("Last-Modified", modified.Format(http.TimeFormat))
+			render.Render(w, r, http.StatusOK, widget{"a"})
+			return
+		}
+		writes++
+		w.WriteHeader(http.StatusNoContent)
+	}), nil)
+
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest("GET", "/", nil))
+	etag := w.Header().Get("ETag")
+	if w.Code != 200 || etag == "" || w.Body.String() != `{"name":"a"}` {
+		t.Fatalf("unexpected response: %d %q %s", w.Code, w.Header(), w.Body)
+	}
+
+	tests := []struct {
+		title   string
+		method  string
+		target  string
+		headers map[string]string
+		status  int
+	}{
+		{"Matching If-None-Match", "GET", "/", map[string]string{"If-None-Match": etag}, 304},
+		{"Other If-None-Match", "GET", "/", map[string]string{"If-None-Match": `"other"`}, 200},
+		{"If-Modified-Since", "GET", "/", map[string]string{"If-Modified-Since": at}, 304},
+		{"Failing If-Match", "GET", "/", map[string]string{"If-Match": `"other"`}, 412},
+		{"Matching If-Match on PUT", "PUT", "/", map[string]string{"If-Match": etag}, 204},
+		{"Failing If-Match on PUT", "PUT", "/", map[string]string{"If-Match": `"other"`}, 412},
+		{"If-Match on PUT of a missing resource", "PUT", "/new", map[string]string{"If-Match": "*"}, 412},
+		{"If-Unmodified-Since on DELETE", "DELETE", "/", map[string]string{"If-Unmodified-Since": before}, 412},
+		{"If-None-Match * on PUT of an existing resource", "PUT", "/", map[string]string{"If-None-Match": "*"}, 412},
+		{"If-None-Match * on PUT of a missing resource", "PUT", "/new", map[string]string{"If-None-Match": "*"}, 204},
+	}
+	for idx, test := range tests {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(test.method, test.target, nil)
+		for k, v := range test.headers {
+			r.Header.Set(k, v)
+		}
+		writes = 0
+		h.ServeHTTP(w, r)
+		if w.Code != test.status {
+			t.Errorf("%d: (%s) expected %d, got %d", idx, test.title, test.status, w.Code)
+		}
+		if test.status == 412 && writes != 0 {
+			t.Errorf("%d: (%s) expected the handler not to be called, got %d calls", idx, test.title, writes)
+		}
+		switch w.Code {
+		case 304:
+			if w.Body.Len() != 0 || w.Header().Get("ETag") != etag || w.Header().Get("Cache-Control") != "max-age=60" {
+				t.Errorf("%d: (%s) unexpected 304 response: %q %s", idx, test.title, w.Header(), w.Body)
+			}
+			if w.Header().Get("X-Extra") != "" || w.Header().Get("Content-Type") != "" {
+				t.Errorf("%d: (%s) expected 304 to drop other headers, got %q", idx, test.title, w.Header())
+			}
+		case 200:
+			if w.Body.String() != `{"name":"a"}` || w.Header().Get("X-Extra") != "1" {
+				t.Errorf("%d: (%s) unexpected 200 response: %q %s", idx, test.title, w.Header(), w.Body)
+			}
+		case 412:
+			if ct := w.Header().Get("Content-Type"); ct != "application/problem+json" {
+				t.Errorf("%d: (%s) expected a problem, got '%s'", idx, test.title, ct)
+			}
+		}
+	}
+}
+
+func TestHandlerValidatorFunc(t *testing.T) {
+	calls := 0
+	h := Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.WriteHeader(http.StatusNoContent)
+	}), func(r *http.Request) (Validators, error) {
+		if r.URL.Path == "/broken" {
+			return Validators{}, errors.New("boom")
+		}
+		return Validators{ETag: current, LastModified: modified, Exists: true}, nil
+	})
+
+	tests := []struct {
+		method, path, header, value string
+		status, calls               int
+	}{
+		{"PUT", "/", "If-Match", `"v2"`, 204, 1},
+		{"PUT", "/", "If-Match", `"v1"`, 412, 0},
+		{"GET", "/", "If-None-Match", `"v2"`, 304, 0},
+		{"GET", "/", "", "", 204, 1},
+		{"PUT", "/broken", "If-Match", `"v2"`, 500, 0},
+	}
+	for idx, test := range tests {
+		calls = 0
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(test.method, test.path, nil)
+		if test.header != "" {
+			r.Header.Set(test.header, test.value)
+		}
+		h.ServeHTTP(w, r)
+		if w.Code != test.status || calls != test.calls {
+			t.Errorf("%d: expected %d after %d calls, got %d after %d", idx, test.status, test.calls, w.Code, calls)
+		}
+		if w.Code == 304 && (w.Header().Get("ETag") != `"v2"` || w.Header().Get("Last-Modified") != at) {
+			t.Errorf("%d: expected validators on 304, got %q", idx, w.Header())
+		}
+	}
+}
@@ -0,0 +1,68 @@
+// Copyright © 2017 Walter Scheper <walter.scheper@gmail.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package conditional
+
+import (
+	"net/http"
+
+	"github.com/wfscheper/mtrest/render"
+)
+
+// Policy enforces optimistic concurrency on a resource: requests that would
+// change it must say which representation they were based on with If-Match,
+// so that concurrent writers cannot silently overwrite each other.
+type Policy struct {
+	// Methods lists the methods that must carry If-Match. RequireIfMatch
+	// sets it to PUT, PATCH and DELETE.
+	Methods []string
+
+	// Validators returns the validators of the resource's current
+	// representation. It must not be nil.
+	Validators ValidatorFunc
+}
+
+// RequireIfMatch returns a Policy that requires If-Match on PUT, PATCH and
+// DELETE requests, and checks it against the validators returned by fn.
+func RequireIfMatch(fn ValidatorFunc) *Policy {
+	return &Policy{
+		Methods:    []string{http.MethodPut, http.MethodPatch, http.MethodDelete},
+		Validators: fn,
+	}
+}
+
+// Handler returns a handler that answers requests covered by the policy
+// that lack If-Match with a 428 Precondition Required problem, and otherwise
+// evaluates every conditional request like Handler, using the policy's
+// Validators. Its signature suits router.With.
+func (p *Policy) Handler(h http.Handler) http.Handler {
+	next := Handler(h, p.Validators)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if p.requires(r.Method) && r.Header.Get("If-Match") == "" {
+			render.Error(w, r, render.NewProblem(http.StatusPreconditionRequired,
+				"This request must be made conditional with an If-Match header"))
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func (p *Policy) requires(method string) bool {
+	for _, m := range p.Methods {
+		if m == method {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,94 @@
+// Copyright © 2017 Walter Scheper <walter.scheper@gmail.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package conditional
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/wfscheper/mtrest/headers"
+	"github.com/wfscheper/mtrest/router"
+)
+
+type document struct {
+	etag    headers.ETag
+	deleted bool
+}
+
+func (res *document) Get(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("ETag", res.etag.String())
+	w.Write([]byte("widget"))
+}
+
+func (res *document) Put(w http.ResponseWriter, r *http.Request) {
+	res.etag = headers.ETag{Tag: res.etag.Tag + "+"}
+	w.Header().Set("ETag", res.etag.String())
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (res *document) Delete(w http.ResponseWriter, r *http.Request) {
+	res.deleted = true
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (res *document) validators(r *http.Request) (Validators, error) {
+	if res.deleted {
+		return Validators{}, nil
+	}
+	return Validators{ETag: &res.etag, Exists: true}, nil
+}
+
+func TestPolicy(t *testing.T) {
+	res := &document{etag: headers.ETag{Tag: "v1"}}
+	mux := router.New()
+	mux.Mount("/widgets/{id}", res, router.With(RequireIfMatch(res.validators).Handler))
+
+	tests := []struct {
+		title, method, ifMatch string
+		status                 int
+		body                   string
+	}{
+		{"Unconditional GET", "GET", "", 200, "widget"},
+		{"Unconditional PUT", "PUT", "", 428,
+			`{"detail":"This request must be made conditional with an If-Match header","status":428,"title":"Precondition Required"}`},
+		{"Matching PUT", "PUT", `"v1"`, 204, ""},
+		{"Stale PUT", "PUT", `"v1"`, 412, `{"status":412,"title":"Precondition Failed"}`},
+		{"Unconditional DELETE", "DELETE", "", 428, ""},
+		{"Weak DELETE", "DELETE", `W/"v1+"`, 412, ""},
+		{"Matching DELETE", "DELETE", `"v1+"`, 204, ""},
+		{"DELETE of a deleted document", "DELETE", "*", 412, ""},
+	}
+	for idx, test := range tests {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(test.method, "/widgets/1", nil)
+		if test.ifMatch != "" {
+			r.Header.Set("If-Match", test.ifMatch)
+		}
+		mux.ServeHTTP(w, r)
+		if w.Code != test.status {
+			t.Errorf("%d: (%s) expected %d, got %d", idx, test.title, test.status, w.Code)
+		}
+		if test.body != "" && w.Body.String() != test.body {
+			t.Errorf("%d: (%s) expected body '%s', got '%s'", idx, test.title, test.body, w.Body)
+		}
+	}
+
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, httptest.NewRequest("PATCH", "/widgets/1", nil))
+	if w.Code != 405 {
+		t.Errorf("expected unsupported methods to get 405, got %d", w.Code)
+	}
+}
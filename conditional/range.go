@@ -0,0 +1,150 @@
+// Copyright © 2017 Walter Scheper <walter.scheper@gmail.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package conditional
+
+import (
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/textproto"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/wfscheper/mtrest/headers"
+	"github.com/wfscheper/mtrest/render"
+)
+
+// ServeContent writes content, a representation of contentType described by
+// v, answering conditional requests and RFC 7233 range requests. A GET
+// request with a satisfiable Range header is answered with 206 Partial
+// Content, holding a single range or, for several ranges, a
+// multipart/byteranges body. If none of the ranges is satisfiable, the
+// response is a 416 Range Not Satisfiable problem.
+//
+// The Range header is ignored if it is malformed, is not in bytes, asks for
+// more than the whole representation, or if an If-Range header no longer
+// matches v.
+func ServeContent(w http.ResponseWriter, r *http.Request, contentType string, v Validators, content io.ReadSeeker) {
+	size, err := content.Seek(0, io.SeekEnd)
+	if err != nil {
+		render.Error(w, r, err)
+		return
+	}
+	v.Exists = true
+	if v.ETag != nil {
+		w.Header().Set("ETag", v.ETag.String())
+	}
+	if !v.LastModified.IsZero() {
+		w.Header().Set("Last-Modified", v.LastModified.UTC().Format(http.TimeFormat))
+	}
+	if status := Evaluate(r, v); status != 0 {
+		respond(w, r, status, v)
+		return
+	}
+	w.Header().Set("Accept-Ranges", "bytes")
+
+	var ranges [][2]int64
+	if s := r.Header.Get("Range"); s != "" && r.Method == http.MethodGet && IfRange(r, v) {
+		rg, err := headers.NewRange(s)
+		if err == nil && rg.Unit == "bytes" {
+			if ranges = satisfiable(rg, size); len(ranges) == 0 {
+				w.Header().Set("Content-Range", headers.ContentRange{Unit: "bytes", First: -1, Last: -1, Length: size}.String())
+				render.Error(w, r, render.NewProblem(http.StatusRequestedRangeNotSatisfiable, ""))
+				return
+			}
+			if total(ranges) > size {
+				ranges = nil
+			}
+		}
+	}
+
+	switch {
+	case len(ranges) == 1:
+		first, last := ranges[0][0], ranges[0][1]
+		w.Header().Set("Content-Range", headers.ContentRange{Unit: "bytes", First: first, Last: last, Length: size}.String())
+		w.Header().Set("Content-Type", contentType)
+		w.Header().Set("Content-Length", strconv.FormatInt(last-first+1, 10))
+		w.WriteHeader(http.StatusPartialContent)
+		copyRange(w, content, first, last)
+	case len(ranges) > 1:
+		mw := multipart.NewWriter(w)
+		w.Header().Set("Content-Type", "multipart/byteranges; boundary="+mw.Boundary())
+		w.WriteHeader(http.StatusPartialContent)
+		for _, rg := range ranges {
+			part, err := mw.CreatePart(textproto.MIMEHeader{
+				"Content-Type":  {contentType},
+				"Content-Range": {headers.ContentRange{Unit: "bytes", First: rg[0], Last: rg[1], Length: size}.String()},
+			})
+			if err != nil || copyRange(part, content, rg[0], rg[1]) != nil {
+				return
+			}
+		}
+		mw.Close()
+	default:
+		w.Header().Set("Content-Type", contentType)
+		w.Header().Set("Content-Length", strconv.FormatInt(size, 10))
+		w.WriteHeader(http.StatusOK)
+		if r.Method != http.MethodHead {
+			copyRange(w, content, 0, size-1)
+		}
+	}
+}
+
+// IfRange reports whether the If-Range header of r, if any, matches v, so
+// that its Range header should be honored. An entity tag must match v's
+// using the strong comparison, and a date must equal v's LastModified.
+func IfRange(r *http.Request, v Validators) bool {
+	s := strings.TrimSpace(r.Header.Get("If-Range"))
+	if s == "" {
+		return true
+	}
+	if strings.HasPrefix(s, `"`) || strings.HasPrefix(s, "W/") {
+		e, err := headers.NewETag(s)
+		return err == nil && v.ETag != nil && e.StrongMatch(*v.ETag)
+	}
+	t, ok := parseTime(s)
+	return ok && !v.LastModified.IsZero() && v.LastModified.Truncate(time.Second).Equal(t)
+}
+
+// satisfiable returns the bounds of the satisfiable ranges of rg within a
+// representation of size bytes.
+func satisfiable(rg headers.Range, size int64) [][2]int64 {
+	var ranges [][2]int64
+	for _, br := range rg.Ranges {
+		if first, last, ok := br.Bounds(size); ok {
+			ranges = append(ranges, [2]int64{first, last})
+		}
+	}
+	return ranges
+}
+
+// total returns the number of bytes in ranges. Overlapping ranges that add
+// up to more than the representation are not worth serving piecemeal.
+func total(ranges [][2]int64) int64 {
+	var n int64
+	for _, rg := range ranges {
+		n += rg[1] - rg[0] + 1
+	}
+	return n
+}
+
+func copyRange(w io.Writer, content io.ReadSeeker, first, last int64) error {
+	if _, err := content.Seek(first, io.SeekStart); err != nil {
+		return err
+	}
+	_, err := io.CopyN(w, content, last-first+1)
+	return err
+}
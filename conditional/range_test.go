@@ -0,0 +1,106 @@
+// Copyright © 2017 Walter Scheper <walter.scheper@gmail.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package conditional
+
+import (
+	"io/ioutil"
+	"mime"
+	"mime/multipart"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestServeContent(t *testing.T) {
+	const content = "0123456789abcdefghij"
+	v := Validators{ETag: current, LastModified: modified}
+	tests := []struct {
+		title    string
+		method   string
+		headers  map[string]string
+		status   int
+		expected string
+		cr       string
+	}{
+		{"Full content", "GET", nil, 200, content, ""},
+		{"HEAD", "HEAD", map[string]string{"Range": "bytes=0-4"}, 200, "", ""},
+		{"Single range", "GET", map[string]string{"Range": "bytes=0-4"}, 206, "01234", "bytes 0-4/20"},
+		{"Open range", "GET", map[string]string{"Range": "bytes=15-"}, 206, "fghij", "bytes 15-19/20"},
+		{"Suffix range", "GET", map[string]string{"Range": "bytes=-3"}, 206, "hij", "bytes 17-19/20"},
+		{"Range past the end", "GET", map[string]string{"Range": "bytes=18-100"}, 206, "ij", "bytes 18-19/20"},
+		{"Unsatisfiable ranges are dropped", "GET", map[string]string{"Range": "bytes=30-40, 1-2"}, 206, "12", "bytes 1-2/20"},
+		{"Unsatisfiable", "GET", map[string]string{"Range": "bytes=20-"}, 416, `"status":416`, "bytes */20"},
+		{"Malformed range", "GET", map[string]string{"Range": "bytes=4-1"}, 200, content, ""},
+		{"Other unit", "GET", map[string]string{"Range": "rows=1-2"}, 200, content, ""},
+		{"Overlapping ranges", "GET", map[string]string{"Range": "bytes=0-15, 5-"}, 200, content, ""},
+		{"If-Range entity tag matches", "GET", map[string]string{"Range": "bytes=0-1", "If-Range": `"v2"`}, 206, "01", "bytes 0-1/20"},
+		{"If-Range entity tag differs", "GET", map[string]string{"Range": "bytes=0-1", "If-Range": `"v1"`}, 200, content, ""},
+		{"If-Range weak entity tag", "GET", map[string]string{"Range": "bytes=0-1", "If-Range": `W/"v2"`}, 200, content, ""},
+		{"If-Range date matches", "GET", map[string]string{"Range": "bytes=0-1", "If-Range": at}, 206, "01", "bytes 0-1/20"},
+		{"If-Range date differs", "GET", map[string]string{"Range": "bytes=0-1", "If-Range": before}, 200, content, ""},
+		{"Not modified", "GET", map[string]string{"Range": "bytes=0-1", "If-None-Match": `"v2"`}, 304, "", ""},
+		{"Precondition failed", "GET", map[string]string{"Range": "bytes=0-1", "If-Match": `"v1"`}, 412, `"status":412`, ""},
+	}
+	for idx, test := range tests {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(test.method, "/export", nil)
+		for k, v := range test.headers {
+			r.Header.Set(k, v)
+		}
+		ServeContent(w, r, "text/csv", v, strings.NewReader(content))
+		if w.Code != test.status {
+			t.Errorf("%d: (%s) expected %d, got %d", idx, test.title, test.status, w.Code)
+		}
+		if !strings.Contains(w.Body.String(), test.expected) || (test.status != 416 && test.status != 412 && w.Body.String() != test.expected) {
+			t.Errorf("%d: (%s) expected body '%s', got '%s'", idx, test.title, test.expected, w.Body)
+		}
+		if actual := w.Header().Get("Content-Range"); actual != test.cr {
+			t.Errorf("%d: (%s) expected Content-Range '%s', got '%s'", idx, test.title, test.cr, actual)
+		}
+		if w.Header().Get("ETag") != `"v2"` {
+			t.Errorf("%d: (%s) expected ETag, got %q", idx, test.title, w.Header())
+		}
+	}
+}
+
+func TestServeContentMultipart(t *testing.T) {
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest("GET", "/export", nil)
+	r.Header.Set("Range", "bytes=0-1, -2")
+	ServeContent(w, r, "text/csv", Validators{LastModified: modified.Add(time.Second)}, strings.NewReader("0123456789"))
+	if w.Code != 206 {
+		t.Fatalf("expected 206, got %d", w.Code)
+	}
+	mt, params, err := mime.ParseMediaType(w.Header().Get("Content-Type"))
+	if err != nil || mt != "multipart/byteranges" {
+		t.Fatalf("expected multipart/byteranges, got '%s'", w.Header().Get("Content-Type"))
+	}
+	expected := []struct{ cr, body string }{{"bytes 0-1/10", "01"}, {"bytes 8-9/10", "89"}}
+	mr := multipart.NewReader(w.Body, params["boundary"])
+	for idx, e := range expected {
+		part, err := mr.NextPart()
+		if err != nil {
+			t.Fatalf("%d: %q", idx, err)
+		}
+		body, _ := ioutil.ReadAll(part)
+		if part.Header.Get("Content-Type") != "text/csv" || part.Header.Get("Content-Range") != e.cr || string(body) != e.body {
+			t.Errorf("%d: expected %s '%s', got %q '%s'", idx, e.cr, e.body, part.Header, body)
+		}
+	}
+	if _, err := mr.NextPart(); err == nil {
+		t.Error("expected no more parts")
+	}
+}
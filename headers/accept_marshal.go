@@ -0,0 +1,115 @@
+// Copyright © 2017 Walter Scheper <walter.scheper@gmail.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package headers
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// String returns a formatted as an Accept header value.
+func (a Accepts) String() string {
+	parts := make([]string, len(a))
+	for i, mt := range a {
+		parts[i] = mt.AcceptString()
+	}
+	return strings.Join(parts, ", ")
+}
+
+// MarshalText implements encoding.TextMarshaler.
+func (a Accepts) MarshalText() ([]byte, error) {
+	return []byte(a.String()), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler. Empty text results in an
+// empty Accepts.
+func (a *Accepts) UnmarshalText(text []byte) error {
+	if strings.TrimSpace(string(text)) == "" {
+		*a = nil
+		return nil
+	}
+	accepts, err := NewAccepts(string(text))
+	if err != nil {
+		return err
+	}
+	*a = accepts
+	return nil
+}
+
+// MarshalBinary implements encoding.BinaryMarshaler. The binary form is the
+// same as the text form.
+func (a Accepts) MarshalBinary() ([]byte, error) {
+	return a.MarshalText()
+}
+
+// UnmarshalBinary implements encoding.BinaryUnmarshaler.
+func (a *Accepts) UnmarshalBinary(data []byte) error {
+	return a.UnmarshalText(data)
+}
+
+// MarshalJSON implements json.Marshaler. The list is encoded as a single JSON
+// string in Accept header form.
+func (a Accepts) MarshalJSON() ([]byte, error) {
+	return json.Marshal(a.String())
+}
+
+// UnmarshalJSON implements json.Unmarshaler. A JSON null leaves a unchanged.
+func (a *Accepts) UnmarshalJSON(data []byte) error {
+	if string(data) == "null" {
+		return nil
+	}
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return err
+	}
+	return a.UnmarshalText([]byte(s))
+}
+
+// Set implements flag.Value. Each call appends to a, so a flag may be repeated
+// or given a comma-separated list.
+func (a *Accepts) Set(s string) error {
+	accepts, err := NewAccepts(s)
+	if err != nil {
+		return err
+	}
+	*a = append(*a, accepts...)
+	return nil
+}
+
+// Scan implements sql.Scanner. A NULL value results in an empty Accepts.
+func (a *Accepts) Scan(src interface{}) error {
+	switch v := src.(type) {
+	case nil:
+		*a = nil
+		return nil
+	case string:
+		return a.UnmarshalText([]byte(v))
+	case []byte:
+		return a.UnmarshalText(v)
+	default:
+		return fmt.Errorf("Error scanning accepts: unsupported type %T", src)
+	}
+}
+
+// Value implements driver.Valuer. An empty Accepts is stored as NULL, as
+// the zero MediaType is.
+func (a Accepts) Value() (driver.Value, error) {
+	if len(a) == 0 {
+		return nil, nil
+	}
+	return a.String(), nil
+}
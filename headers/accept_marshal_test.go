@@ -0,0 +1,160 @@
+// Copyright © 2017 Walter Scheper <walter.scheper@gmail.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package headers
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"flag"
+	"testing"
+)
+
+func TestAcceptsString(t *testing.T) {
+	tests := []struct {
+		in, expected string
+	}{
+		{"a/b", "a/b"},
+		{"a/b,c/d", "a/b, c/d"},
+		{"a/b;q=0.5, c/d;p=1", "a/b; q=0.5, c/d; p=1"},
+		{"a/b; q=0.5; e=1", "a/b; q=0.5; e=1"},
+	}
+	for i, test := range tests {
+		accepts, err := NewAccepts(test.in)
+		if err != nil {
+			t.Fatalf("%d: %q", i, err)
+		}
+		if actual := accepts.String(); actual != test.expected {
+			t.Errorf("%d: expected '%s', got '%s'", i, test.expected, actual)
+		}
+	}
+}
+
+func TestAcceptsMarshalText(t *testing.T) {
+	var a Accepts
+	if err := a.UnmarshalText([]byte("a/b; q=0.5, c/d")); err != nil {
+		t.Fatal(err)
+	}
+	if len(a) != 2 || a[0].Weight != 0.5 {
+		t.Fatalf("expected two media types, got %+v", a)
+	}
+	text, err := a.MarshalText()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(text) != "a/b; q=0.5, c/d" {
+		t.Errorf("expected 'a/b; q=0.5, c/d', got '%s'", text)
+	}
+	if err := a.UnmarshalText([]byte(" ")); err != nil || a != nil {
+		t.Errorf("expected empty accepts, got %+v and %v", a, err)
+	}
+	if err := a.UnmarshalText([]byte("a/")); err == nil {
+		t.Error("expected error, got nil")
+	}
+
+	data, _ := Accepts{}.MarshalBinary()
+	if err := a.UnmarshalBinary(data); err != nil || len(a) != 0 {
+		t.Errorf("expected empty accepts, got %+v and %v", a, err)
+	}
+}
+
+func TestAcceptsMarshalJSON(t *testing.T) {
+	type doc struct {
+		Accept Accepts `json:"accept"`
+	}
+	in := `{"accept":"application/json, application/yaml; q=0.5"}`
+	var d doc
+	if err := json.Unmarshal([]byte(in), &d); err != nil {
+		t.Fatal(err)
+	}
+	if len(d.Accept) != 2 {
+		t.Fatalf("expected two media types, got %+v", d.Accept)
+	}
+	out, err := json.Marshal(d)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(out) != in {
+		t.Errorf("expected '%s', got '%s'", in, out)
+	}
+	if err := json.Unmarshal([]byte(`{"accept":null}`), &d); err != nil || len(d.Accept) != 2 {
+		t.Errorf("expected null to leave accepts unchanged, got %+v and %v", d.Accept, err)
+	}
+}
+
+func TestAcceptsSet(t *testing.T) {
+	var a Accepts
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	fs.Var(&a, "accept", "accepted media types")
+	if err := fs.Parse([]string{"-accept", "a/b, c/d", "-accept", "e/f"}); err != nil {
+		t.Fatal(err)
+	}
+	if actual := a.String(); actual != "a/b, c/d, e/f" {
+		t.Errorf("expected 'a/b, c/d, e/f', got '%s'", actual)
+	}
+}
+
+func TestAcceptsScan(t *testing.T) {
+	var a Accepts
+	if err := a.Scan([]byte("a/b, c/d")); err != nil || len(a) != 2 {
+		t.Errorf("expected two media types, got %+v and %v", a, err)
+	}
+	if err := a.Scan("a/b"); err != nil || len(a) != 1 {
+		t.Errorf("expected one media type, got %+v and %v", a, err)
+	}
+	if err := a.Scan(nil); err != nil || a != nil {
+		t.Errorf("expected empty accepts, got %+v and %v", a, err)
+	}
+	if err := a.Scan(1); err == nil || err.Error() != "Error scanning accepts: unsupported type int" {
+		t.Errorf("expected 'Error scanning accepts: unsupported type int', got %v", err)
+	}
+
+	a, _ = NewAccepts("a/b; q=0.5")
+	if v, err := a.Value(); err != nil || v != "a/b; q=0.5" {
+		t.Errorf("expected 'a/b; q=0.5', got '%v' and %v", v, err)
+	}
+}
+
+func TestAcceptsValueRoundTrip(t *testing.T) {
+	tests := []struct {
+		in       Accepts
+		expected driver.Value
+	}{
+		{nil, nil},
+		{Accepts{}, nil},
+		{mustAccepts(t, "a/b; q=0.5, c/d"), "a/b; q=0.5, c/d"},
+	}
+	for idx, test := range tests {
+		v, err := test.in.Value()
+		if err != nil || v != test.expected {
+			t.Errorf("%d: expected '%v', got '%v' and %v", idx, test.expected, v, err)
+			continue
+		}
+		actual, _ := NewAccepts("x/y")
+		if err := actual.Scan(v); err != nil {
+			t.Errorf("%d: %q", idx, err)
+		} else if actual.String() != test.in.String() || (len(test.in) == 0 && actual != nil) {
+			t.Errorf("%d: expected '%s' to round-trip, got %#v", idx, test.in, actual)
+		}
+	}
+}
+
+func mustAccepts(t *testing.T, s string) Accepts {
+	t.Helper()
+	a, err := NewAccepts(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return a
+}
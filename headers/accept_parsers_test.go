@@ -58,10 +58,12 @@ func TestNewAcceptsErrors(t *testing.T) {
 }
 
 func TestBestMatch(t *testing.T) {
+	applicationJson, _ := mtrest.NewMediaType("application/json")
+	applicationYaml, _ := mtrest.NewMediaType("application/yaml")
 	textPlain, _ := mtrest.NewMediaType("text/plain")
 	offers := []*mtrest.MediaType{
-		&mtrest.ApplicationJson,
-		&mtrest.ApplicationYaml,
+		applicationJson,
+		applicationYaml,
 		textPlain,
 	}
 	applicationYamlQS, _ := mtrest.NewMediaType("application/yaml; q=0.4")
@@ -93,8 +95,8 @@ func TestBestMatch(t *testing.T) {
 	for i, test := range tests {
 		accepts, _ := NewAccepts(test.accepts)
 		actual := accepts.BestMatch(test.offers)
-		if test.expected != actual.String() {
-			t.Errorf("%d: expected %+v, got %+v", i, test.expected, actual.String())
+		if test.expected != actual.AcceptString() {
+			t.Errorf("%d: expected %+v, got %+v", i, test.expected, actual.AcceptString())
 		}
 	}
 }
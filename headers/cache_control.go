@@ -0,0 +1,209 @@
+// Copyright © 2017 Walter Scheper <walter.scheper@gmail.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package headers
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/wfscheper/mtrest/internal/lex"
+)
+
+// CacheControl holds the directives of an RFC 7234 Cache-Control header,
+// along with the RFC 5861 and RFC 8246 extensions. Some directives only
+// make sense in requests, and some only in responses. Durations have
+// second resolution; a nil duration is an absent directive.
+type CacheControl struct {
+	MaxAge  *time.Duration
+	SMaxAge *time.Duration
+
+	// MaxStale is the staleness a client will accept. MaxStaleAny is set
+	// for a max-stale directive without a value, which accepts any
+	// staleness.
+	MaxStale    *time.Duration
+	MaxStaleAny bool
+	MinFresh    *time.Duration
+
+	// NoCache is set for a no-cache directive. In responses, NoCacheFields
+	// may limit it to the named header fields.
+	NoCache       bool
+	NoCacheFields []string
+
+	// Private is set for a private directive. In responses, PrivateFields
+	// may limit it to the named header fields.
+	Private       bool
+	PrivateFields []string
+
+	Public          bool
+	NoStore         bool
+	NoTransform     bool
+	OnlyIfCached    bool
+	MustRevalidate  bool
+	ProxyRevalidate bool
+	Immutable       bool
+
+	StaleWhileRevalidate *time.Duration
+	StaleIfError         *time.Duration
+
+	// Extensions holds any other directives. Directives without a value
+	// map to the empty string.
+	Extensions map[string]string
+}
+
+// Seconds returns a pointer to a duration of n seconds, for setting the
+// durations of a CacheControl.
+func Seconds(n int) *time.Duration {
+	d := time.Duration(n) * time.Second
+	return &d
+}
+
+// NewCacheControl returns the CacheControl parsed from s, the value of a
+// Cache-Control header.
+func NewCacheControl(s string) (CacheControl, error) {
+	var cc CacheControl
+	for _, part := range splitQuoted(s, ',') {
+		if part == "" {
+			continue
+		}
+		name, value, err := splitPair(part)
+		if err != nil {
+			return CacheControl{}, err
+		}
+		hasValue := strings.Contains(part, "=")
+		switch name {
+		case "max-age":
+			cc.MaxAge, err = parseDelta(part, value)
+		case "s-maxage":
+			cc.SMaxAge, err = parseDelta(part, value)
+		case "max-stale":
+			if hasValue {
+				cc.MaxStale, err = parseDelta(part, value)
+			} else {
+				cc.MaxStaleAny = true
+			}
+		case "min-fresh":
+			cc.MinFresh, err = parseDelta(part, value)
+		case "stale-while-revalidate":
+			cc.StaleWhileRevalidate, err = parseDelta(part, value)
+		case "stale-if-error":
+			cc.StaleIfError, err = parseDelta(part, value)
+		case "no-cache":
+			cc.NoCache = true
+			cc.NoCacheFields = parseFields(value)
+		case "private":
+			cc.Private = true
+			cc.PrivateFields = parseFields(value)
+		case "public":
+			cc.Public = true
+		case "no-store":
+			cc.NoStore = true
+		case "no-transform":
+			cc.NoTransform = true
+		case "only-if-cached":
+			cc.OnlyIfCached = true
+		case "must-revalidate":
+			cc.MustRevalidate = true
+		case "proxy-revalidate":
+			cc.ProxyRevalidate = true
+		case "immutable":
+			cc.Immutable = true
+		default:
+			if cc.Extensions == nil {
+				cc.Extensions = map[string]string{}
+			}
+			cc.Extensions[name] = value
+		}
+		if err != nil {
+			return CacheControl{}, err
+		}
+	}
+	return cc, nil
+}
+
+// parseDelta parses delta-seconds. Values too large for an int32 are capped
+// at 2^31 seconds, as RFC 7234, section 1.2.1 requires.
+func parseDelta(directive, value string) (*time.Duration, error) {
+	if value == "" || strings.TrimLeft(value, "0123456789") != "" {
+		return nil, fmt.Errorf("Error parsing cache directive: '%s'", directive)
+	}
+	n, err := strconv.ParseInt(value, 10, 64)
+	if err != nil || n > math.MaxInt32 {
+		n = math.MaxInt32 + 1
+	}
+	d := time.Duration(n) * time.Second
+	return &d, nil
+}
+
+func parseFields(value string) []string {
+	var fields []string
+	for _, f := range strings.Split(value, ",") {
+		if f = strings.TrimSpace(f); f != "" {
+			fields = append(fields, f)
+		}
+	}
+	return fields
+}
+
+func (cc CacheControl) String() string {
+	var parts []string
+	flag := func(set bool, name string) {
+		if set {
+			parts = append(parts, name)
+		}
+	}
+	fields := func(set bool, name string, fields []string) {
+		if set && len(fields) > 0 {
+			parts = append(parts, name+`="`+strings.Join(fields, ", ")+`"`)
+		} else {
+			flag(set, name)
+		}
+	}
+	delta := func(d *time.Duration, name string) {
+		if d != nil {
+			parts = append(parts, name+"="+strconv.FormatInt(int64(*d/time.Second), 10))
+		}
+	}
+
+	flag(cc.Public, "public")
+	fields(cc.Private, "private", cc.PrivateFields)
+	fields(cc.NoCache, "no-cache", cc.NoCacheFields)
+	flag(cc.NoStore, "no-store")
+	flag(cc.NoTransform, "no-transform")
+	flag(cc.MustRevalidate, "must-revalidate")
+	flag(cc.ProxyRevalidate, "proxy-revalidate")
+	delta(cc.MaxAge, "max-age")
+	delta(cc.SMaxAge, "s-maxage")
+	if cc.MaxStaleAny {
+		parts = append(parts, "max-stale")
+	} else {
+		delta(cc.MaxStale, "max-stale")
+	}
+	delta(cc.MinFresh, "min-fresh")
+	flag(cc.OnlyIfCached, "only-if-cached")
+	flag(cc.Immutable, "immutable")
+	delta(cc.StaleWhileRevalidate, "stale-while-revalidate")
+	delta(cc.StaleIfError, "stale-if-error")
+	for _, k := range sortedKeys(cc.Extensions) {
+		if v := cc.Extensions[k]; v != "" {
+			parts = append(parts, k+"="+lex.Quote(v))
+		} else {
+			parts = append(parts, k)
+		}
+	}
+	return strings.Join(parts, ", ")
+}
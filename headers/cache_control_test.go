@@ -0,0 +1,91 @@
+// Copyright © 2017 Walter Scheper <walter.scheper@gmail.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package headers
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestNewCacheControl(t *testing.T) {
+	tests := []struct {
+		in       string
+		expected CacheControl
+	}{
+		{"", CacheControl{}},
+		{"max-age=60", CacheControl{MaxAge: Seconds(60)}},
+		{`Max-Age="60", S-MAXAGE=0`, CacheControl{MaxAge: Seconds(60), SMaxAge: Seconds(0)}},
+		{"public, immutable, max-age=31536000", CacheControl{Public: true, Immutable: true, MaxAge: Seconds(31536000)}},
+		{`no-cache="Set-Cookie, X-Token", private`, CacheControl{NoCache: true, NoCacheFields: []string{"Set-Cookie", "X-Token"}, Private: true}},
+		{`private="Authorization"`, CacheControl{Private: true, PrivateFields: []string{"Authorization"}}},
+		{"max-age=600, stale-while-revalidate=30, stale-if-error=86400",
+			CacheControl{MaxAge: Seconds(600), StaleWhileRevalidate: Seconds(30), StaleIfError: Seconds(86400)}},
+		{"max-stale", CacheControl{MaxStaleAny: true}},
+		{"max-stale=10, min-fresh=5, only-if-cached, no-transform",
+			CacheControl{MaxStale: Seconds(10), MinFresh: Seconds(5), OnlyIfCached: true, NoTransform: true}},
+		{"no-store, must-revalidate, proxy-revalidate", CacheControl{NoStore: true, MustRevalidate: true, ProxyRevalidate: true}},
+		{"max-age=99999999999", CacheControl{MaxAge: Seconds(2147483648)}},
+		{`community="UCI", foo`, CacheControl{Extensions: map[string]string{"community": "UCI", "foo": ""}}},
+	}
+	for i, test := range tests {
+		actual, err := NewCacheControl(test.in)
+		if err != nil {
+			t.Fatalf("%d: %q", i, err)
+		}
+		if !reflect.DeepEqual(actual, test.expected) {
+			t.Errorf("%d: expected %+v, got %+v", i, test.expected, actual)
+		}
+	}
+}
+
+func TestNewCacheControlErrors(t *testing.T) {
+	tests := []struct {
+		in, expected string
+	}{
+		{"max-age", "Error parsing cache directive: 'max-age'"},
+		{"max-age=-1", "Error parsing cache directive: 'max-age=-1'"},
+		{"s-maxage=1.5", "Error parsing cache directive: 's-maxage=1.5'"},
+		{`no-cache="a`, `Error parsing quoted-string: '"a'`},
+	}
+	for i, test := range tests {
+		_, err := NewCacheControl(test.in)
+		if err == nil || err.Error() != test.expected {
+			t.Errorf("%d: expected '%s', got '%v'", i, test.expected, err)
+		}
+	}
+}
+
+func TestCacheControlString(t *testing.T) {
+	tests := []string{
+		"",
+		"max-age=60",
+		"public, max-age=31536000, immutable",
+		`private="Authorization", no-cache="Set-Cookie, X-Token"`,
+		"no-store, no-transform, must-revalidate, proxy-revalidate",
+		"max-age=600, s-maxage=60, stale-while-revalidate=30, stale-if-error=86400",
+		"max-stale, min-fresh=5, only-if-cached",
+		"max-stale=10",
+		`community="a b", foo`,
+	}
+	for i, test := range tests {
+		cc, err := NewCacheControl(test)
+		if err != nil {
+			t.Fatalf("%d: %q", i, err)
+		}
+		if actual := cc.String(); actual != test {
+			t.Errorf("%d: expected '%s', got '%s'", i, test, actual)
+		}
+	}
+}
@@ -0,0 +1,155 @@
+// Copyright © 2017 Walter Scheper <walter.scheper@gmail.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package headers
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ETag is an RFC 7232 entity tag. Tag holds the opaque tag without its
+// quotes.
+type ETag struct {
+	Tag  string
+	Weak bool
+}
+
+// NewETag returns the ETag parsed from s, such as "xyzzy" or W/"xyzzy".
+func NewETag(s string) (ETag, error) {
+	e, rest, err := parseETag(strings.TrimSpace(s))
+	if err != nil {
+		return ETag{}, err
+	}
+	if rest != "" {
+		return ETag{}, fmt.Errorf("Error parsing entity tag: '%s'", s)
+	}
+	return e, nil
+}
+
+// parseETag parses the entity tag at the start of s, and returns the rest
+// of s.
+func parseETag(s string) (ETag, string, error) {
+	var e ETag
+	t := s
+	if strings.HasPrefix(t, "W/") {
+		e.Weak = true
+		t = t[2:]
+	}
+	if !strings.HasPrefix(t, `"`) {
+		return ETag{}, "", fmt.Errorf("Error parsing entity tag: '%s'", s)
+	}
+	end := strings.IndexByte(t[1:], '"')
+	if end < 0 {
+		return ETag{}, "", fmt.Errorf("Error parsing entity tag: '%s'", s)
+	}
+	e.Tag = t[1 : end+1]
+	for i := 0; i < len(e.Tag); i++ {
+		if c := e.Tag[i]; c < 0x21 || c == 0x7f {
+			return ETag{}, "", fmt.Errorf("Error parsing entity tag: '%s'", s)
+		}
+	}
+	return e, t[end+2:], nil
+}
+
+func (e ETag) String() string {
+	if e.Weak {
+		return `W/"` + e.Tag + `"`
+	}
+	return `"` + e.Tag + `"`
+}
+
+// StrongMatch reports whether e and o match using the strong comparison of
+// RFC 7232, section 2.3.2: both must be strong and have the same tag.
+func (e ETag) StrongMatch(o ETag) bool {
+	return !e.Weak && !o.Weak && e.Tag == o.Tag
+}
+
+// WeakMatch reports whether e and o have the same tag, whether or not either
+// is weak.
+func (e ETag) WeakMatch(o ETag) bool {
+	return e.Tag == o.Tag
+}
+
+// EntityTags is the value of an If-Match or If-None-Match header. Any is set
+// for the value "*", which matches any current representation.
+type EntityTags struct {
+	Any  bool
+	Tags []ETag
+}
+
+// NewEntityTags returns the EntityTags parsed from s, a comma-separated list
+// of entity tags or "*".
+func NewEntityTags(s string) (EntityTags, error) {
+	s = strings.TrimSpace(s)
+	if s == "*" {
+		return EntityTags{Any: true}, nil
+	}
+	var tags EntityTags
+	for s != "" {
+		if s[0] == ',' {
+			s = strings.TrimSpace(s[1:])
+			continue
+		}
+		e, rest, err := parseETag(s)
+		if err != nil {
+			return EntityTags{}, err
+		}
+		tags.Tags = append(tags.Tags, e)
+		s = strings.TrimSpace(rest)
+		if s != "" && s[0] != ',' {
+			return EntityTags{}, fmt.Errorf("Error parsing entity tag: '%s'", s)
+		}
+	}
+	return tags, nil
+}
+
+func (t EntityTags) String() string {
+	if t.Any {
+		return "*"
+	}
+	s := make([]string, len(t.Tags))
+	for i, e := range t.Tags {
+		s[i] = e.String()
+	}
+	return strings.Join(s, ", ")
+}
+
+// StrongMatch reports whether any tag in t strongly matches e, as required
+// by If-Match. "*" matches every entity tag.
+func (t EntityTags) StrongMatch(e ETag) bool {
+	if t.Any {
+		return true
+	}
+	for _, o := range t.Tags {
+		if o.StrongMatch(e) {
+			return true
+		}
+	}
+	return false
+}
+
+// WeakMatch reports whether any tag in t weakly matches e, as required by
+// If-None-Match. "*" matches every entity tag.
+func (t EntityTags) WeakMatch(e ETag) bool {
+	if t.Any {
+		return true
+	}
+	for _, o := range t.Tags {
+		if o.WeakMatch(e) {
+			return true
+		}
+	}
+	return false
+}
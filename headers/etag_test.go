@@ -0,0 +1,131 @@
+// Copyright © 2017 Walter Scheper <walter.scheper@gmail.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package headers
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestNewETag(t *testing.T) {
+	tests := []struct {
+		in       string
+		expected ETag
+	}{
+		{`"xyzzy"`, ETag{Tag: "xyzzy"}},
+		{`W/"xyzzy"`, ETag{Tag: "xyzzy", Weak: true}},
+		{`""`, ETag{}},
+		{` "a,b" `, ETag{Tag: "a,b"}},
+	}
+	for i, test := range tests {
+		actual, err := NewETag(test.in)
+		if err != nil {
+			t.Fatalf("%d: %q", i, err)
+		}
+		if actual != test.expected {
+			t.Errorf("%d: expected %+v, got %+v", i, test.expected, actual)
+		}
+	}
+
+	for i, in := range []string{"xyzzy", `w/"xyzzy"`, `"xyzzy`, `"xy zzy"`, `"a" "b"`} {
+		if _, err := NewETag(in); err == nil {
+			t.Errorf("%d: expected error parsing '%s', got nil", i, in)
+		}
+	}
+}
+
+func TestETagString(t *testing.T) {
+	for i, test := range []string{`"xyzzy"`, `W/"xyzzy"`, `""`} {
+		e, _ := NewETag(test)
+		if actual := e.String(); actual != test {
+			t.Errorf("%d: expected '%s', got '%s'", i, test, actual)
+		}
+	}
+}
+
+func TestETagMatch(t *testing.T) {
+	// RFC 7232, section 2.3.2
+	tests := []struct {
+		a, b         string
+		strong, weak bool
+	}{
+		{`W/"1"`, `W/"1"`, false, true},
+		{`W/"1"`, `W/"2"`, false, false},
+		{`W/"1"`, `"1"`, false, true},
+		{`"1"`, `"1"`, true, true},
+	}
+	for i, test := range tests {
+		a, _ := NewETag(test.a)
+		b, _ := NewETag(test.b)
+		if actual := a.StrongMatch(b); actual != test.strong {
+			t.Errorf("%d: expected strong match %t, got %t", i, test.strong, actual)
+		}
+		if actual := a.WeakMatch(b); actual != test.weak {
+			t.Errorf("%d: expected weak match %t, got %t", i, test.weak, actual)
+		}
+	}
+}
+
+func TestNewEntityTags(t *testing.T) {
+	tests := []struct {
+		in       string
+		expected EntityTags
+	}{
+		{"*", EntityTags{Any: true}},
+		{` * `, EntityTags{Any: true}},
+		{`"xyzzy"`, EntityTags{Tags: []ETag{{Tag: "xyzzy"}}}},
+		{`"xyzzy", "r2d2xxxx", "c3piozzzz"`, EntityTags{Tags: []ETag{{Tag: "xyzzy"}, {Tag: "r2d2xxxx"}, {Tag: "c3piozzzz"}}}},
+		{`W/"a,b",,"c"`, EntityTags{Tags: []ETag{{Tag: "a,b", Weak: true}, {Tag: "c"}}}},
+		{"", EntityTags{}},
+	}
+	for i, test := range tests {
+		actual, err := NewEntityTags(test.in)
+		if err != nil {
+			t.Fatalf("%d: %q", i, err)
+		}
+		if !reflect.DeepEqual(actual, test.expected) {
+			t.Errorf("%d: expected %+v, got %+v", i, test.expected, actual)
+		}
+	}
+
+	for i, in := range []string{`"a" "b"`, `"a", *`, `"a`} {
+		if _, err := NewEntityTags(in); err == nil {
+			t.Errorf("%d: expected error parsing '%s', got nil", i, in)
+		}
+	}
+}
+
+func TestEntityTags(t *testing.T) {
+	tags, _ := NewEntityTags(`"a", W/"b"`)
+	if actual := tags.String(); actual != `"a", W/"b"` {
+		t.Errorf(`expected '"a", W/"b"', got '%s'`, actual)
+	}
+	if !tags.StrongMatch(ETag{Tag: "a"}) {
+		t.Error(`expected "a" to match strongly`)
+	}
+	if tags.StrongMatch(ETag{Tag: "b"}) {
+		t.Error(`expected "b" not to match strongly`)
+	}
+	if !tags.WeakMatch(ETag{Tag: "b"}) {
+		t.Error(`expected "b" to match weakly`)
+	}
+	if tags.WeakMatch(ETag{Tag: "c"}) {
+		t.Error(`expected "c" not to match`)
+	}
+	star := EntityTags{Any: true}
+	if !star.StrongMatch(ETag{Tag: "c", Weak: true}) || star.String() != "*" {
+		t.Error("expected * to match anything")
+	}
+}
@@ -0,0 +1,95 @@
+// Copyright © 2017 Walter Scheper <walter.scheper@gmail.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package headers
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/wfscheper/mtrest/internal/lex"
+)
+
+// ForwardedElement is a single proxy hop in a Forwarded header. Parameters
+// other than by, for, host and proto are kept in Extensions.
+type ForwardedElement struct {
+	By         string
+	For        string
+	Host       string
+	Proto      string
+	Extensions map[string]string
+}
+
+// Forwarded is a parsed RFC 7239 Forwarded header. The first element was
+// added by the proxy closest to the client.
+type Forwarded []ForwardedElement
+
+// NewForwarded returns a Forwarded list constructed from s, the values of one
+// or more Forwarded headers joined by commas.
+func NewForwarded(s string) (Forwarded, error) {
+	var forwarded Forwarded
+	for _, element := range splitQuoted(s, ',') {
+		if element == "" {
+			continue
+		}
+		var e ForwardedElement
+		for _, pair := range splitQuoted(element, ';') {
+			if pair == "" {
+				continue
+			}
+			if strings.IndexByte(pair, '=') < 0 {
+				return nil, fmt.Errorf("Error parsing forwarded pair: '%s'", pair)
+			}
+			name, value, err := splitPair(pair)
+			if err != nil {
+				return nil, err
+			}
+			switch name {
+			case "by":
+				e.By = value
+			case "for":
+				e.For = value
+			case "host":
+				e.Host = value
+			case "proto":
+				e.Proto = strings.ToLower(value)
+			default:
+				if e.Extensions == nil {
+					e.Extensions = map[string]string{}
+				}
+				e.Extensions[name] = value
+			}
+		}
+		forwarded = append(forwarded, e)
+	}
+	return forwarded, nil
+}
+
+// String returns f formatted as a Forwarded header value.
+func (f Forwarded) String() string {
+	elements := make([]string, len(f))
+	for i, e := range f {
+		var pairs []string
+		for _, p := range [][2]string{{"by", e.By}, {"for", e.For}, {"host", e.Host}, {"proto", e.Proto}} {
+			if p[1] != "" {
+				pairs = append(pairs, p[0]+"="+lex.Quote(p[1]))
+			}
+		}
+		for _, k := range sortedKeys(e.Extensions) {
+			pairs = append(pairs, k+"="+lex.Quote(e.Extensions[k]))
+		}
+		elements[i] = strings.Join(pairs, ";")
+	}
+	return strings.Join(elements, ", ")
+}
@@ -0,0 +1,77 @@
+// Copyright © 2017 Walter Scheper <walter.scheper@gmail.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package headers
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestNewForwarded(t *testing.T) {
+	tests := []struct {
+		in       string
+		expected Forwarded
+	}{
+		{"", nil},
+		{"for=192.0.2.60", Forwarded{{For: "192.0.2.60"}}},
+		{`For="[2001:db8:cafe::17]:4711"`, Forwarded{{For: "[2001:db8:cafe::17]:4711"}}},
+		{"for=192.0.2.60;proto=HTTPS;by=203.0.113.43", Forwarded{{By: "203.0.113.43", For: "192.0.2.60", Proto: "https"}}},
+		{"for=192.0.2.43, for=198.51.100.17", Forwarded{{For: "192.0.2.43"}, {For: "198.51.100.17"}}},
+		{`host="example.com:8443";proto=https;secret="a,b"`, Forwarded{{Host: "example.com:8443", Proto: "https", Extensions: map[string]string{"secret": "a,b"}}}},
+		{"for=unknown;;proto=http,", Forwarded{{For: "unknown", Proto: "http"}}},
+	}
+	for i, test := range tests {
+		actual, err := NewForwarded(test.in)
+		if err != nil {
+			t.Fatalf("%d: %q", i, err)
+		}
+		if !reflect.DeepEqual(test.expected, actual) {
+			t.Errorf("%d: expected %+v, got %+v", i, test.expected, actual)
+		}
+	}
+}
+
+func TestNewForwardedErrors(t *testing.T) {
+	tests := []struct {
+		in, expected string
+	}{
+		{"for", "Error parsing forwarded pair: 'for'"},
+		{`for="192.0.2.60`, `Error parsing quoted-string: '"192.0.2.60'`},
+	}
+	for i, test := range tests {
+		_, err := NewForwarded(test.in)
+		if err == nil || err.Error() != test.expected {
+			t.Errorf("%d: expected '%s', got '%v'", i, test.expected, err)
+		}
+	}
+}
+
+func TestForwardedString(t *testing.T) {
+	tests := []string{
+		"for=192.0.2.60",
+		`for="[2001:db8:cafe::17]:4711";proto=https`,
+		`by=203.0.113.43;for=192.0.2.60;host=example.com;proto=http;secret="a b"`,
+		"for=192.0.2.43, for=198.51.100.17",
+	}
+	for i, test := range tests {
+		f, err := NewForwarded(test)
+		if err != nil {
+			t.Fatalf("%d: %q", i, err)
+		}
+		if actual := f.String(); actual != test {
+			t.Errorf("%d: expected '%s', got '%s'", i, test, actual)
+		}
+	}
+}
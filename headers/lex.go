@@ -0,0 +1,134 @@
+// Copyright © 2017 Walter Scheper <walter.scheper@gmail.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package headers
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/wfscheper/mtrest/internal/lex"
+)
+
+// splitQuoted splits s on sep, ignoring any sep inside a quoted-string or an
+// angle-bracketed URI reference. Each part is trimmed of whitespace.
+func splitQuoted(s string, sep byte) []string {
+	var parts []string
+	quoted, escaped, angled, start := false, false, false, 0
+	for i := 0; i < len(s); i++ {
+		switch c := s[i]; {
+		case escaped:
+			escaped = false
+		case quoted && c == '\\':
+			escaped = true
+		case c == '"' && !angled:
+			quoted = !quoted
+		case c == '<' && !quoted:
+			angled = true
+		case c == '>' && !quoted:
+			angled = false
+		case c == sep && !quoted && !angled:
+			parts = append(parts, strings.TrimSpace(s[start:i]))
+			start = i + 1
+		}
+	}
+	return append(parts, strings.TrimSpace(s[start:]))
+}
+
+// splitPair splits a name=value parameter, unquoting the value. The name is
+// returned in lower case.
+func splitPair(s string) (name, value string, err error) {
+	i := strings.IndexByte(s, '=')
+	if i < 0 {
+		return strings.ToLower(strings.TrimSpace(s)), "", nil
+	}
+	name = strings.ToLower(strings.TrimSpace(s[:i]))
+	value, err = lex.Unquote(strings.TrimSpace(s[i+1:]))
+	return name, value, err
+}
+
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// decodeExtValue decodes an RFC 8187 ext-value, such as UTF-8'en'%C2%A3, and
+// returns the value and its language tag. The UTF-8 and ISO-8859-1 charsets
+// are supported.
+func decodeExtValue(s string) (value, lang string, err error) {
+	parts := strings.SplitN(s, "'", 3)
+	if len(parts) != 3 {
+		return "", "", fmt.Errorf("Error parsing ext-value: '%s'", s)
+	}
+	charset, lang := strings.ToLower(parts[0]), parts[1]
+	var buf bytes.Buffer
+	for i := 0; i < len(parts[2]); i++ {
+		c := parts[2][i]
+		if c == '%' {
+			if i+2 >= len(parts[2]) {
+				return "", "", fmt.Errorf("Error parsing ext-value: '%s'", s)
+			}
+			b, err := strconv.ParseUint(parts[2][i+1:i+3], 16, 8)
+			if err != nil {
+				return "", "", fmt.Errorf("Error parsing ext-value: '%s'", s)
+			}
+			c = byte(b)
+			i += 2
+		} else if !isAttrChar(c) {
+			return "", "", fmt.Errorf("Error parsing ext-value: '%s'", s)
+		}
+		buf.WriteByte(c)
+	}
+	switch charset {
+	case "utf-8":
+		if !utf8.Valid(buf.Bytes()) {
+			return "", "", fmt.Errorf("Error parsing ext-value: '%s'", s)
+		}
+		return buf.String(), lang, nil
+	case "iso-8859-1":
+		runes := make([]rune, buf.Len())
+		for i, b := range buf.Bytes() {
+			runes[i] = rune(b)
+		}
+		return string(runes), lang, nil
+	default:
+		return "", "", fmt.Errorf("Error parsing ext-value: unsupported charset '%s'", parts[0])
+	}
+}
+
+// encodeExtValue encodes s as an RFC 8187 ext-value in UTF-8.
+func encodeExtValue(s, lang string) string {
+	var buf bytes.Buffer
+	buf.WriteString("UTF-8'" + lang + "'")
+	for i := 0; i < len(s); i++ {
+		if isAttrChar(s[i]) {
+			buf.WriteByte(s[i])
+		} else {
+			fmt.Fprintf(&buf, "%%%02X", s[i])
+		}
+	}
+	return buf.String()
+}
+
+func isAttrChar(c byte) bool {
+	return c >= 'A' && c <= 'Z' || c >= 'a' && c <= 'z' || c >= '0' && c <= '9' || strings.IndexByte("!#$&+-.^_`|~", c) >= 0
+}
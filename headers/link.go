@@ -0,0 +1,278 @@
+// Copyright © 2017 Walter Scheper <walter.scheper@gmail.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package headers
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/wfscheper/mtrest"
+	"github.com/wfscheper/mtrest/internal/lex"
+)
+
+// Link is a single web link from an RFC 8288 Link header.
+type Link struct {
+	// Target is the link target, a URI-Reference.
+	Target string
+
+	// Rel holds the link's relation types. Registered relation types are
+	// lower-cased; extension relation types are absolute URIs and are kept
+	// as given.
+	Rel []string
+
+	Anchor   string
+	Type     *mtrest.MediaType
+	Hreflang []string
+	Media    string
+
+	// Title is the link's title. When parsing, a title* parameter takes
+	// precedence over title, and its language is stored in TitleLang. When
+	// formatting, Title is written as title* if it is not ASCII or TitleLang
+	// is set.
+	Title     string
+	TitleLang string
+
+	// Params holds any other target attributes.
+	Params map[string]string
+}
+
+// Links is a list of web links, as found in one or more Link headers.
+type Links []Link
+
+// NewLinks returns a Links list constructed from s, a Link header value.
+func NewLinks(s string) (Links, error) {
+	var links Links
+	for _, part := range splitQuoted(s, ',') {
+		if part == "" {
+			continue
+		}
+		link, err := newLink(part)
+		if err != nil {
+			return nil, err
+		}
+		links = append(links, link)
+	}
+	return links, nil
+}
+
+// NewLinksFromHeader returns the links from every Link header in h, merged
+// in order into a single list.
+func NewLinksFromHeader(h http.Header) (Links, error) {
+	var links Links
+	for _, v := range h["Link"] {
+		l, err := NewLinks(v)
+		if err != nil {
+			return nil, err
+		}
+		links = append(links, l...)
+	}
+	return links, nil
+}
+
+func newLink(s string) (Link, error) {
+	parts := splitQuoted(s, ';')
+	target := parts[0]
+	if len(target) < 2 || target[0] != '<' || target[len(target)-1] != '>' {
+		return Link{}, fmt.Errorf("Error parsing link target: '%s'", target)
+	}
+	link := Link{Target: strings.TrimSpace(target[1 : len(target)-1])}
+
+	// per RFC 8288, only the first occurrence of most parameters counts
+	seen := map[string]bool{}
+	hasTitleStar := false
+	for _, param := range parts[1:] {
+		if param == "" {
+			continue
+		}
+		name, value, err := splitPair(param)
+		if err != nil {
+			return Link{}, err
+		}
+		if name != "hreflang" && seen[name] {
+			continue
+		}
+		seen[name] = true
+		switch name {
+		case "rel":
+			for _, rel := range strings.Fields(value) {
+				if !strings.Contains(rel, ":") {
+					rel = strings.ToLower(rel)
+				}
+				link.Rel = append(link.Rel, rel)
+			}
+		case "anchor":
+			link.Anchor = value
+		case "type":
+			if link.Type, err = mtrest.NewMediaType(value); err != nil {
+				return Link{}, err
+			}
+		case "hreflang":
+			link.Hreflang = append(link.Hreflang, value)
+		case "media":
+			link.Media = value
+		case "title":
+			if !hasTitleStar {
+				link.Title = value
+			}
+		case "title*":
+			if link.Title, link.TitleLang, err = decodeExtValue(value); err != nil {
+				return Link{}, err
+			}
+			hasTitleStar = true
+		default:
+			if link.Params == nil {
+				link.Params = map[string]string{}
+			}
+			link.Params[name] = value
+		}
+	}
+	return link, nil
+}
+
+// HasRel reports whether l has the relation type rel. Registered relation
+// types are compared case-insensitively.
+func (l Link) HasRel(rel string) bool {
+	for _, r := range l.Rel {
+		if r == rel || (!strings.Contains(rel, ":") && strings.EqualFold(r, rel)) {
+			return true
+		}
+	}
+	return false
+}
+
+// URL resolves the link target against base, which should be the URL of the
+// response the link was found in.
+func (l Link) URL(base *url.URL) (*url.URL, error) {
+	u, err := url.Parse(l.Target)
+	if err != nil {
+		return nil, err
+	}
+	if base == nil {
+		return u, nil
+	}
+	return base.ResolveReference(u), nil
+}
+
+// String returns l formatted as a Link header value.
+func (l Link) String() string {
+	parts := []string{"<" + l.Target + ">"}
+	if len(l.Rel) > 0 {
+		parts = append(parts, "rel="+lex.QuoteString(strings.Join(l.Rel, " ")))
+	}
+	if l.Anchor != "" {
+		parts = append(parts, "anchor="+lex.QuoteString(l.Anchor))
+	}
+	if l.Type != nil {
+		parts = append(parts, "type="+lex.Quote(l.Type.String()))
+	}
+	for _, lang := range l.Hreflang {
+		parts = append(parts, "hreflang="+lex.Quote(lang))
+	}
+	if l.Media != "" {
+		parts = append(parts, "media="+lex.Quote(l.Media))
+	}
+	if l.Title != "" {
+		if l.TitleLang != "" || !isASCII(l.Title) {
+			parts = append(parts, "title*="+encodeExtValue(l.Title, l.TitleLang))
+		} else {
+			parts = append(parts, "title="+lex.QuoteString(l.Title))
+		}
+	}
+	for _, k := range sortedKeys(l.Params) {
+		parts = append(parts, k+"="+lex.Quote(l.Params[k]))
+	}
+	return strings.Join(parts, "; ")
+}
+
+// ByRel returns the links in l with the relation type rel.
+func (l Links) ByRel(rel string) Links {
+	var links Links
+	for _, link := range l {
+		if link.HasRel(rel) {
+			links = append(links, link)
+		}
+	}
+	return links
+}
+
+// Find returns the first link in l with the relation type rel.
+func (l Links) Find(rel string) (Link, bool) {
+	for _, link := range l {
+		if link.HasRel(rel) {
+			return link, true
+		}
+	}
+	return Link{}, false
+}
+
+// Merge returns the links of l followed by those of other that are not
+// already in l. Links are the same if they have the same target, anchor and
+// relation types.
+func (l Links) Merge(other Links) Links {
+	merged := append(Links(nil), l...)
+	for _, link := range other {
+		dup := false
+		for _, m := range merged {
+			if m.Target == link.Target && m.Anchor == link.Anchor && sameRels(m.Rel, link.Rel) {
+				dup = true
+				break
+			}
+		}
+		if !dup {
+			merged = append(merged, link)
+		}
+	}
+	return merged
+}
+
+// String returns l formatted as a single Link header value.
+func (l Links) String() string {
+	parts := make([]string, len(l))
+	for i, link := range l {
+		parts[i] = link.String()
+	}
+	return strings.Join(parts, ", ")
+}
+
+// AddTo adds each link in l to h as a separate Link header.
+func (l Links) AddTo(h http.Header) {
+	for _, link := range l {
+		h.Add("Link", link.String())
+	}
+}
+
+func sameRels(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for _, rel := range a {
+		if !(Link{Rel: b}).HasRel(rel) {
+			return false
+		}
+	}
+	return true
+}
+
+func isASCII(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] >= utf8.RuneSelf {
+			return false
+		}
+	}
+	return true
+}
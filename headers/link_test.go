@@ -0,0 +1,175 @@
+// Copyright © 2017 Walter Scheper <walter.scheper@gmail.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package headers
+
+import (
+	"net/http"
+	"net/url"
+	"reflect"
+	"testing"
+)
+
+func TestNewLinks(t *testing.T) {
+	tests := []struct {
+		in       string
+		expected Links
+	}{
+		{"", nil},
+		{`<http://example.com/TheBook/chapter2>; rel="previous"; title="previous chapter"`,
+			Links{{Target: "http://example.com/TheBook/chapter2", Rel: []string{"previous"}, Title: "previous chapter"}}},
+		{`</>; rel="http://example.net/foo"`, Links{{Target: "/", Rel: []string{"http://example.net/foo"}}}},
+		{`</terms>; rel="copyright"; anchor="#foo"`, Links{{Target: "/terms", Rel: []string{"copyright"}, Anchor: "#foo"}}},
+		{`</TheBook/chapter2>; rel="previous"; title*=UTF-8'de'letztes%20Kapitel, </TheBook/chapter4>; rel="next"; title*=UTF-8'de'n%c3%a4chstes%20Kapitel`,
+			Links{
+				{Target: "/TheBook/chapter2", Rel: []string{"previous"}, Title: "letztes Kapitel", TitleLang: "de"},
+				{Target: "/TheBook/chapter4", Rel: []string{"next"}, Title: "nächstes Kapitel", TitleLang: "de"},
+			}},
+		{`<http://example.org/>; rel="start http://example.net/relation/other"`,
+			Links{{Target: "http://example.org/", Rel: []string{"start", "http://example.net/relation/other"}}}},
+		{`</a>; REL=Next; rel=prev`, Links{{Target: "/a", Rel: []string{"next"}}}},
+		{`</a>; title*=UTF-8''caf%C3%A9; title="cafe"`, Links{{Target: "/a", Title: "café"}}},
+		{`</a>; title*=ISO-8859-1'en'%A3%20rates`, Links{{Target: "/a", Title: "£ rates", TitleLang: "en"}}},
+		{`</a>; hreflang=en; hreflang=de; media=screen; foo="a,b"`,
+			Links{{Target: "/a", Hreflang: []string{"en", "de"}, Media: "screen", Params: map[string]string{"foo": "a,b"}}}},
+		{`</a;b>; rel=self,`, Links{{Target: "/a;b", Rel: []string{"self"}}}},
+	}
+	for i, test := range tests {
+		actual, err := NewLinks(test.in)
+		if err != nil {
+			t.Fatalf("%d: %q", i, err)
+		}
+		if !reflect.DeepEqual(test.expected, actual) {
+			t.Errorf("%d: expected %+v, got %+v", i, test.expected, actual)
+		}
+	}
+}
+
+func TestNewLinksType(t *testing.T) {
+	links, err := NewLinks(`</a>; rel=alternate; type="application/json; charset=utf-8"`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if links[0].Type == nil || links[0].Type.String() != "application/json; charset=utf-8" {
+		t.Errorf("expected 'application/json; charset=utf-8', got %v", links[0].Type)
+	}
+}
+
+func TestNewLinksErrors(t *testing.T) {
+	tests := []struct {
+		in, expected string
+	}{
+		{"/a; rel=next", "Error parsing link target: '/a'"},
+		{`</a>; rel="next`, `Error parsing quoted-string: '"next'`},
+		{"</a>; type=a/", "mime: expected token after slash"},
+		{"</a>; title*=caf%C3%A9", "Error parsing ext-value: 'caf%C3%A9'"},
+		{"</a>; title*=UTF-8''caf%C3", "Error parsing ext-value: 'UTF-8''caf%C3'"},
+		{"</a>; title*=UTF-16''cafe", "Error parsing ext-value: unsupported charset 'UTF-16'"},
+	}
+	for i, test := range tests {
+		_, err := NewLinks(test.in)
+		if err == nil || err.Error() != test.expected {
+			t.Errorf("%d: expected '%s', got '%v'", i, test.expected, err)
+		}
+	}
+}
+
+func TestLinksString(t *testing.T) {
+	tests := []string{
+		`</a>`,
+		`</a>; rel="next"`,
+		`<http://example.org/>; rel="start http://example.net/relation/other"; anchor="#foo"`,
+		`</a>; rel="alternate"; type="application/json; charset=utf-8"; hreflang=en; hreflang=de; media=screen`,
+		`</a>; title="previous chapter"`,
+		`</a>; title*=UTF-8'de'n%C3%A4chstes%20Kapitel`,
+		`</a>; rel="next", </b>; rel="prev"; foo="a,b"`,
+	}
+	for i, test := range tests {
+		links, err := NewLinks(test)
+		if err != nil {
+			t.Fatalf("%d: %q", i, err)
+		}
+		if actual := links.String(); actual != test {
+			t.Errorf("%d: expected '%s', got '%s'", i, test, actual)
+		}
+	}
+
+	l := Link{Target: "/a", Title: "café"}
+	if actual := l.String(); actual != "</a>; title*=UTF-8''caf%C3%A9" {
+		t.Errorf("expected '</a>; title*=UTF-8''caf%%C3%%A9', got '%s'", actual)
+	}
+}
+
+func TestNewLinksFromHeader(t *testing.T) {
+	h := http.Header{}
+	h.Add("Link", `</a>; rel="next"`)
+	h.Add("Link", `</b>; rel="prev", </c>; rel="self"`)
+	links, err := NewLinksFromHeader(h)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if actual := links.String(); actual != `</a>; rel="next", </b>; rel="prev", </c>; rel="self"` {
+		t.Errorf("unexpected links: '%s'", actual)
+	}
+
+	out := http.Header{}
+	links.AddTo(out)
+	if !reflect.DeepEqual(out["Link"], []string{`</a>; rel="next"`, `</b>; rel="prev"`, `</c>; rel="self"`}) {
+		t.Errorf("unexpected Link headers: %q", out["Link"])
+	}
+}
+
+func TestLinksLookup(t *testing.T) {
+	links, _ := NewLinks(`</1>; rel="item", </2>; rel="item next", </x>; rel="http://example.net/Rel"`)
+	if actual := links.ByRel("ITEM"); len(actual) != 2 {
+		t.Errorf("expected 2 items, got %d", len(actual))
+	}
+	if link, ok := links.Find("next"); !ok || link.Target != "/2" {
+		t.Errorf("expected /2, got %+v", link)
+	}
+	if _, ok := links.Find("http://example.net/rel"); ok {
+		t.Error("expected extension relation types to be case-sensitive")
+	}
+	if _, ok := links.Find("http://example.net/Rel"); !ok {
+		t.Error("expected to find extension relation type")
+	}
+	if _, ok := links.Find("prev"); ok {
+		t.Error("expected no prev link")
+	}
+}
+
+func TestLinksMerge(t *testing.T) {
+	a, _ := NewLinks(`</a>; rel="next", </b>; rel="prev"`)
+	b, _ := NewLinks(`</a>; rel="NEXT"; title="dup", </a>; rel="next"; anchor="#x", </c>; rel="self"`)
+	merged := a.Merge(b)
+	expected := `</a>; rel="next", </b>; rel="prev", </a>; rel="next"; anchor="#x", </c>; rel="self"`
+	if actual := merged.String(); actual != expected {
+		t.Errorf("expected '%s', got '%s'", expected, actual)
+	}
+	if len(a) != 2 {
+		t.Errorf("expected Merge to leave its receiver alone, got %d links", len(a))
+	}
+}
+
+func TestLinkURL(t *testing.T) {
+	base, _ := url.Parse("http://example.com/books/1")
+	l := Link{Target: "../authors/2"}
+	u, err := l.URL(base)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if u.String() != "http://example.com/authors/2" {
+		t.Errorf("expected 'http://example.com/authors/2', got '%s'", u)
+	}
+}
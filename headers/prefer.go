@@ -0,0 +1,139 @@
+// Copyright © 2017 Walter Scheper <walter.scheper@gmail.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package headers
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/wfscheper/mtrest/internal/lex"
+)
+
+// Preference is a single RFC 7240 preference, such as return=minimal or
+// respond-async.
+type Preference struct {
+	Name   string
+	Value  string
+	Params map[string]string
+}
+
+// Prefer is the list of preferences from a Prefer header. Only the first of
+// several preferences with the same name is kept.
+type Prefer []Preference
+
+// NewPrefer returns the preferences parsed from s, the value of one or more
+// Prefer headers joined with commas. Preference and parameter names are
+// lower-cased.
+func NewPrefer(s string) (Prefer, error) {
+	var prefer Prefer
+	for _, part := range splitQuoted(s, ',') {
+		if part == "" {
+			continue
+		}
+		params := splitQuoted(part, ';')
+		name, value, err := splitPair(params[0])
+		if err != nil {
+			return nil, err
+		}
+		p := Preference{Name: name, Value: value}
+		for _, param := range params[1:] {
+			if param == "" {
+				continue
+			}
+			k, v, err := splitPair(param)
+			if err != nil {
+				return nil, err
+			}
+			if p.Params == nil {
+				p.Params = map[string]string{}
+			}
+			p.Params[k] = v
+		}
+		if _, ok := prefer.Get(name); !ok {
+			prefer = append(prefer, p)
+		}
+	}
+	return prefer, nil
+}
+
+// Get returns the preference called name.
+func (p Prefer) Get(name string) (Preference, bool) {
+	name = strings.ToLower(name)
+	for _, pref := range p {
+		if pref.Name == name {
+			return pref, true
+		}
+	}
+	return Preference{}, false
+}
+
+// Return returns the value of the return preference, such as minimal or
+// representation, or the empty string.
+func (p Prefer) Return() string {
+	pref, _ := p.Get("return")
+	return strings.ToLower(pref.Value)
+}
+
+// RespondAsync reports whether the client prefers an asynchronous response.
+func (p Prefer) RespondAsync() bool {
+	_, ok := p.Get("respond-async")
+	return ok
+}
+
+// Wait returns how long the client is prepared to wait for a response.
+func (p Prefer) Wait() (time.Duration, bool) {
+	pref, ok := p.Get("wait")
+	if !ok {
+		return 0, false
+	}
+	n, err := strconv.Atoi(pref.Value)
+	if err != nil || n < 0 {
+		return 0, false
+	}
+	return time.Duration(n) * time.Second, true
+}
+
+// Handling returns the value of the handling preference, strict or lenient,
+// or the empty string.
+func (p Prefer) Handling() string {
+	pref, _ := p.Get("handling")
+	return strings.ToLower(pref.Value)
+}
+
+// String returns the preference formatted for a Prefer header. Without its
+// parameters, it is also the form used in a Preference-Applied header.
+func (p Preference) String() string {
+	s := p.Name
+	if p.Value != "" {
+		s += "=" + lex.Quote(p.Value)
+	}
+	for _, k := range sortedKeys(p.Params) {
+		if v := p.Params[k]; v != "" {
+			s += "; " + k + "=" + lex.Quote(v)
+		} else {
+			s += "; " + k
+		}
+	}
+	return s
+}
+
+func (p Prefer) String() string {
+	s := make([]string, len(p))
+	for i, pref := range p {
+		s[i] = pref.String()
+	}
+	return strings.Join(s, ", ")
+}
@@ -0,0 +1,89 @@
+// Copyright © 2017 Walter Scheper <walter.scheper@gmail.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package headers
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestNewPrefer(t *testing.T) {
+	tests := []struct {
+		in       string
+		expected Prefer
+	}{
+		{"", nil},
+		{"return=minimal", Prefer{{Name: "return", Value: "minimal"}}},
+		{"respond-async, wait=100", Prefer{{Name: "respond-async"}, {Name: "wait", Value: "100"}}},
+		{`Foo="a, b"; Bar; baz=1, handling=lenient`,
+			Prefer{{Name: "foo", Value: "a, b", Params: map[string]string{"bar": "", "baz": "1"}}, {Name: "handling", Value: "lenient"}}},
+		{"return=minimal, return=representation", Prefer{{Name: "return", Value: "minimal"}}},
+		{"return=,,priority=5;", Prefer{{Name: "return"}, {Name: "priority", Value: "5"}}},
+	}
+	for i, test := range tests {
+		actual, err := NewPrefer(test.in)
+		if err != nil {
+			t.Fatalf("%d: %q", i, err)
+		}
+		if !reflect.DeepEqual(actual, test.expected) {
+			t.Errorf("%d: expected %+v, got %+v", i, test.expected, actual)
+		}
+	}
+
+	if _, err := NewPrefer(`foo="bar`); err == nil || err.Error() != `Error parsing quoted-string: '"bar'` {
+		t.Errorf(`expected 'Error parsing quoted-string: '"bar'', got %v`, err)
+	}
+}
+
+func TestPreferAccessors(t *testing.T) {
+	p, _ := NewPrefer("RETURN=Representation, respond-async, wait=10, handling=strict")
+	if p.Return() != "representation" {
+		t.Errorf("expected 'representation', got '%s'", p.Return())
+	}
+	if !p.RespondAsync() {
+		t.Error("expected respond-async")
+	}
+	if d, ok := p.Wait(); !ok || d != 10*time.Second {
+		t.Errorf("expected 10s, got %v", d)
+	}
+	if p.Handling() != "strict" {
+		t.Errorf("expected 'strict', got '%s'", p.Handling())
+	}
+	if _, ok := p.Get("Respond-Async"); !ok {
+		t.Error("expected Get to ignore case")
+	}
+
+	p, _ = NewPrefer("wait=soon")
+	if _, ok := p.Wait(); ok || p.Return() != "" || p.RespondAsync() || p.Handling() != "" {
+		t.Errorf("expected no recognized preferences in %+v", p)
+	}
+}
+
+func TestPreferString(t *testing.T) {
+	tests := []string{
+		"return=minimal",
+		`respond-async, wait=100, foo="a, b"; bar; baz=1`,
+	}
+	for i, test := range tests {
+		p, err := NewPrefer(test)
+		if err != nil {
+			t.Fatalf("%d: %q", i, err)
+		}
+		if actual := p.String(); actual != test {
+			t.Errorf("%d: expected '%s', got '%s'", i, test, actual)
+		}
+	}
+}
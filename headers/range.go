@@ -0,0 +1,189 @@
+// Copyright © 2017 Walter Scheper <walter.scheper@gmail.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package headers
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/wfscheper/mtrest/internal/lex"
+)
+
+// ByteRange is a single range of a bytes Range header. First and Last are
+// the positions of the first and last bytes of the range, and Last is -1 if
+// the range runs to the end of the representation, as in "9500-". In a
+// suffix range, such as "-500", First is -1 and Last is the suffix length.
+type ByteRange struct {
+	First int64
+	Last  int64
+}
+
+// Bounds returns the positions of the first and last bytes of the range
+// within a representation of size bytes, and false if the range is not
+// satisfiable.
+func (br ByteRange) Bounds(size int64) (first, last int64, ok bool) {
+	if br.First < 0 {
+		if br.Last == 0 || size == 0 {
+			return 0, 0, false
+		}
+		if br.Last > size {
+			return 0, size - 1, true
+		}
+		return size - br.Last, size - 1, true
+	}
+	if br.First >= size {
+		return 0, 0, false
+	}
+	if br.Last < 0 || br.Last >= size {
+		return br.First, size - 1, true
+	}
+	return br.First, br.Last, true
+}
+
+func (br ByteRange) String() string {
+	switch {
+	case br.First < 0:
+		return "-" + strconv.FormatInt(br.Last, 10)
+	case br.Last < 0:
+		return strconv.FormatInt(br.First, 10) + "-"
+	}
+	return strconv.FormatInt(br.First, 10) + "-" + strconv.FormatInt(br.Last, 10)
+}
+
+// Range is an RFC 7233 Range header. Ranges is only parsed for the bytes
+// unit; a Range in any other unit has just its Unit, and is best ignored.
+type Range struct {
+	Unit   string
+	Ranges []ByteRange
+}
+
+// NewRange returns the Range parsed from s, such as "bytes=0-499, -500".
+func NewRange(s string) (Range, error) {
+	eq := strings.IndexByte(s, '=')
+	if eq < 0 || !lex.IsToken(strings.TrimSpace(s[:eq])) {
+		return Range{}, fmt.Errorf("Error parsing range: '%s'", s)
+	}
+	rg := Range{Unit: strings.ToLower(strings.TrimSpace(s[:eq]))}
+	if rg.Unit != "bytes" {
+		return rg, nil
+	}
+	for _, spec := range strings.Split(s[eq+1:], ",") {
+		spec = strings.TrimSpace(spec)
+		if spec == "" {
+			continue
+		}
+		br, err := parseByteRange(spec)
+		if err != nil {
+			return Range{}, fmt.Errorf("Error parsing range: '%s'", s)
+		}
+		rg.Ranges = append(rg.Ranges, br)
+	}
+	if len(rg.Ranges) == 0 {
+		return Range{}, fmt.Errorf("Error parsing range: '%s'", s)
+	}
+	return rg, nil
+}
+
+func parseByteRange(s string) (ByteRange, error) {
+	dash := strings.IndexByte(s, '-')
+	if dash < 0 {
+		return ByteRange{}, fmt.Errorf("Error parsing byte range: '%s'", s)
+	}
+	br := ByteRange{First: -1, Last: -1}
+	var err error
+	if dash > 0 {
+		if br.First, err = parsePosition(s[:dash]); err != nil {
+			return ByteRange{}, err
+		}
+	}
+	if dash < len(s)-1 {
+		if br.Last, err = parsePosition(s[dash+1:]); err != nil {
+			return ByteRange{}, err
+		}
+	}
+	if (br.First < 0 && br.Last < 0) || (br.First >= 0 && br.Last >= 0 && br.Last < br.First) {
+		return ByteRange{}, fmt.Errorf("Error parsing byte range: '%s'", s)
+	}
+	return br, nil
+}
+
+// parsePosition parses a byte position or length, which is a string of
+// digits.
+func parsePosition(s string) (int64, error) {
+	if strings.Trim(s, "0123456789") != "" {
+		return 0, fmt.Errorf("Error parsing byte position: '%s'", s)
+	}
+	return strconv.ParseInt(s, 10, 64)
+}
+
+func (rg Range) String() string {
+	s := make([]string, len(rg.Ranges))
+	for i, br := range rg.Ranges {
+		s[i] = br.String()
+	}
+	return rg.Unit + "=" + strings.Join(s, ",")
+}
+
+// ContentRange is an RFC 7233 Content-Range header. First and Last are the
+// positions of the first and last bytes enclosed, or -1 for an unsatisfied
+// range, as in "bytes */1000". Length is the complete length of the
+// representation, or -1 if it is unknown, as in "bytes 0-499/*".
+type ContentRange struct {
+	Unit   string
+	First  int64
+	Last   int64
+	Length int64
+}
+
+// NewContentRange returns the ContentRange parsed from s, such as
+// "bytes 0-499/1234".
+func NewContentRange(s string) (ContentRange, error) {
+	sp := strings.IndexByte(s, ' ')
+	slash := strings.LastIndexByte(s, '/')
+	if sp < 0 || slash < sp || !lex.IsToken(s[:sp]) {
+		return ContentRange{}, fmt.Errorf("Error parsing content range: '%s'", s)
+	}
+	cr := ContentRange{Unit: strings.ToLower(s[:sp]), First: -1, Last: -1, Length: -1}
+	var err error
+	if length := s[slash+1:]; length != "*" {
+		if cr.Length, err = parsePosition(length); err != nil {
+			return ContentRange{}, fmt.Errorf("Error parsing content range: '%s'", s)
+		}
+	}
+	if spec := s[sp+1 : slash]; spec != "*" {
+		br, err := parseByteRange(spec)
+		if err != nil || br.First < 0 || br.Last < 0 || (cr.Length >= 0 && br.Last >= cr.Length) {
+			return ContentRange{}, fmt.Errorf("Error parsing content range: '%s'", s)
+		}
+		cr.First, cr.Last = br.First, br.Last
+	} else if cr.Length < 0 {
+		return ContentRange{}, fmt.Errorf("Error parsing content range: '%s'", s)
+	}
+	return cr, nil
+}
+
+func (cr ContentRange) String() string {
+	s := cr.Unit + " "
+	if cr.First < 0 {
+		s += "*"
+	} else {
+		s += strconv.FormatInt(cr.First, 10) + "-" + strconv.FormatInt(cr.Last, 10)
+	}
+	if cr.Length < 0 {
+		return s + "/*"
+	}
+	return s + "/" + strconv.FormatInt(cr.Length, 10)
+}
@@ -0,0 +1,106 @@
+// Copyright © 2017 Walter Scheper <walter.scheper@gmail.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package headers
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestNewRange(t *testing.T) {
+	tests := []struct {
+		in       string
+		expected Range
+		str      string
+	}{
+		{"bytes=0-499", Range{"bytes", []ByteRange{{0, 499}}}, "bytes=0-499"},
+		{"bytes=500-999, 9500-", Range{"bytes", []ByteRange{{500, 999}, {9500, -1}}}, "bytes=500-999,9500-"},
+		{"Bytes=-500", Range{"bytes", []ByteRange{{-1, 500}}}, "bytes=-500"},
+		{"bytes=0-0,-1", Range{"bytes", []ByteRange{{0, 0}, {-1, 1}}}, "bytes=0-0,-1"},
+		{"bytes= 0-1 , ,2-3", Range{"bytes", []ByteRange{{0, 1}, {2, 3}}}, "bytes=0-1,2-3"},
+		{"rows=1-5", Range{Unit: "rows"}, "rows="},
+	}
+	for i, test := range tests {
+		actual, err := NewRange(test.in)
+		if err != nil {
+			t.Fatalf("%d: %q", i, err)
+		}
+		if !reflect.DeepEqual(actual, test.expected) {
+			t.Errorf("%d: expected %+v, got %+v", i, test.expected, actual)
+		}
+		if actual.String() != test.str {
+			t.Errorf("%d: expected '%s', got '%s'", i, test.str, actual)
+		}
+	}
+
+	for i, in := range []string{"", "bytes", "bytes=", "bytes=-", "bytes=5-1", "bytes=a-b", "bytes=1-2-3", "bytes=+1-2", "bytes=99999999999999999999-", "by tes=1-2"} {
+		if _, err := NewRange(in); err == nil || err.Error() != "Error parsing range: '"+in+"'" {
+			t.Errorf("%d: expected error parsing '%s', got %v", i, in, err)
+		}
+	}
+}
+
+func TestByteRangeBounds(t *testing.T) {
+	tests := []struct {
+		br          ByteRange
+		size        int64
+		first, last int64
+		ok          bool
+	}{
+		{ByteRange{0, 499}, 10000, 0, 499, true},
+		{ByteRange{9500, -1}, 10000, 9500, 9999, true},
+		{ByteRange{9500, 20000}, 10000, 9500, 9999, true},
+		{ByteRange{-1, 500}, 10000, 9500, 9999, true},
+		{ByteRange{-1, 20000}, 10000, 0, 9999, true},
+		{ByteRange{10000, -1}, 10000, 0, 0, false},
+		{ByteRange{-1, 0}, 10000, 0, 0, false},
+		{ByteRange{-1, 5}, 0, 0, 0, false},
+	}
+	for i, test := range tests {
+		first, last, ok := test.br.Bounds(test.size)
+		if first != test.first || last != test.last || ok != test.ok {
+			t.Errorf("%d: expected %d, %d, %t, got %d, %d, %t", i, test.first, test.last, test.ok, first, last, ok)
+		}
+	}
+}
+
+func TestNewContentRange(t *testing.T) {
+	tests := []struct {
+		in       string
+		expected ContentRange
+	}{
+		{"bytes 0-499/1234", ContentRange{"bytes", 0, 499, 1234}},
+		{"bytes 42-1233/*", ContentRange{"bytes", 42, 1233, -1}},
+		{"bytes */1234", ContentRange{"bytes", -1, -1, 1234}},
+	}
+	for i, test := range tests {
+		actual, err := NewContentRange(test.in)
+		if err != nil {
+			t.Fatalf("%d: %q", i, err)
+		}
+		if actual != test.expected {
+			t.Errorf("%d: expected %+v, got %+v", i, test.expected, actual)
+		}
+		if actual.String() != test.in {
+			t.Errorf("%d: expected '%s', got '%s'", i, test.in, actual)
+		}
+	}
+
+	for i, in := range []string{"", "bytes", "bytes 0-499", "bytes */*", "bytes 0-1234/1234", "bytes 5-1/10", "bytes -5/10", "bytes 0-/10"} {
+		if _, err := NewContentRange(in); err == nil || err.Error() != "Error parsing content range: '"+in+"'" {
+			t.Errorf("%d: expected error parsing '%s', got %v", i, in, err)
+		}
+	}
+}
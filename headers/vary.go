@@ -0,0 +1,42 @@
+// Copyright © 2017 Walter Scheper <walter.scheper@gmail.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package headers
+
+import (
+	"net/http"
+	"strings"
+)
+
+// AddVary adds each of fields to the Vary header of h, unless it is already
+// listed or the header is "*".
+func AddVary(h http.Header, fields ...string) {
+	for _, field := range fields {
+		if !Varies(h, field) {
+			h.Add("Vary", field)
+		}
+	}
+}
+
+// Varies reports whether the Vary header of h lists field, or is "*".
+func Varies(h http.Header, field string) bool {
+	for _, v := range h["Vary"] {
+		for _, f := range strings.Split(v, ",") {
+			if f = strings.TrimSpace(f); f == "*" || strings.EqualFold(f, field) {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,39 @@
+// Copyright © 2017 Walter Scheper <walter.scheper@gmail.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package headers
+
+import (
+	"net/http"
+	"reflect"
+	"testing"
+)
+
+func TestAddVary(t *testing.T) {
+	h := http.Header{}
+	h.Set("Vary", "Accept-Encoding, accept")
+	AddVary(h, "Accept", "Accept-Language", "accept-language")
+	if expected := []string{"Accept-Encoding, accept", "Accept-Language"}; !reflect.DeepEqual(h["Vary"], expected) {
+		t.Errorf("expected %q, got %q", expected, h["Vary"])
+	}
+	if !Varies(h, "ACCEPT-ENCODING") || Varies(h, "Cookie") {
+		t.Errorf("unexpected Varies results for %q", h["Vary"])
+	}
+
+	h.Set("Vary", "*")
+	AddVary(h, "Accept")
+	if !reflect.DeepEqual(h["Vary"], []string{"*"}) || !Varies(h, "Cookie") {
+		t.Errorf("expected Vary: *, got %q", h["Vary"])
+	}
+}
@@ -78,11 +78,11 @@ func Match(a, b *mtrest.MediaType) (score *Score) {
 			score.Value += 10
 		}
 		for k, v := range a.Params {
-			if k != "q" && b.Params[k] == v {
+			if b.Params[k] == v {
 				score.Value += 1
 			}
 		}
-		score.Q = toFixed(a.Q*b.Q, 3)
+		score.Q = toFixed(a.Weight*b.Weight, 3)
 	}
 	return
 }
@@ -0,0 +1,81 @@
+// Copyright © 2017 Walter Scheper <walter.scheper@gmail.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package lex implements the RFC 7230 token and quoted-string grammar shared
+// by the media type and header parsers.
+package lex
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+)
+
+// IsToken reports whether s is an RFC 7230 token.
+func IsToken(s string) bool {
+	if s == "" {
+		return false
+	}
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if c <= ' ' || c >= 0x7f || strings.IndexByte(`()<>@,;:\"/[]?={}`, c) >= 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// Unquote returns the contents of the quoted-string s, or s itself if it is
+// not quoted. A quoted-pair stands for the character that follows the
+// backslash.
+func Unquote(s string) (string, error) {
+	if !strings.HasPrefix(s, `"`) {
+		return s, nil
+	}
+	if len(s) < 2 || !strings.HasSuffix(s, `"`) {
+		return "", fmt.Errorf("Error parsing quoted-string: '%s'", s)
+	}
+	var buf bytes.Buffer
+	for i := 1; i < len(s)-1; i++ {
+		if s[i] == '\\' && i+1 < len(s)-1 {
+			i++
+		} else if s[i] == '"' || s[i] == '\\' {
+			return "", fmt.Errorf("Error parsing quoted-string: '%s'", s)
+		}
+		buf.WriteByte(s[i])
+	}
+	return buf.String(), nil
+}
+
+// Quote returns s as a token if possible, or as a quoted-string.
+func Quote(s string) string {
+	if IsToken(s) {
+		return s
+	}
+	return QuoteString(s)
+}
+
+// QuoteString returns s as a quoted-string.
+func QuoteString(s string) string {
+	var buf bytes.Buffer
+	buf.WriteByte('"')
+	for i := 0; i < len(s); i++ {
+		if s[i] == '"' || s[i] == '\\' {
+			buf.WriteByte('\\')
+		}
+		buf.WriteByte(s[i])
+	}
+	buf.WriteByte('"')
+	return buf.String()
+}
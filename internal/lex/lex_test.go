@@ -0,0 +1,62 @@
+// Copyright © 2017 Walter Scheper <walter.scheper@gmail.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package lex
+
+import "testing"
+
+func TestUnquote(t *testing.T) {
+	tests := []struct {
+		in, expected, err string
+	}{
+		{"token", "token", ""},
+		{`"a b"`, "a b", ""},
+		{`"a\qb"`, "aqb", ""},
+		{`"\"\\"`, `"\`, ""},
+		{`""`, "", ""},
+		{`"a`, "", `Error parsing quoted-string: '"a'`},
+		{`"a"b"`, "", `Error parsing quoted-string: '"a"b"'`},
+		{`"a\"`, "", `Error parsing quoted-string: '"a\"'`},
+	}
+	for idx, test := range tests {
+		actual, err := Unquote(test.in)
+		if test.err != "" {
+			if err == nil || err.Error() != test.err {
+				t.Errorf("%d: expected '%s', got %q", idx, test.err, err)
+			}
+		} else if err != nil || actual != test.expected {
+			t.Errorf("%d: expected '%s', got '%s' and %q", idx, test.expected, actual, err)
+		}
+	}
+}
+
+func TestQuote(t *testing.T) {
+	tests := []struct {
+		in, expected string
+	}{
+		{"token", "token"},
+		{"", `""`},
+		{"a b", `"a b"`},
+		{`a"b\c`, `"a\"b\\c"`},
+		{"{a}", `"{a}"`},
+	}
+	for idx, test := range tests {
+		if actual := Quote(test.in); actual != test.expected {
+			t.Errorf("%d: expected '%s', got '%s'", idx, test.expected, actual)
+		}
+		if actual, err := Unquote(Quote(test.in)); err != nil || actual != test.in {
+			t.Errorf("%d: expected round trip of '%s', got '%s' and %q", idx, test.in, actual, err)
+		}
+	}
+}
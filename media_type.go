@@ -17,36 +17,79 @@ package mtrest
 import (
 	"fmt"
 	"mime"
+	"sort"
 	"strconv"
 	"strings"
+
+	"github.com/wfscheper/mtrest/internal/lex"
 )
 
+// MediaType is a parsed media type or media range, as found in Content-Type and
+// Accept headers. Weight holds the quality factor of an Accept header media
+// range, taken from its q parameter, and defaults to 1.0. The q parameter
+// itself is never present in Params.
 type MediaType struct {
 	Type     string
 	SubType  string
 	Params   map[string]string
-	Q        float64
+	Weight   float64
 	Unparsed string
+
+	// Extensions holds the accept-ext parameters that follow the quality
+	// factor in an Accept header, in the order they were given. They are not
+	// media type parameters and are never present in Params.
+	Extensions []Param
+
+	// order records the names of Params in the order they were parsed.
+	order []string
+}
+
+// Param is a single media type or accept-ext parameter. Value is empty for an
+// accept-ext given without a value.
+type Param struct {
+	Name  string
+	Value string
 }
 
 func NewMediaType(s string) (*MediaType, error) {
-	mt, p, err := mime.ParseMediaType(s)
+	parts := splitParams(s)
+
+	// parameters after q are accept-ext parameters, not media type parameters
+	media, q, exts := parts, "", []string(nil)
+	for i, part := range parts[1:] {
+		if paramName(part) == "q" {
+			media, q, exts = parts[:i+1], part, parts[i+2:]
+			break
+		}
+	}
+
+	order, err := paramOrder(media[1:])
 	if err != nil {
 		return nil, err
 	}
-	m := &MediaType{Params: p, Q: 1.0, Unparsed: s}
+	mt, p, err := mime.ParseMediaType(strings.Join(media, ";"))
+	if err != nil {
+		return nil, err
+	}
+	m := &MediaType{Params: p, Weight: 1.0, Unparsed: s, order: order}
 	i := strings.Index(mt, "/")
 	if i == -1 {
 		m.Type = mt
 	} else {
 		m.Type, m.SubType = mt[:i], mt[i+1:]
 	}
-	if v, ok := p["q"]; ok {
-		m.Q, err = strconv.ParseFloat(v, 64)
-		if err != nil {
-			return nil, fmt.Errorf("Error parsing quality factor: '%s'", v)
+	if q != "" {
+		v := ""
+		if i := strings.Index(q, "="); i >= 0 {
+			v = strings.Trim(strings.TrimSpace(q[i+1:]), `"`)
+		}
+		if m.Weight, err = parseWeight(v); err != nil {
+			return nil, err
 		}
 	}
+	if m.Extensions, err = parseExtensions(exts); err != nil {
+		return nil, err
+	}
 	return m, nil
 }
 
@@ -59,6 +102,222 @@ func (m MediaType) Encoding() string {
 	return m.SubType
 }
 
+// Clone returns a deep copy of m.
+func (m MediaType) Clone() *MediaType {
+	c := m
+	c.Params = make(map[string]string, len(m.Params))
+	for k, v := range m.Params {
+		c.Params[k] = v
+	}
+	c.Extensions = append([]Param(nil), m.Extensions...)
+	c.order = append([]string(nil), m.order...)
+	return &c
+}
+
+// Parameters returns the parameters of m in the order they were parsed.
+// Parameters added to Params after parsing follow in sorted order.
+func (m MediaType) Parameters() []Param {
+	params := make([]Param, 0, len(m.Params))
+	seen := make(map[string]bool, len(m.order))
+	for _, k := range m.order {
+		if v, ok := m.Params[k]; ok && !seen[k] {
+			params = append(params, Param{k, v})
+			seen[k] = true
+		}
+	}
+	var rest []string
+	for k := range m.Params {
+		if !seen[k] {
+			rest = append(rest, k)
+		}
+	}
+	sort.Strings(rest)
+	for _, k := range rest {
+		params = append(params, Param{k, m.Params[k]})
+	}
+	return params
+}
+
+// MigrateQ moves a q parameter found in m.Params into m.Weight. NewMediaType
+// used to leave q in Params; callers that build MediaType values by hand with a
+// q parameter, or that read q from Params, can use MigrateQ to move to Weight.
+func (m *MediaType) MigrateQ() error {
+	v, ok := m.Params["q"]
+	if !ok {
+		return nil
+	}
+	w, err := parseWeight(v)
+	if err != nil {
+		return err
+	}
+	m.Weight = w
+	delete(m.Params, "q")
+	return nil
+}
+
+// String returns m formatted as a media type, as used in a Content-Type
+// header. The weight and any accept-ext parameters are omitted; use
+// AcceptString to format m as an Accept header media range.
 func (m MediaType) String() string {
-	return mime.FormatMediaType(m.Type+"/"+m.SubType, m.Params)
+	s := mime.FormatMediaType(m.Type+"/"+m.SubType, nil)
+	if s == "" {
+		return ""
+	}
+	for _, p := range m.Parameters() {
+		s += formatParam(p)
+	}
+	return s
+}
+
+// AcceptString returns m formatted as a media range, as used in an Accept
+// header. The weight is included when it is not 1, or when m has accept-ext
+// parameters, which must follow it.
+func (m MediaType) AcceptString() string {
+	s := m.String()
+	if s == "" {
+		return ""
+	}
+	if m.Weight != 1.0 || len(m.Extensions) > 0 {
+		s += "; q=" + strconv.FormatFloat(m.Weight, 'f', -1, 64)
+	}
+	for _, p := range m.Extensions {
+		if p.Value == "" {
+			s += "; " + p.Name
+		} else {
+			s += formatParam(p)
+		}
+	}
+	return s
+}
+
+// parseWeight parses a quality factor, which must be an RFC 7231 qvalue:
+// 0 to 1 with at most three decimal places.
+func parseWeight(v string) (float64, error) {
+	if !isQValue(v) {
+		return 0, fmt.Errorf("Error parsing quality factor: '%s'", v)
+	}
+	return strconv.ParseFloat(v, 64)
+}
+
+// isQValue reports whether v matches the qvalue grammar of RFC 7231:
+// "0" [ "." 0*3DIGIT ] / "1" [ "." 0*3("0") ].
+func isQValue(v string) bool {
+	if v == "" || (v[0] != '0' && v[0] != '1') {
+		return false
+	}
+	if len(v) == 1 {
+		return true
+	}
+	if v[1] != '.' || len(v) > 5 {
+		return false
+	}
+	for _, c := range v[2:] {
+		if c < '0' || c > '9' || (v[0] == '1' && c != '0') {
+			return false
+		}
+	}
+	return true
+}
+
+// splitParams splits s on the semicolons that separate a media type from its
+// parameters, ignoring any that appear in quoted strings.
+func splitParams(s string) []string {
+	var parts []string
+	quoted, escaped, start := false, false, 0
+	for i := 0; i < len(s); i++ {
+		switch {
+		case escaped:
+			escaped = false
+		case quoted && s[i] == '\\':
+			escaped = true
+		case s[i] == '"':
+			quoted = !quoted
+		case !quoted && s[i] == ';':
+			parts = append(parts, s[start:i])
+			start = i + 1
+		}
+	}
+	return append(parts, s[start:])
+}
+
+// paramName returns the lower-cased name of the parameter in part.
+func paramName(part string) string {
+	if i := strings.Index(part, "="); i >= 0 {
+		part = part[:i]
+	}
+	return strings.ToLower(strings.TrimSpace(part))
+}
+
+// paramOrder returns the names of the parameters in parts in the order they
+// appear. RFC 2231 continuations are reported once under their base name.
+func paramOrder(parts []string) ([]string, error) {
+	var order []string
+	raw := make(map[string]bool, len(parts))
+	seen := make(map[string]bool, len(parts))
+	for _, part := range parts {
+		name := paramName(part)
+		if name == "" {
+			continue
+		}
+		if raw[name] {
+			return nil, fmt.Errorf("Duplicate parameter: '%s'", name)
+		}
+		raw[name] = true
+		if i := strings.Index(name, "*"); i >= 0 {
+			name = name[:i]
+		}
+		if !seen[name] {
+			order = append(order, name)
+			seen[name] = true
+		}
+	}
+	return order, nil
+}
+
+// parseExtensions parses the accept-ext parameters in parts.
+func parseExtensions(parts []string) ([]Param, error) {
+	var exts []Param
+	seen := make(map[string]bool, len(parts))
+	for _, part := range parts {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		var p Param
+		if i := strings.Index(part, "="); i >= 0 {
+			p.Name, p.Value = strings.TrimSpace(part[:i]), strings.TrimSpace(part[i+1:])
+		} else {
+			p.Name = part
+		}
+		p.Name = strings.ToLower(p.Name)
+		if !lex.IsToken(p.Name) {
+			return nil, fmt.Errorf("Error parsing accept extension: '%s'", part)
+		}
+		if seen[p.Name] {
+			return nil, fmt.Errorf("Duplicate parameter: '%s'", p.Name)
+		}
+		seen[p.Name] = true
+		if strings.HasPrefix(p.Value, `"`) {
+			v, err := lex.Unquote(p.Value)
+			if err != nil {
+				return nil, fmt.Errorf("Error parsing accept extension: '%s'", part)
+			}
+			p.Value = v
+		} else if p.Value != "" && !lex.IsToken(p.Value) {
+			return nil, fmt.Errorf("Error parsing accept extension: '%s'", part)
+		}
+		exts = append(exts, p)
+	}
+	return exts, nil
+}
+
+// formatParam returns p formatted as "; name=value", quoting the value when
+// required.
+func formatParam(p Param) string {
+	// let mime handle quoting and RFC 2231 encoding of the value
+	s := mime.FormatMediaType("x/x", map[string]string{p.Name: p.Value})
+	if s == "" {
+		return ""
+	}
+	return s[len("x/x"):]
 }
@@ -0,0 +1,200 @@
+// Copyright © 2017 Walter Scheper <walter.scheper@gmail.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mtrest
+
+import (
+	"strings"
+)
+
+// caseInsensitiveParams lists the parameters whose values are compared without
+// regard to case for every media type. Any parameter not listed here, or in
+// caseInsensitiveTypeParams, is compared case-sensitively.
+var caseInsensitiveParams = map[string]bool{
+	"charset": true, // RFC 2046, section 4.1.2 and RFC 6838, section 4.2.1
+}
+
+// caseInsensitiveTypeParams lists the parameters whose values are compared
+// without regard to case for a specific media type.
+var caseInsensitiveTypeParams = map[string]map[string]bool{
+	"multipart/related": {"type": true},                  // RFC 2387, section 3.1
+	"text/plain":        {"delsp": true, "format": true}, // RFC 3676, section 4
+}
+
+// Equal reports whether m and o represent the same media type. Types,
+// subtypes and parameter names are compared case-insensitively. Parameter
+// values are compared case-insensitively only where the RFC defining the
+// parameter says so.
+func (m MediaType) Equal(o *MediaType) bool {
+	if o == nil {
+		return false
+	}
+	if !strings.EqualFold(m.Type, o.Type) || !strings.EqualFold(m.SubType, o.SubType) {
+		return false
+	}
+	if m.Weight != o.Weight {
+		return false
+	}
+	return m.paramsEqual(o) && extensionsEqual(m.Extensions, o.Extensions)
+}
+
+// Canonical returns a copy of m with its type, subtype and parameter names in
+// lower case. Values of parameters that are case-insensitive are also lowered.
+// Parameter order is preserved.
+func (m MediaType) Canonical() *MediaType {
+	c := &MediaType{
+		Type:     strings.ToLower(m.Type),
+		SubType:  strings.ToLower(m.SubType),
+		Params:   make(map[string]string, len(m.Params)),
+		Weight:   m.Weight,
+		Unparsed: m.Unparsed,
+	}
+	for _, k := range m.order {
+		c.order = append(c.order, strings.ToLower(k))
+	}
+	for _, p := range m.Extensions {
+		c.Extensions = append(c.Extensions, Param{strings.ToLower(p.Name), p.Value})
+	}
+	for k, v := range m.Params {
+		k = strings.ToLower(k)
+		if c.paramCaseInsensitive(k) {
+			v = strings.ToLower(v)
+		}
+		c.Params[k] = v
+	}
+	return c
+}
+
+// Includes reports whether o falls within the media range m. A wildcard type
+// or subtype in m matches any value in o, and every parameter of m must be
+// present in o with an equal value. Weights are ignored.
+func (m MediaType) Includes(o *MediaType) bool {
+	if o == nil {
+		return false
+	}
+	if m.Type != "*" && !strings.EqualFold(m.Type, o.Type) {
+		return false
+	}
+	if m.SubType != "*" && !strings.EqualFold(m.SubType, o.SubType) {
+		return false
+	}
+	for k, v := range m.Params {
+		k = strings.ToLower(k)
+		ov, ok := o.param(k)
+		if !ok || !m.paramValueEqual(k, v, ov) {
+			return false
+		}
+	}
+	return true
+}
+
+// Compare orders m and o by specificity, as used to decide precedence between
+// overlapping media ranges. It returns 1 if m is more specific than o, -1 if
+// it is less specific, and 0 if they are equally specific. A concrete type is
+// more specific than a wildcard subtype, which is more specific than */*.
+// Ties are broken by the number of parameters. Weights are ignored.
+func (m MediaType) Compare(o *MediaType) int {
+	if o == nil {
+		return 1
+	}
+	if d := m.specificity() - o.specificity(); d != 0 {
+		if d > 0 {
+			return 1
+		}
+		return -1
+	}
+	d := len(m.Params) - len(o.Params)
+	switch {
+	case d > 0:
+		return 1
+	case d < 0:
+		return -1
+	default:
+		return 0
+	}
+}
+
+func (m MediaType) specificity() int {
+	switch {
+	case m.Type == "*":
+		return 0
+	case m.SubType == "*":
+		return 1
+	default:
+		return 2
+	}
+}
+
+// extensionsEqual reports whether a and b hold the same accept-ext parameters,
+// in any order.
+func extensionsEqual(a, b []Param) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for _, p := range a {
+		found := false
+		for _, o := range b {
+			if strings.EqualFold(p.Name, o.Name) && p.Value == o.Value {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+func (m MediaType) paramsEqual(o *MediaType) bool {
+	if len(m.Params) != len(o.Params) {
+		return false
+	}
+	for k, v := range m.Params {
+		k = strings.ToLower(k)
+		ov, ok := o.param(k)
+		if !ok || !m.paramValueEqual(k, v, ov) {
+			return false
+		}
+	}
+	return true
+}
+
+// param looks up the parameter named k, which must be in lower case.
+func (m MediaType) param(k string) (string, bool) {
+	if v, ok := m.Params[k]; ok {
+		return v, true
+	}
+	for pk, v := range m.Params {
+		if strings.ToLower(pk) == k {
+			return v, true
+		}
+	}
+	return "", false
+}
+
+func (m MediaType) paramValueEqual(k, a, b string) bool {
+	if m.paramCaseInsensitive(k) {
+		return strings.EqualFold(a, b)
+	}
+	return a == b
+}
+
+func (m MediaType) paramCaseInsensitive(k string) bool {
+	if caseInsensitiveParams[k] {
+		return true
+	}
+	params := caseInsensitiveTypeParams[strings.ToLower(m.Type+"/"+m.SubType)]
+	return params[k]
+}
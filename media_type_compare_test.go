@@ -0,0 +1,162 @@
+// Copyright © 2017 Walter Scheper <walter.scheper@gmail.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mtrest
+
+import (
+	"testing"
+)
+
+func TestEqual(t *testing.T) {
+	tests := []struct {
+		title, a, b string
+		expected    bool
+	}{
+		{"Identical media types", "text/plain", "text/plain", true},
+		{"Type is case-insensitive", "TEXT/plain", "text/plain", true},
+		{"Subtype is case-insensitive", "text/PLAIN", "text/plain", true},
+		{"Different subtypes", "text/plain", "text/html", false},
+		{"Different types", "text/plain", "audio/plain", false},
+		{"Parameter order is ignored", "text/plain; a=1; b=2", "text/plain; b=2; a=1", true},
+		{"Parameter names are case-insensitive", "text/plain; Version=1", "text/plain; version=1", true},
+		{"Missing parameter", "text/plain; version=1", "text/plain", false},
+		{"Extra parameter", "text/plain", "text/plain; version=1", false},
+		{"Parameter values are case-sensitive", "text/plain; version=A", "text/plain; version=a", false},
+		{"charset is case-insensitive", "text/plain; charset=UTF-8", "text/plain; charset=utf-8", true},
+		{"format is case-insensitive for text/plain", "text/plain; format=Flowed", "text/plain; format=flowed", true},
+		{"format is case-sensitive for other types", "text/html; format=Flowed", "text/html; format=flowed", false},
+		{"boundary is case-sensitive", "multipart/mixed; boundary=ABC", "multipart/mixed; boundary=abc", false},
+		{"Quality factors must match", "text/plain; q=0.5", "text/plain; q=0.4", false},
+		{"Equal quality factors", "text/plain; q=0.5", "text/plain; q=0.5", true},
+		{"Extension order is ignored", "text/plain; q=0.5; a=1; b", "text/plain; q=0.5; b; a=1", true},
+		{"Extensions must match", "text/plain; q=0.5; a=1", "text/plain; q=0.5; a=2", false},
+	}
+	for idx, test := range tests {
+		a, err := NewMediaType(test.a)
+		if err != nil {
+			t.Fatalf("%d: (%s) %q", idx, test.title, err)
+		}
+		b, err := NewMediaType(test.b)
+		if err != nil {
+			t.Fatalf("%d: (%s) %q", idx, test.title, err)
+		}
+		if actual := a.Equal(b); actual != test.expected {
+			t.Errorf("%d: (%s) expected %t, got %t", idx, test.title, test.expected, actual)
+		}
+		if actual := b.Equal(a); actual != test.expected {
+			t.Errorf("%d: (%s) expected %t reversed, got %t", idx, test.title, test.expected, actual)
+		}
+	}
+	if (MediaType{Type: "a", SubType: "b"}).Equal(nil) {
+		t.Error("expected media type to not equal nil")
+	}
+}
+
+func TestCanonical(t *testing.T) {
+	tests := []struct {
+		in, expected string
+	}{
+		{"text/plain", "text/plain"},
+		{"Text/Plain", "text/plain"},
+		{"text/plain; charset=UTF-8", "text/plain; charset=utf-8"},
+		{"text/plain; Format=Flowed", "text/plain; format=flowed"},
+		{"text/html; version=ABC", "text/html; version=ABC"},
+		{"multipart/mixed; boundary=ABC", "multipart/mixed; boundary=ABC"},
+	}
+	for idx, test := range tests {
+		m, err := NewMediaType(test.in)
+		if err != nil {
+			t.Fatalf("%d: %q", idx, err)
+		}
+		c := m.Canonical()
+		if actual := c.String(); actual != test.expected {
+			t.Errorf("%d: expected '%s', got '%s'", idx, test.expected, actual)
+		}
+		if !c.Equal(m) {
+			t.Errorf("%d: expected %q to equal %q", idx, c, m)
+		}
+	}
+
+	m := MediaType{Type: "Text", SubType: "Plain", Params: map[string]string{"CharSet": "UTF-8"}}
+	if actual := m.Canonical().String(); actual != "text/plain; charset=utf-8" {
+		t.Errorf("expected 'text/plain; charset=utf-8', got '%s'", actual)
+	}
+}
+
+func TestIncludes(t *testing.T) {
+	tests := []struct {
+		title, a, b string
+		expected    bool
+	}{
+		{"*/* includes everything", "*/*", "text/plain", true},
+		{"*/* includes wildcard subtype", "*/*", "text/*", true},
+		{"*/* includes itself", "*/*", "*/*", true},
+		{"Wildcard subtype includes matching type", "text/*", "text/plain", true},
+		{"Wildcard subtype excludes other types", "text/*", "audio/basic", false},
+		{"Wildcard subtype does not include */*", "text/*", "*/*", false},
+		{"Concrete type includes itself", "text/plain", "text/plain", true},
+		{"Concrete type is case-insensitive", "text/plain", "Text/Plain", true},
+		{"Concrete type does not include wildcard", "text/plain", "text/*", false},
+		{"Range parameters must be present", "text/*; version=1", "text/plain", false},
+		{"Range parameters must match", "text/*; version=1", "text/plain; version=2", false},
+		{"Range parameters included", "text/*; version=1", "text/plain; version=1", true},
+		{"Extra parameters are included", "text/*", "text/plain; version=1", true},
+		{"charset matches case-insensitively", "text/*; charset=utf-8", "text/plain; charset=UTF-8", true},
+		{"Quality factor is ignored", "text/*; q=0.5", "text/plain", true},
+	}
+	for idx, test := range tests {
+		a, err := NewMediaType(test.a)
+		if err != nil {
+			t.Fatalf("%d: (%s) %q", idx, test.title, err)
+		}
+		b, err := NewMediaType(test.b)
+		if err != nil {
+			t.Fatalf("%d: (%s) %q", idx, test.title, err)
+		}
+		if actual := a.Includes(b); actual != test.expected {
+			t.Errorf("%d: (%s) expected %t, got %t", idx, test.title, test.expected, actual)
+		}
+	}
+}
+
+func TestCompare(t *testing.T) {
+	tests := []struct {
+		title, a, b string
+		expected    int
+	}{
+		{"Equal media types", "text/plain", "text/plain", 0},
+		{"Different concrete types are equally specific", "text/plain", "audio/basic", 0},
+		{"Concrete type beats wildcard subtype", "text/plain", "text/*", 1},
+		{"Wildcard subtype loses to concrete type", "text/*", "text/plain", -1},
+		{"Wildcard subtype beats */*", "text/*", "*/*", 1},
+		{"*/* loses to wildcard subtype", "*/*", "text/*", -1},
+		{"Parameters break ties", "text/plain; version=1", "text/plain", 1},
+		{"Fewer parameters lose ties", "text/plain", "text/plain; version=1", -1},
+		{"Parameters do not beat specificity", "text/*; version=1", "text/plain", -1},
+		{"Quality factor is ignored", "text/plain; q=0.5", "text/plain", 0},
+	}
+	for idx, test := range tests {
+		a, err := NewMediaType(test.a)
+		if err != nil {
+			t.Fatalf("%d: (%s) %q", idx, test.title, err)
+		}
+		b, err := NewMediaType(test.b)
+		if err != nil {
+			t.Fatalf("%d: (%s) %q", idx, test.title, err)
+		}
+		if actual := a.Compare(b); actual != test.expected {
+			t.Errorf("%d: (%s) expected %d, got %d", idx, test.title, test.expected, actual)
+		}
+	}
+}
@@ -0,0 +1,101 @@
+// Copyright © 2017 Walter Scheper <walter.scheper@gmail.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mtrest
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+)
+
+// MarshalText implements encoding.TextMarshaler. The media type is formatted
+// with AcceptString, so the weight and any accept-ext parameters survive a
+// round-trip through UnmarshalText. The zero MediaType is empty text.
+func (m MediaType) MarshalText() ([]byte, error) {
+	return []byte(m.AcceptString()), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler. Empty text sets m to
+// the zero MediaType.
+func (m *MediaType) UnmarshalText(text []byte) error {
+	if len(text) == 0 {
+		*m = MediaType{}
+		return nil
+	}
+	mt, err := NewMediaType(string(text))
+	if err != nil {
+		return err
+	}
+	*m = *mt
+	return nil
+}
+
+// MarshalBinary implements encoding.BinaryMarshaler. The binary form is the
+// same as the text form.
+func (m MediaType) MarshalBinary() ([]byte, error) {
+	return m.MarshalText()
+}
+
+// UnmarshalBinary implements encoding.BinaryUnmarshaler.
+func (m *MediaType) UnmarshalBinary(data []byte) error {
+	return m.UnmarshalText(data)
+}
+
+// MarshalJSON implements json.Marshaler. The media type is encoded as a JSON
+// string.
+func (m MediaType) MarshalJSON() ([]byte, error) {
+	return json.Marshal(m.AcceptString())
+}
+
+// UnmarshalJSON implements json.Unmarshaler. A JSON null leaves m unchanged.
+func (m *MediaType) UnmarshalJSON(data []byte) error {
+	if string(data) == "null" {
+		return nil
+	}
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return err
+	}
+	return m.UnmarshalText([]byte(s))
+}
+
+// Set implements flag.Value.
+func (m *MediaType) Set(s string) error {
+	return m.UnmarshalText([]byte(s))
+}
+
+// Scan implements sql.Scanner. A NULL value sets m to the zero MediaType.
+func (m *MediaType) Scan(src interface{}) error {
+	switch v := src.(type) {
+	case nil:
+		*m = MediaType{}
+		return nil
+	case string:
+		return m.UnmarshalText([]byte(v))
+	case []byte:
+		return m.UnmarshalText(v)
+	default:
+		return fmt.Errorf("Error scanning media type: unsupported type %T", src)
+	}
+}
+
+// Value implements driver.Valuer. The zero MediaType is stored as NULL.
+func (m MediaType) Value() (driver.Value, error) {
+	s := m.AcceptString()
+	if s == "" {
+		return nil, nil
+	}
+	return s, nil
+}
@@ -0,0 +1,195 @@
+// Copyright © 2017 Walter Scheper <walter.scheper@gmail.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mtrest
+
+import (
+	"encoding/json"
+	"flag"
+	"reflect"
+	"testing"
+)
+
+func TestMarshalText(t *testing.T) {
+	tests := []string{
+		"a/b",
+		"a/b; p=1",
+		"a/b; z=1; a=2",
+		"a/b; p=1; q=0.5",
+		"a/b; q=0.5; e=1",
+	}
+	for idx, test := range tests {
+		m, err := NewMediaType(test)
+		if err != nil {
+			t.Fatalf("%d: %q", idx, err)
+		}
+		text, err := m.MarshalText()
+		if err != nil {
+			t.Fatalf("%d: %q", idx, err)
+		}
+		if string(text) != test {
+			t.Errorf("%d: expected '%s', got '%s'", idx, test, text)
+		}
+		var actual MediaType
+		if err := actual.UnmarshalText(text); err != nil {
+			t.Fatalf("%d: %q", idx, err)
+		}
+		if !actual.Equal(m) {
+			t.Errorf("%d: expected %q, got %q", idx, m, actual)
+		}
+	}
+
+	var m MediaType
+	if text, err := m.MarshalText(); err != nil || len(text) != 0 {
+		t.Errorf("expected empty text for the zero value, got '%s' and %v", text, err)
+	}
+	m = MediaType{Type: "x", SubType: "y"}
+	if err := m.UnmarshalText(nil); err != nil || !reflect.DeepEqual(m, MediaType{}) {
+		t.Errorf("expected the zero value from empty text, got %+v and %v", m, err)
+	}
+	if err := m.UnmarshalText([]byte("a/")); err == nil || err.Error() != "mime: expected token after slash" {
+		t.Errorf("expected 'mime: expected token after slash', got %v", err)
+	}
+}
+
+func TestMarshalJSON(t *testing.T) {
+	type doc struct {
+		Type    MediaType  `json:"type"`
+		Pointer *MediaType `json:"pointer"`
+	}
+	in := `{"type":"application/vnd.foo+json; version=2","pointer":"text/plain; q=0.5"}`
+	var d doc
+	if err := json.Unmarshal([]byte(in), &d); err != nil {
+		t.Fatal(err)
+	}
+	if d.Type.SubType != "vnd.foo+json" || d.Type.Params["version"] != "2" {
+		t.Errorf("expected application/vnd.foo+json; version=2, got %+v", d.Type)
+	}
+	if d.Pointer == nil || d.Pointer.Weight != 0.5 {
+		t.Errorf("expected text/plain; q=0.5, got %+v", d.Pointer)
+	}
+	out, err := json.Marshal(d)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(out) != in {
+		t.Errorf("expected '%s', got '%s'", in, out)
+	}
+
+	d = doc{}
+	if err := json.Unmarshal([]byte(`{"type":null,"pointer":null}`), &d); err != nil {
+		t.Fatal(err)
+	}
+	if d.Pointer != nil {
+		t.Errorf("expected nil, got %+v", d.Pointer)
+	}
+
+	d = doc{}
+	out, err = json.Marshal(d)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(out) != `{"type":"","pointer":null}` {
+		t.Errorf("expected an empty type, got '%s'", out)
+	}
+	d.Type = ApplicationJSON
+	if err := json.Unmarshal(out, &d); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(d, doc{}) {
+		t.Errorf("expected the zero value to round-trip, got %+v", d)
+	}
+
+	if err := json.Unmarshal([]byte(`{"type":1}`), &d); err == nil {
+		t.Error("expected error unmarshaling a number, got nil")
+	}
+}
+
+func TestMarshalBinary(t *testing.T) {
+	m, _ := NewMediaType("a/b; p=1")
+	data, err := m.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var actual MediaType
+	if err := actual.UnmarshalBinary(data); err != nil {
+		t.Fatal(err)
+	}
+	if !actual.Equal(m) {
+		t.Errorf("expected %q, got %q", m, actual)
+	}
+}
+
+func TestSet(t *testing.T) {
+	var m MediaType
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	fs.Var(&m, "type", "media type")
+	if err := fs.Parse([]string{"-type", "application/yaml; charset=utf-8"}); err != nil {
+		t.Fatal(err)
+	}
+	if actual := m.String(); actual != "application/yaml; charset=utf-8" {
+		t.Errorf("expected 'application/yaml; charset=utf-8', got '%s'", actual)
+	}
+}
+
+func TestScan(t *testing.T) {
+	tests := []struct {
+		src      interface{}
+		expected string
+	}{
+		{"a/b", "a/b"},
+		{[]byte("a/b; p=1"), "a/b; p=1"},
+		{nil, ""},
+		{"", ""},
+	}
+	for idx, test := range tests {
+		m := MediaType{Type: "x", SubType: "y"}
+		if err := m.Scan(test.src); err != nil {
+			t.Fatalf("%d: %q", idx, err)
+		}
+		if actual := m.String(); actual != test.expected {
+			t.Errorf("%d: expected '%s', got '%s'", idx, test.expected, actual)
+		}
+	}
+
+	var m MediaType
+	if err := m.Scan(1); err == nil || err.Error() != "Error scanning media type: unsupported type int" {
+		t.Errorf("expected 'Error scanning media type: unsupported type int', got %v", err)
+	}
+}
+
+func TestValue(t *testing.T) {
+	m, _ := NewMediaType("a/b; p=1; q=0.5")
+	v, err := m.Value()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v != "a/b; p=1; q=0.5" {
+		t.Errorf("expected 'a/b; p=1; q=0.5', got '%v'", v)
+	}
+	var scanned MediaType
+	if err := scanned.Scan(v); err != nil || !scanned.Equal(m) || scanned.Weight != m.Weight {
+		t.Errorf("expected '%s' to round-trip, got %+v and %v", m.AcceptString(), scanned, err)
+	}
+
+	var zero MediaType
+	null, err := zero.Value()
+	if err != nil || null != nil {
+		t.Errorf("expected NULL for the zero value, got '%v' and %v", null, err)
+	}
+	m = &MediaType{Type: "x", SubType: "y"}
+	if err := m.Scan(null); err != nil || !reflect.DeepEqual(*m, zero) {
+		t.Errorf("expected the zero value to round-trip, got %+v and %v", m, err)
+	}
+}
@@ -15,30 +15,10 @@
 package mtrest
 
 import (
+	"reflect"
 	"testing"
 )
 
-func assertMediaTypeEqual(a, b *MediaType) bool {
-	if a.Type != b.Type {
-		return false
-	}
-	if a.SubType != b.SubType {
-		return false
-	}
-	if a.Q != b.Q {
-		return false
-	}
-	if len(a.Params) != len(b.Params) {
-		return false
-	}
-	for key, value := range a.Params {
-		if b.Params[key] != value {
-			return false
-		}
-	}
-	return true
-}
-
 func TestEncoding(t *testing.T) {
 	tests := []struct {
 		mt       string
@@ -69,51 +49,65 @@ func TestNewMediaType(t *testing.T) {
 			Type:     "text",
 			SubType:  "",
 			Params:   map[string]string{},
-			Q:        1.0,
+			Weight:   1.0,
 			Unparsed: "text",
 		}},
 		{"text/plain", &MediaType{
 			Type:     "text",
 			SubType:  "plain",
 			Params:   map[string]string{},
-			Q:        1.0,
+			Weight:   1.0,
 			Unparsed: "text/plain",
 		}},
 		{"text/*", &MediaType{
 			Type:     "text",
 			SubType:  "*",
 			Params:   map[string]string{},
-			Q:        1.0,
+			Weight:   1.0,
 			Unparsed: "text/*",
 		}},
 		{"*/*", &MediaType{
 			Type:     "*",
 			SubType:  "*",
 			Params:   map[string]string{},
-			Q:        1.0,
+			Weight:   1.0,
 			Unparsed: "*/*",
 		}},
 		{"text/plain; version=1", &MediaType{
 			Type:     "text",
 			SubType:  "plain",
 			Params:   map[string]string{"version": "1"},
-			Q:        1.0,
+			Weight:   1.0,
 			Unparsed: "text/plain; version=1",
 		}},
 		{"text/plain; q=0.3", &MediaType{
 			Type:     "text",
 			SubType:  "plain",
-			Params:   map[string]string{"q": "0.3"},
-			Q:        0.3,
+			Params:   map[string]string{},
+			Weight:   0.3,
 			Unparsed: "text/plain; q=0.3",
 		}},
+		{"text/plain; q=0.125", &MediaType{
+			Type:     "text",
+			SubType:  "plain",
+			Params:   map[string]string{},
+			Weight:   0.125,
+			Unparsed: "text/plain; q=0.125",
+		}},
+		{"text/plain; q=1.", &MediaType{
+			Type:     "text",
+			SubType:  "plain",
+			Params:   map[string]string{},
+			Weight:   1.0,
+			Unparsed: "text/plain; q=1.",
+		}},
 	}
 	for idx, test := range tests {
 		mt, err := NewMediaType(test.in)
 		if err != nil {
 			t.Errorf("%d: exected nil, got %q", idx, err)
 		}
-		if !assertMediaTypeEqual(test.expected, mt) {
+		if !test.expected.Equal(mt) {
 			t.Errorf("%d: Expected %q, got %q", idx, test.expected, mt)
 		}
 	}
@@ -128,6 +122,24 @@ func TestNewMediaTypeErrors(t *testing.T) {
 		{"text/", "mime: expected token after slash"},
 		{"text/plain/a", "mime: unexpected content after media subtype"},
 		{"text/plain; q=foo", "Error parsing quality factor: 'foo'"},
+		{"text/plain; q=1.5", "Error parsing quality factor: '1.5'"},
+		{"text/plain; q=-1", "Error parsing quality factor: '-1'"},
+		{"text/plain; q", "Error parsing quality factor: ''"},
+		{"text/plain; q=NaN", "Error parsing quality factor: 'NaN'"},
+		{"text/plain; q=Inf", "Error parsing quality factor: 'Inf'"},
+		{"text/plain; q=1e-1", "Error parsing quality factor: '1e-1'"},
+		{"text/plain; q=0e0", "Error parsing quality factor: '0e0'"},
+		{"text/plain; q=+0.5", "Error parsing quality factor: '+0.5'"},
+		{"text/plain; q=0x1p-1", "Error parsing quality factor: '0x1p-1'"},
+		{"text/plain; q=.5", "Error parsing quality factor: '.5'"},
+		{"text/plain; q=0.1234", "Error parsing quality factor: '0.1234'"},
+		{"text/plain; q=1.001", "Error parsing quality factor: '1.001'"},
+		{"text/plain; p=1; p=2", "Duplicate parameter: 'p'"},
+		{"text/plain; p=1; P=2", "Duplicate parameter: 'p'"},
+		{"text/plain; q=0.5; e=1; e=2", "Duplicate parameter: 'e'"},
+		{"text/plain; q=0.5; e=a b", "Error parsing accept extension: 'e=a b'"},
+		{`text/plain; q=0.5; e="a"b"`, `Error parsing accept extension: 'e="a"b"'`},
+		{`text/plain; q=0.5; e={a}`, "Error parsing accept extension: 'e={a}'"},
 	}
 	for idx, test := range tests {
 		_, err := NewMediaType(test.in)
@@ -140,6 +152,30 @@ func TestNewMediaTypeErrors(t *testing.T) {
 }
 
 func TestString(t *testing.T) {
+	tests := []struct {
+		in, expected string
+	}{
+		{"a/b", "a/b"},
+		{"a/b; p=1", "a/b; p=1"},
+		{"a/b+c", "a/b+c"},
+		{"a/b+c; p=1", "a/b+c; p=1"},
+		{"a/b; q=0.5", "a/b"},
+		{"a/b; p=1; q=0.5", "a/b; p=1"},
+		{"a/b+c; q=0.5", "a/b+c"},
+		{"a/b+c; p=1; q=0.5", "a/b+c; p=1"},
+		{"a/b; z=1; a=2", "a/b; z=1; a=2"},
+		{"a/b; z=1; a=2; q=0.5", "a/b; z=1; a=2"},
+		{"a/b; q=0.5; e=1", "a/b"},
+	}
+	for i, test := range tests {
+		m, _ := NewMediaType(test.in)
+		if actual := m.String(); actual != test.expected {
+			t.Errorf("%d: expected '%s', got '%s'", i, test.expected, actual)
+		}
+	}
+}
+
+func TestAcceptString(t *testing.T) {
 	tests := []string{
 		"a/b",
 		"a/b; p=1",
@@ -149,15 +185,117 @@ func TestString(t *testing.T) {
 		"a/b; p=1; q=0.5",
 		"a/b+c; q=0.5",
 		"a/b+c; p=1; q=0.5",
+		"a/b; z=1; a=2; q=0.5",
+		"a/b; q=0; e=1",
+		"a/b; q=0.5; e=1",
+		"a/b; q=0.5; e",
+		"a/b; q=1; e=\"a b\"; f",
 	}
 	for i, test := range tests {
 		m, _ := NewMediaType(test)
-		if actual := m.String(); actual != test {
+		if actual := m.AcceptString(); actual != test {
 			t.Errorf("%d: expected '%s', got '%s'", i, test, actual)
 		}
 	}
 }
 
+func TestMigrateQ(t *testing.T) {
+	m := MediaType{Type: "a", SubType: "b", Params: map[string]string{"p": "1", "q": "0.5"}, Weight: 1.0}
+	if err := m.MigrateQ(); err != nil {
+		t.Fatal(err)
+	}
+	if m.Weight != 0.5 {
+		t.Errorf("expected weight 0.5, got %v", m.Weight)
+	}
+	if _, ok := m.Params["q"]; ok {
+		t.Errorf("expected q to be removed from %+v", m.Params)
+	}
+	if actual := m.AcceptString(); actual != "a/b; p=1; q=0.5" {
+		t.Errorf("expected 'a/b; p=1; q=0.5', got '%s'", actual)
+	}
+
+	m = MediaType{Type: "a", SubType: "b", Params: map[string]string{}, Weight: 1.0}
+	if err := m.MigrateQ(); err != nil || m.Weight != 1.0 {
+		t.Errorf("expected weight 1 and nil error, got %v and %v", m.Weight, err)
+	}
+
+	m = MediaType{Type: "a", SubType: "b", Params: map[string]string{"q": "foo"}}
+	if err := m.MigrateQ(); err == nil || err.Error() != "Error parsing quality factor: 'foo'" {
+		t.Errorf("expected 'Error parsing quality factor: 'foo'', got %v", err)
+	}
+}
+
+func TestParameters(t *testing.T) {
+	tests := []struct {
+		in       string
+		expected []Param
+	}{
+		{"a/b", []Param{}},
+		{"a/b; p=1", []Param{{"p", "1"}}},
+		{"a/b; z=1; a=2", []Param{{"z", "1"}, {"a", "2"}}},
+		{"a/b; Z=1; A=2", []Param{{"z", "1"}, {"a", "2"}}},
+		{"a/b; z=1; q=0.5; a=2", []Param{{"z", "1"}}},
+		{"a/b; z*0=x; a=2; z*1=y", []Param{{"z", "xy"}, {"a", "2"}}},
+	}
+	for idx, test := range tests {
+		m, err := NewMediaType(test.in)
+		if err != nil {
+			t.Fatalf("%d: %q", idx, err)
+		}
+		if actual := m.Parameters(); !reflect.DeepEqual(test.expected, actual) {
+			t.Errorf("%d: expected %+v, got %+v", idx, test.expected, actual)
+		}
+	}
+
+	m := MediaType{Type: "a", SubType: "b", Params: map[string]string{"z": "1", "a": "2"}}
+	if actual := m.String(); actual != "a/b; a=2; z=1" {
+		t.Errorf("expected 'a/b; a=2; z=1', got '%s'", actual)
+	}
+}
+
+func TestExtensions(t *testing.T) {
+	tests := []struct {
+		in       string
+		params   map[string]string
+		expected []Param
+	}{
+		{"a/b", map[string]string{}, nil},
+		{"a/b; p=1", map[string]string{"p": "1"}, nil},
+		{"a/b; p=1; q=0.5", map[string]string{"p": "1"}, nil},
+		{"a/b; q=0.5; e=1", map[string]string{}, []Param{{"e", "1"}}},
+		{"a/b; q=0.5; e", map[string]string{}, []Param{{"e", ""}}},
+		{"a/b; q=0.5; E=\"x;y\"; f", map[string]string{}, []Param{{"e", "x;y"}, {"f", ""}}},
+		{"a/b; p=1; q=0.5; p=2", map[string]string{"p": "1"}, []Param{{"p", "2"}}},
+		{`a/b; q=0.5; e="a\qb"; f="\"\\"`, map[string]string{}, []Param{{"e", "aqb"}, {"f", `"\`}}},
+		{`a/b; q=0.5; e="a\nb\x41"`, map[string]string{}, []Param{{"e", "anbx41"}}},
+	}
+	for idx, test := range tests {
+		m, err := NewMediaType(test.in)
+		if err != nil {
+			t.Fatalf("%d: %q", idx, err)
+		}
+		if !reflect.DeepEqual(test.params, m.Params) {
+			t.Errorf("%d: expected params %+v, got %+v", idx, test.params, m.Params)
+		}
+		if !reflect.DeepEqual(test.expected, m.Extensions) {
+			t.Errorf("%d: expected extensions %+v, got %+v", idx, test.expected, m.Extensions)
+		}
+	}
+}
+
+func TestClone(t *testing.T) {
+	m, _ := NewMediaType("a/b; z=1; a=2; q=0.5; e=1")
+	c := m.Clone()
+	if !c.Equal(m) || c.AcceptString() != m.AcceptString() {
+		t.Fatalf("expected %q, got %q", m.AcceptString(), c.AcceptString())
+	}
+	c.Params["z"] = "3"
+	c.Extensions[0].Value = "2"
+	if m.Params["z"] != "1" || m.Extensions[0].Value != "1" {
+		t.Errorf("expected clone to be independent of %q", m.AcceptString())
+	}
+}
+
 func BenchmarkNewMediaType(b *testing.B) {
 	for i := 0; i < b.N; i++ {
 		NewMediaType("text/plain; q=0.8; version=1")
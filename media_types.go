@@ -0,0 +1,27 @@
+// Copyright © 2017 Walter Scheper <walter.scheper@gmail.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mtrest
+
+// Well-known media types. Use Clone to get a copy that is safe to modify.
+var (
+	ApplicationCBOR        = MediaType{Type: "application", SubType: "cbor", Params: map[string]string{}, Weight: 1.0}
+	ApplicationJSON        = MediaType{Type: "application", SubType: "json", Params: map[string]string{}, Weight: 1.0}
+	ApplicationMsgpack     = MediaType{Type: "application", SubType: "msgpack", Params: map[string]string{}, Weight: 1.0}
+	ApplicationOctetStream = MediaType{Type: "application", SubType: "octet-stream", Params: map[string]string{}, Weight: 1.0}
+	ApplicationProtobuf    = MediaType{Type: "application", SubType: "protobuf", Params: map[string]string{}, Weight: 1.0}
+	ApplicationXProtobuf   = MediaType{Type: "application", SubType: "x-protobuf", Params: map[string]string{}, Weight: 1.0}
+	ApplicationXML         = MediaType{Type: "application", SubType: "xml", Params: map[string]string{}, Weight: 1.0}
+	ApplicationYAML        = MediaType{Type: "application", SubType: "yaml", Params: map[string]string{}, Weight: 1.0}
+)
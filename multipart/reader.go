@@ -0,0 +1,313 @@
+// Copyright © 2017 Walter Scheper <walter.scheper@gmail.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package multipart reads and writes multipart/form-data, multipart/mixed
+// and multipart/related bodies, decoding and encoding each part with the
+// codec for its Content-Type.
+package multipart
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"mime/multipart"
+	"net/http"
+	"net/textproto"
+	"os"
+	"strings"
+
+	"github.com/wfscheper/mtrest"
+	"github.com/wfscheper/mtrest/codec"
+	"github.com/wfscheper/mtrest/render"
+)
+
+var (
+	// MultipartFormData is the RFC 7578 media type for form submissions.
+	MultipartFormData = mtrest.MediaType{Type: "multipart", SubType: "form-data", Params: map[string]string{}, Weight: 1.0}
+
+	// MultipartMixed is the RFC 2046 media type for independent parts.
+	MultipartMixed = mtrest.MediaType{Type: "multipart", SubType: "mixed", Params: map[string]string{}, Weight: 1.0}
+
+	// MultipartRelated is the RFC 2387 media type for parts that make up a
+	// compound object, such as a document and its attachments.
+	MultipartRelated = mtrest.MediaType{Type: "multipart", SubType: "related", Params: map[string]string{}, Weight: 1.0}
+)
+
+// Limits of a new Reader.
+const (
+	DefaultThreshold = 10 << 20
+	DefaultMaxMemory = 32 << 20
+	DefaultMaxParts  = 1000
+)
+
+// Reader reads the parts of a multipart request body.
+type Reader struct {
+	// Threshold is the size in bytes above which a part is streamed to a
+	// temporary file rather than held in memory.
+	Threshold int64
+
+	// MaxMemory is the most bytes that the parts read by the Reader hold
+	// in memory together. Once it is used up, parts are streamed to
+	// temporary files whatever their size. Zero means no limit.
+	MaxMemory int64
+
+	// MaxParts is the most parts the Reader reads. Further parts are
+	// refused with a 413 Payload Too Large problem. Zero means no limit.
+	MaxParts int
+
+	// Dir is the directory for temporary files, or "" for the default
+	// directory for temporary files.
+	Dir string
+
+	mediaType *mtrest.MediaType
+	mr        *multipart.Reader
+	memory    int64
+	parts     int
+}
+
+// NewReader returns a Reader for the body of r, which must have a multipart
+// Content-Type with a boundary parameter.
+func NewReader(r *http.Request) (*Reader, error) {
+	return NewBodyReader(r.Header.Get("Content-Type"), r.Body)
+}
+
+// NewBodyReader returns a Reader for body, a multipart body with the media
+// type contentType, such as the content of a nested multipart part.
+func NewBodyReader(contentType string, body io.Reader) (*Reader, error) {
+	m, err := mtrest.NewMediaType(contentType)
+	if err != nil {
+		return nil, &codec.UnsupportedMediaTypeError{}
+	}
+	if m.Type != "multipart" {
+		return nil, &codec.UnsupportedMediaTypeError{MediaType: m}
+	}
+	boundary := m.Params["boundary"]
+	if boundary == "" {
+		return nil, render.NewProblem(http.StatusBadRequest, "Missing multipart boundary")
+	}
+	return &Reader{
+		Threshold: DefaultThreshold,
+		MaxMemory: DefaultMaxMemory,
+		MaxParts:  DefaultMaxParts,
+		mediaType: m,
+		mr:        multipart.NewReader(body, boundary),
+	}, nil
+}
+
+// MediaType returns the media type of the body, with its parameters, such
+// as the start and type parameters of multipart/related.
+func (rd *Reader) MediaType() *mtrest.MediaType {
+	return rd.mediaType
+}
+
+// NextPart reads the next part of the body, or returns io.EOF if there are
+// no more parts. A malformed body is reported as a 400 Bad Request
+// problem, and a body with more than MaxParts parts as a 413 Payload Too
+// Large problem.
+func (rd *Reader) NextPart() (*Part, error) {
+	mp, err := rd.mr.NextPart()
+	if err == io.EOF {
+		return nil, err
+	}
+	if err != nil {
+		return nil, render.NewProblem(http.StatusBadRequest, err.Error())
+	}
+	defer mp.Close()
+	if rd.parts++; rd.MaxParts > 0 && rd.parts > rd.MaxParts {
+		return nil, render.NewProblem(http.StatusRequestEntityTooLarge, fmt.Sprintf("A multipart body may hold at most %d parts", rd.MaxParts))
+	}
+
+	p := &Part{
+		Header:    mp.Header,
+		Name:      mp.FormName(),
+		FileName:  mp.FileName(),
+		ContentID: strings.Trim(mp.Header.Get("Content-Id"), "<>"),
+	}
+	if s := mp.Header.Get("Content-Type"); s != "" {
+		p.MediaType, _ = mtrest.NewMediaType(s)
+	} else if p.FileName != "" {
+		p.MediaType = mtrest.ApplicationOctetStream.Clone()
+	} else {
+		p.MediaType = &mtrest.MediaType{Type: "text", SubType: "plain", Params: map[string]string{}, Weight: 1.0}
+	}
+
+	threshold := rd.Threshold
+	if rd.MaxMemory > 0 && rd.MaxMemory-rd.memory < threshold {
+		threshold = rd.MaxMemory - rd.memory
+	}
+	var buf bytes.Buffer
+	if p.Size, err = io.CopyN(&buf, mp, threshold+1); err != nil && err != io.EOF {
+		return nil, render.NewProblem(http.StatusBadRequest, err.Error())
+	}
+	if p.Size <= threshold {
+		p.data = buf.Bytes()
+		rd.memory += p.Size
+		return p, nil
+	}
+
+	f, err := ioutil.TempFile(rd.Dir, "mtrest-part-")
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	p.path = f.Name()
+	if _, err = buf.WriteTo(f); err == nil {
+		var n int64
+		n, err = io.Copy(f, mp)
+		p.Size += n
+	}
+	if err != nil {
+		p.Remove()
+		return nil, err
+	}
+	return p, nil
+}
+
+// ReadAll reads the remaining parts of the body. If it fails, the parts it
+// has read are removed.
+func (rd *Reader) ReadAll() (Parts, error) {
+	var parts Parts
+	for {
+		p, err := rd.NextPart()
+		if err == io.EOF {
+			return parts, nil
+		}
+		if err != nil {
+			parts.RemoveAll()
+			return nil, err
+		}
+		parts = append(parts, p)
+	}
+}
+
+// Part is a part of a multipart body, held in memory or, if it is larger
+// than the Reader's Threshold or the Reader's MaxMemory is used up, in a
+// temporary file.
+type Part struct {
+	Header textproto.MIMEHeader
+
+	// MediaType is the media type of the part. Parts without a
+	// Content-Type are text/plain, or application/octet-stream if they
+	// have a file name. It is nil if the Content-Type is malformed.
+	MediaType *mtrest.MediaType
+
+	// Name and FileName are from the form-data Content-Disposition.
+	Name     string
+	FileName string
+
+	// ContentID is the Content-ID of the part, without angle brackets, by
+	// which multipart/related parts refer to each other.
+	ContentID string
+
+	// Size is the size of the part's content in bytes.
+	Size int64
+
+	data []byte
+	path string
+}
+
+// Open returns the content of the part.
+func (p *Part) Open() (io.ReadCloser, error) {
+	if p.path != "" {
+		return os.Open(p.path)
+	}
+	return ioutil.NopCloser(bytes.NewReader(p.data)), nil
+}
+
+// Decode decodes the content of the part into v, using the codec for its
+// media type.
+func (p *Part) Decode(v interface{}) error {
+	c, ok := codec.For(p.MediaType)
+	if !ok {
+		return &codec.UnsupportedMediaTypeError{MediaType: p.MediaType}
+	}
+	f, err := p.Open()
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	data, err := ioutil.ReadAll(f)
+	if err != nil {
+		return err
+	}
+	return c.Unmarshal(data, v)
+}
+
+// InFile reports whether the part is held in a temporary file.
+func (p *Part) InFile() bool {
+	return p.path != ""
+}
+
+// Remove removes the temporary file holding the part, if any.
+func (p *Part) Remove() error {
+	if p.path == "" {
+		return nil
+	}
+	err := os.Remove(p.path)
+	if err == nil || errors.Is(err, os.ErrNotExist) {
+		p.path = ""
+		return nil
+	}
+	return err
+}
+
+// Parts are the parts of a multipart body.
+type Parts []*Part
+
+// Get returns the form-data part called name, or nil.
+func (ps Parts) Get(name string) *Part {
+	for _, p := range ps {
+		if p.Name == name {
+			return p
+		}
+	}
+	return nil
+}
+
+// ContentID returns the part with the Content-ID id, with or without angle
+// brackets, or nil.
+func (ps Parts) ContentID(id string) *Part {
+	id = strings.Trim(id, "<>")
+	for _, p := range ps {
+		if p.ContentID == id {
+			return p
+		}
+	}
+	return nil
+}
+
+// Root returns the root part of a multipart/related body of media type m:
+// the part named by its start parameter, or else the first part.
+func (ps Parts) Root(m *mtrest.MediaType) *Part {
+	if start := m.Params["start"]; start != "" {
+		return ps.ContentID(start)
+	}
+	if len(ps) == 0 {
+		return nil
+	}
+	return ps[0]
+}
+
+// RemoveAll removes the temporary files of all the parts.
+func (ps Parts) RemoveAll() error {
+	var first error
+	for _, p := range ps {
+		if err := p.Remove(); err != nil && first == nil {
+			first = err
+		}
+	}
+	return first
+}
@@ -0,0 +1,218 @@
+// Copyright © 2017 Walter Scheper <walter.scheper@gmail.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package multipart
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/wfscheper/mtrest/codec"
+	"github.com/wfscheper/mtrest/render"
+)
+
+type widget struct {
+	Name  string `json:"name" xml:"name" yaml:"name"`
+	Count int    `json:"count" xml:"count" yaml:"count"`
+}
+
+func request(contentType, body string) *http.Request {
+	r := httptest.NewRequest("POST", "/uploads", strings.NewReader(strings.ReplaceAll(body, "\n", "\r\n")))
+	r.Header.Set("Content-Type", contentType)
+	return r
+}
+
+const formData = `--xyz
+Content-Disposition: form-data; name="metadata"
+Content-Type: application/json
+
+{"name":"a","count":1}
+--xyz
+Content-Disposition: form-data; name="title"
+
+Quarterly report
+--xyz
+Content-Disposition: form-data; name="file"; filename="report.bin"
+
+0123456789
+--xyz--
+`
+
+func TestReader(t *testing.T) {
+	rd, err := NewReader(request(`multipart/form-data; boundary="xyz"`, formData))
+	if err != nil {
+		t.Fatal(err)
+	}
+	rd.Threshold = 16
+	rd.Dir = t.TempDir()
+	parts, err := rd.ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(parts) != 3 {
+		t.Fatalf("expected 3 parts, got %d", len(parts))
+	}
+
+	var w widget
+	if err := parts.Get("metadata").Decode(&w); err != nil || w != (widget{"a", 1}) {
+		t.Errorf("expected {a 1}, got %+v and %v", w, err)
+	}
+	if err := parts.Get("title").Decode(&w); err == nil {
+		t.Error("expected text/plain part not to decode")
+	}
+
+	tests := []struct {
+		name, fileName, mediaType, content string
+		inFile                             bool
+	}{
+		{"metadata", "", "application/json", `{"name":"a","count":1}`, true},
+		{"title", "", "text/plain", "Quarterly report", false},
+		{"file", "report.bin", "application/octet-stream", "0123456789", false},
+	}
+	for idx, test := range tests {
+		p := parts[idx]
+		if p.Name != test.name || p.FileName != test.fileName || p.MediaType.String() != test.mediaType {
+			t.Errorf("%d: expected %s %s %s, got %s %s %s", idx, test.name, test.fileName, test.mediaType, p.Name, p.FileName, p.MediaType)
+		}
+		f, err := p.Open()
+		if err != nil {
+			t.Fatalf("%d: %q", idx, err)
+		}
+		content, _ := ioutil.ReadAll(f)
+		f.Close()
+		if string(content) != test.content || p.Size != int64(len(test.content)) || p.InFile() != test.inFile {
+			t.Errorf("%d: expected '%s' in file %t, got '%s' (%d) in file %t", idx, test.content, test.inFile, content, p.Size, p.InFile())
+		}
+	}
+
+	path := parts[0].path
+	if err := parts.RemoveAll(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Errorf("expected temporary file to be removed, got %v", err)
+	}
+	if parts[0].InFile() {
+		t.Error("expected part to be removed")
+	}
+}
+
+func TestReaderLimits(t *testing.T) {
+	rd, err := NewReader(request(`multipart/form-data; boundary="xyz"`, formData))
+	if err != nil {
+		t.Fatal(err)
+	}
+	rd.Threshold = 100
+	rd.MaxMemory = 20
+	rd.Dir = t.TempDir()
+	parts, err := rd.ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer parts.RemoveAll()
+	for idx, inFile := range []bool{true, false, true} {
+		if parts[idx].InFile() != inFile {
+			t.Errorf("%d: expected in file %t, got %t", idx, inFile, parts[idx].InFile())
+		}
+	}
+
+	rd, err = NewReader(request(`multipart/form-data; boundary="xyz"`, formData))
+	if err != nil {
+		t.Fatal(err)
+	}
+	rd.MaxParts = 2
+	_, err = rd.ReadAll()
+	if p, ok := err.(*render.Problem); !ok || p.Status != 413 || p.Detail != "A multipart body may hold at most 2 parts" {
+		t.Errorf("expected 413 problem, got %v", err)
+	}
+}
+
+func TestReaderRelated(t *testing.T) {
+	body := `--b
+Content-Type: application/xml
+Content-ID: <attachment@example.com>
+
+<widget><name>b</name><count>2</count></widget>
+--b
+Content-Type: application/json
+Content-ID: <root@example.com>
+
+{"name":"a","count":1}
+--b--
+`
+	rd, err := NewReader(request(`multipart/related; boundary=b; type="application/json"; start="<root@example.com>"`, body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	parts, err := rd.ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rd.MediaType().Params["type"] != "application/json" {
+		t.Errorf("expected type parameter, got %v", rd.MediaType().Params)
+	}
+	var w widget
+	if err := parts.Root(rd.MediaType()).Decode(&w); err != nil || w != (widget{"a", 1}) {
+		t.Errorf("expected root {a 1}, got %+v and %v", w, err)
+	}
+	if err := parts.ContentID("<attachment@example.com>").Decode(&w); err != nil || w != (widget{"b", 2}) {
+		t.Errorf("expected attachment {b 2}, got %+v and %v", w, err)
+	}
+	if parts.ContentID("missing") != nil || parts.Get("missing") != nil {
+		t.Error("expected missing parts to be nil")
+	}
+	if p := parts.Root(&MultipartRelated); p != parts[0] {
+		t.Errorf("expected first part as root without start, got %+v", p)
+	}
+}
+
+func TestReaderErrors(t *testing.T) {
+	_, err := NewReader(request("application/json", "{}"))
+	if _, ok := err.(*codec.UnsupportedMediaTypeError); !ok {
+		t.Errorf("expected unsupported media type, got %v", err)
+	}
+	_, err = NewReader(request("multipart/mixed", ""))
+	if p, ok := err.(*render.Problem); !ok || p.Status != 400 || p.Detail != "Missing multipart boundary" {
+		t.Errorf("expected 400 problem, got %v", err)
+	}
+
+	rd, err := NewReader(request("multipart/mixed; boundary=xyz", "--xyz\nContent-Type: text/plain\n\nunterminated"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = rd.ReadAll()
+	if p, ok := err.(*render.Problem); !ok || p.Status != 400 {
+		t.Errorf("expected 400 problem, got %v", err)
+	}
+}
+
+func TestNewBodyReader(t *testing.T) {
+	body := "--inner\r\nContent-Type: application/json\r\n\r\n{\"name\":\"c\",\"count\":3}\r\n--inner--\r\n"
+	rd, err := NewBodyReader("multipart/mixed; boundary=inner", strings.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	p, err := rd.NextPart()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var w widget
+	if err := p.Decode(&w); err != nil || w != (widget{"c", 3}) {
+		t.Errorf("expected {c 3}, got %+v and %v", w, err)
+	}
+}
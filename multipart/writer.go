@@ -0,0 +1,116 @@
+// Copyright © 2017 Walter Scheper <walter.scheper@gmail.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package multipart
+
+import (
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/textproto"
+
+	"github.com/wfscheper/mtrest"
+	"github.com/wfscheper/mtrest/codec"
+)
+
+// Writer writes a multipart body.
+type Writer struct {
+	mediaType *mtrest.MediaType
+	mw        *multipart.Writer
+}
+
+// NewWriter returns a Writer of a body of media type m, such as
+// MultipartMixed, to w. The boundary is taken from the boundary parameter of
+// m, or generated if m has none. Nothing is written to w until the first
+// part is created, so a handler can set the Content-Type header to
+// MediaType before writing a response body.
+func NewWriter(w io.Writer, m *mtrest.MediaType) (*Writer, error) {
+	if m.Type != "multipart" {
+		return nil, fmt.Errorf("Not a multipart media type: '%s'", m)
+	}
+	mw := multipart.NewWriter(w)
+	m = m.Clone()
+	if boundary := m.Params["boundary"]; boundary != "" {
+		if err := mw.SetBoundary(boundary); err != nil {
+			return nil, fmt.Errorf("Invalid multipart boundary: '%s'", boundary)
+		}
+	} else {
+		m.Params["boundary"] = mw.Boundary()
+	}
+	return &Writer{mediaType: m, mw: mw}, nil
+}
+
+// MediaType returns the media type of the body, with its boundary.
+func (w *Writer) MediaType() *mtrest.MediaType {
+	return w.mediaType.Clone()
+}
+
+// CreatePart starts a part with header, and returns a writer for its
+// content. The part's content ends when the next part is created, or the
+// Writer is closed.
+func (w *Writer) CreatePart(header textproto.MIMEHeader) (io.Writer, error) {
+	return w.mw.CreatePart(header)
+}
+
+// Encode writes v as a part of media type m, using the codec for m. Header
+// may add other headers, such as Content-Disposition or Content-ID, to the
+// part.
+func (w *Writer) Encode(m *mtrest.MediaType, v interface{}, header textproto.MIMEHeader) error {
+	c, ok := codec.For(m)
+	if !ok {
+		return &codec.UnsupportedMediaTypeError{MediaType: m}
+	}
+	data, err := c.Marshal(v)
+	if err != nil {
+		return err
+	}
+	h := textproto.MIMEHeader{}
+	for k, v := range header {
+		h[k] = v
+	}
+	h.Set("Content-Type", m.String())
+	part, err := w.CreatePart(h)
+	if err != nil {
+		return err
+	}
+	_, err = part.Write(data)
+	return err
+}
+
+// WriteField writes a form-data part called name with value as its content.
+func (w *Writer) WriteField(name, value string) error {
+	part, err := w.CreatePart(textproto.MIMEHeader{
+		"Content-Disposition": {mime.FormatMediaType("form-data", map[string]string{"name": name})},
+	})
+	if err != nil {
+		return err
+	}
+	_, err = io.WriteString(part, value)
+	return err
+}
+
+// CreateFormFile starts a form-data part called name, holding a file called
+// filename of media type m, and returns a writer for its content.
+func (w *Writer) CreateFormFile(name, filename string, m *mtrest.MediaType) (io.Writer, error) {
+	return w.CreatePart(textproto.MIMEHeader{
+		"Content-Disposition": {mime.FormatMediaType("form-data", map[string]string{"name": name, "filename": filename})},
+		"Content-Type":        {m.String()},
+	})
+}
+
+// Close writes the closing boundary of the body.
+func (w *Writer) Close() error {
+	return w.mw.Close()
+}
@@ -0,0 +1,108 @@
+// Copyright © 2017 Walter Scheper <walter.scheper@gmail.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package multipart
+
+import (
+	"bytes"
+	"io"
+	"net/http/httptest"
+	"net/textproto"
+	"strings"
+	"testing"
+
+	"github.com/wfscheper/mtrest"
+)
+
+func TestWriter(t *testing.T) {
+	m := MultipartMixed.Clone()
+	m.Params["boundary"] = "xyz"
+	var buf bytes.Buffer
+	w, err := NewWriter(&buf, m)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Encode(&mtrest.ApplicationJSON, widget{"a", 1}, textproto.MIMEHeader{"Content-Id": {"<a>"}}); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Encode(&mtrest.ApplicationXML, widget{"b", 2}, nil); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	expected := strings.ReplaceAll(`--xyz
+Content-Id: <a>
+Content-Type: application/json
+
+{"name":"a","count":1}
+--xyz
+Content-Type: application/xml
+
+<widget><name>b</name><count>2</count></widget>
+--xyz--
+`, "\n", "\r\n")
+	if buf.String() != expected {
+		t.Errorf("expected '%s', got '%s'", expected, buf.String())
+	}
+	if actual := w.MediaType().String(); actual != "multipart/mixed; boundary=xyz" {
+		t.Errorf("expected 'multipart/mixed; boundary=xyz', got '%s'", actual)
+	}
+	if m, _ := mtrest.NewMediaType("text/plain"); w.Encode(m, "a", nil) == nil {
+		t.Error("expected error encoding text/plain")
+	}
+}
+
+func TestWriterRoundTrip(t *testing.T) {
+	var buf bytes.Buffer
+	w, err := NewWriter(&buf, &MultipartFormData)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if MultipartFormData.Params["boundary"] != "" {
+		t.Error("expected NewWriter not to modify its media type")
+	}
+	w.WriteField("title", "Quarterly report")
+	f, _ := w.CreateFormFile("file", "report.csv", &mtrest.MediaType{Type: "text", SubType: "csv"})
+	io.WriteString(f, "a,b\n1,2\n")
+	w.Close()
+
+	r := httptest.NewRequest("POST", "/uploads", &buf)
+	r.Header.Set("Content-Type", w.MediaType().String())
+	rd, err := NewReader(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	parts, err := rd.ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(parts) != 2 || parts.Get("title").data == nil || string(parts.Get("title").data) != "Quarterly report" {
+		t.Fatalf("expected title field, got %+v", parts)
+	}
+	if p := parts.Get("file"); p == nil || p.FileName != "report.csv" || p.MediaType.String() != "text/csv" || string(p.data) != "a,b\n1,2\n" {
+		t.Errorf("expected report.csv, got %+v", p)
+	}
+}
+
+func TestNewWriterErrors(t *testing.T) {
+	if _, err := NewWriter(io.Discard, &mtrest.ApplicationJSON); err == nil || err.Error() != "Not a multipart media type: 'application/json'" {
+		t.Errorf("expected error, got %v", err)
+	}
+	m := MultipartMixed.Clone()
+	m.Params["boundary"] = strings.Repeat("x", 71)
+	if _, err := NewWriter(io.Discard, m); err == nil {
+		t.Error("expected invalid boundary error, got nil")
+	}
+}
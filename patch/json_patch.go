@@ -0,0 +1,299 @@
+// Copyright © 2017 Walter Scheper <walter.scheper@gmail.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package patch
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math/big"
+)
+
+// Operation is a single operation of a JSON Patch.
+type Operation struct {
+	Op    string          `json:"op"`
+	Path  string          `json:"path"`
+	From  string          `json:"from,omitempty"`
+	Value json.RawMessage `json:"value,omitempty"`
+}
+
+// JSONPatch is an RFC 6902 JSON Patch document.
+type JSONPatch []Operation
+
+// NewJSONPatch parses data as a JSON Patch, and checks that each operation
+// has the members it requires.
+func NewJSONPatch(data []byte) (JSONPatch, error) {
+	var raw []map[string]json.RawMessage
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, fmt.Errorf("Error parsing JSON Patch: %s", err)
+	}
+	var p JSONPatch
+	if err := json.Unmarshal(data, &p); err != nil {
+		return nil, fmt.Errorf("Error parsing JSON Patch: %s", err)
+	}
+	for i, members := range raw {
+		for _, member := range p[i].required() {
+			if _, ok := members[member]; !ok {
+				return nil, fmt.Errorf("Error parsing JSON Patch: operation %d is missing '%s'", i, member)
+			}
+		}
+		if _, _, err := p[i].pointers(); err != nil {
+			return nil, fmt.Errorf("Error parsing JSON Patch: operation %d: %s", i, err)
+		}
+	}
+	return p, nil
+}
+
+// required returns the members the operation must have.
+func (op Operation) required() []string {
+	switch op.Op {
+	case "add", "replace", "test":
+		return []string{"path", "value"}
+	case "move", "copy":
+		return []string{"path", "from"}
+	}
+	return []string{"path"}
+}
+
+func (op Operation) pointers() (path, from Pointer, err error) {
+	switch op.Op {
+	case "add", "remove", "replace", "move", "copy", "test":
+	default:
+		return nil, nil, fmt.Errorf("unknown op '%s'", op.Op)
+	}
+	if path, err = NewPointer(op.Path); err != nil {
+		return nil, nil, err
+	}
+	if op.Op == "move" || op.Op == "copy" {
+		if from, err = NewPointer(op.From); err != nil {
+			return nil, nil, err
+		}
+	}
+	return path, from, nil
+}
+
+// Apply applies the patch to doc, a JSON document, and returns the patched
+// document. The patch is applied atomically: if any operation fails, an error
+// is returned and doc is left as it was.
+func (p JSONPatch) Apply(doc []byte) ([]byte, error) {
+	v, err := decode(doc)
+	if err != nil {
+		return nil, err
+	}
+	for i, op := range p {
+		if v, err = op.apply(v); err != nil {
+			return nil, fmt.Errorf("Error applying operation %d (%s): %s", i, op.Op, err)
+		}
+	}
+	return json.Marshal(v)
+}
+
+func (op Operation) apply(doc interface{}) (interface{}, error) {
+	path, from, err := op.pointers()
+	if err != nil {
+		return nil, err
+	}
+	var value interface{}
+	if op.Op == "add" || op.Op == "replace" || op.Op == "test" {
+		if value, err = decode(op.Value); err != nil {
+			return nil, err
+		}
+	}
+	switch op.Op {
+	case "add":
+		return add(doc, path, value)
+	case "remove":
+		return remove(doc, path)
+	case "replace":
+		if _, err := path.Get(doc); err != nil {
+			return nil, err
+		}
+		return set(doc, path, value)
+	case "move":
+		if len(path) > len(from) && path.HasPrefix(from) {
+			return nil, fmt.Errorf("Cannot move '%s' into itself", from)
+		}
+		if value, err = from.Get(doc); err != nil {
+			return nil, err
+		}
+		if doc, err = remove(doc, from); err != nil {
+			return nil, err
+		}
+		return add(doc, path, value)
+	case "copy":
+		if value, err = from.Get(doc); err != nil {
+			return nil, err
+		}
+		return add(doc, path, clone(value))
+	default:
+		actual, err := path.Get(doc)
+		if err != nil {
+			return nil, err
+		}
+		if !equal(actual, value) {
+			return nil, fmt.Errorf("Value at '%s' does not match", path)
+		}
+		return doc, nil
+	}
+}
+
+// decode decodes data, keeping numbers as json.Number so that they survive
+// the round trip exactly.
+func decode(data []byte) (interface{}, error) {
+	var v interface{}
+	d := json.NewDecoder(bytes.NewReader(data))
+	d.UseNumber()
+	if err := d.Decode(&v); err != nil {
+		return nil, err
+	}
+	return v, nil
+}
+
+// add inserts value at p, replacing any member of an object, and shifting
+// the elements of an array. It returns the new document.
+func add(doc interface{}, p Pointer, value interface{}) (interface{}, error) {
+	if len(p) == 0 {
+		return value, nil
+	}
+	parent, token := p[:len(p)-1], p[len(p)-1]
+	container, err := parent.Get(doc)
+	if err != nil {
+		return nil, err
+	}
+	switch v := container.(type) {
+	case map[string]interface{}:
+		v[token] = value
+		return doc, nil
+	case []interface{}:
+		i, err := index(token, len(v), true)
+		if err != nil {
+			return nil, fmt.Errorf("Path '%s' does not exist", p)
+		}
+		v = append(v, nil)
+		copy(v[i+1:], v[i:])
+		v[i] = value
+		return set(doc, parent, v)
+	}
+	return nil, fmt.Errorf("Path '%s' does not exist", p)
+}
+
+// remove removes the value at p, which must exist, and returns the new
+// document.
+func remove(doc interface{}, p Pointer) (interface{}, error) {
+	if len(p) == 0 {
+		return nil, fmt.Errorf("Cannot remove the whole document")
+	}
+	parent, token := p[:len(p)-1], p[len(p)-1]
+	container, err := parent.Get(doc)
+	if err != nil {
+		return nil, err
+	}
+	switch v := container.(type) {
+	case map[string]interface{}:
+		if _, ok := v[token]; ok {
+			delete(v, token)
+			return doc, nil
+		}
+	case []interface{}:
+		if i, err := index(token, len(v), false); err == nil {
+			return set(doc, parent, append(v[:i], v[i+1:]...))
+		}
+	}
+	return nil, fmt.Errorf("Path '%s' does not exist", p)
+}
+
+// set replaces the value at p, whose parent must exist, and returns the new
+// document.
+func set(doc interface{}, p Pointer, value interface{}) (interface{}, error) {
+	if len(p) == 0 {
+		return value, nil
+	}
+	container, err := p[:len(p)-1].Get(doc)
+	if err != nil {
+		return nil, err
+	}
+	token := p[len(p)-1]
+	switch v := container.(type) {
+	case map[string]interface{}:
+		v[token] = value
+		return doc, nil
+	case []interface{}:
+		if i, err := index(token, len(v), false); err == nil {
+			v[i] = value
+			return doc, nil
+		}
+	}
+	return nil, fmt.Errorf("Path '%s' does not exist", p)
+}
+
+// clone returns a deep copy of a decoded JSON value.
+func clone(v interface{}) interface{} {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		c := make(map[string]interface{}, len(v))
+		for k, e := range v {
+			c[k] = clone(e)
+		}
+		return c
+	case []interface{}:
+		c := make([]interface{}, len(v))
+		for i, e := range v {
+			c[i] = clone(e)
+		}
+		return c
+	}
+	return v
+}
+
+// equal reports whether two decoded JSON values are equal. Numbers are
+// compared by value, so 1 and 1.0 are equal.
+func equal(a, b interface{}) bool {
+	switch a := a.(type) {
+	case json.Number:
+		b, ok := b.(json.Number)
+		if !ok {
+			return false
+		}
+		x, ok := new(big.Rat).SetString(string(a))
+		if !ok {
+			return false
+		}
+		y, ok := new(big.Rat).SetString(string(b))
+		return ok && x.Cmp(y) == 0
+	case map[string]interface{}:
+		b, ok := b.(map[string]interface{})
+		if !ok || len(a) != len(b) {
+			return false
+		}
+		for k, e := range a {
+			if f, ok := b[k]; !ok || !equal(e, f) {
+				return false
+			}
+		}
+		return true
+	case []interface{}:
+		b, ok := b.([]interface{})
+		if !ok || len(a) != len(b) {
+			return false
+		}
+		for i := range a {
+			if !equal(a[i], b[i]) {
+				return false
+			}
+		}
+		return true
+	}
+	return a == b
+}
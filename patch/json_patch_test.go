@@ -0,0 +1,99 @@
+// Copyright © 2017 Walter Scheper <walter.scheper@gmail.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package patch
+
+import (
+	"testing"
+)
+
+func TestJSONPatch(t *testing.T) {
+	tests := []struct {
+		doc      string
+		patch    string
+		expected string
+	}{
+		// RFC 6902, Appendix A
+		{`{"foo":"bar"}`, `[{"op":"add","path":"/baz","value":"qux"}]`, `{"baz":"qux","foo":"bar"}`},
+		{`{"foo":["bar","baz"]}`, `[{"op":"add","path":"/foo/1","value":"qux"}]`, `{"foo":["bar","qux","baz"]}`},
+		{`{"baz":"qux","foo":"bar"}`, `[{"op":"remove","path":"/baz"}]`, `{"foo":"bar"}`},
+		{`{"foo":["bar","qux","baz"]}`, `[{"op":"remove","path":"/foo/1"}]`, `{"foo":["bar","baz"]}`},
+		{`{"baz":"qux","foo":"bar"}`, `[{"op":"replace","path":"/baz","value":"boo"}]`, `{"baz":"boo","foo":"bar"}`},
+		{`{"foo":{"bar":"baz","waldo":"fred"},"qux":{"corge":"grault"}}`, `[{"op":"move","from":"/foo/waldo","path":"/qux/thud"}]`, `{"foo":{"bar":"baz"},"qux":{"corge":"grault","thud":"fred"}}`},
+		{`{"foo":["all","grass","cows","eat"]}`, `[{"op":"move","from":"/foo/1","path":"/foo/3"}]`, `{"foo":["all","cows","eat","grass"]}`},
+		{`{"baz":"qux","foo":["a",2,"c"]}`, `[{"op":"test","path":"/baz","value":"qux"},{"op":"test","path":"/foo/1","value":2}]`, `{"baz":"qux","foo":["a",2,"c"]}`},
+		{`{"foo":"bar"}`, `[{"op":"add","path":"/child","value":{"grandchild":{}}}]`, `{"child":{"grandchild":{}},"foo":"bar"}`},
+		{`{"foo":"bar"}`, `[{"op":"add","path":"/baz","value":"qux","xyz":123}]`, `{"baz":"qux","foo":"bar"}`},
+		{`{"foo":["bar"]}`, `[{"op":"add","path":"/foo/-","value":["abc","def"]}]`, `{"foo":["bar",["abc","def"]]}`},
+		{`{"/":9,"~1":10}`, `[{"op":"test","path":"/~01","value":10}]`, `{"/":9,"~1":10}`},
+
+		{`{"foo":"bar"}`, `[{"op":"add","path":"","value":[1]}]`, `[1]`},
+		{`{"foo":null}`, `[{"op":"test","path":"/foo","value":null},{"op":"replace","path":"/foo","value":1.0}]`, `{"foo":1.0}`},
+		{`{"n":12345678901234567890}`, `[{"op":"test","path":"/n","value":1.234567890123456789e19}]`, `{"n":12345678901234567890}`},
+		{`{"a":{"b":[1]}}`, `[{"op":"copy","from":"/a","path":"/c"},{"op":"add","path":"/c/b/0","value":0}]`, `{"a":{"b":[1]},"c":{"b":[0,1]}}`},
+		{`{"a":[1,2]}`, `[{"op":"move","from":"/a/0","path":"/a/0"}]`, `{"a":[1,2]}`},
+	}
+	for idx, test := range tests {
+		p, err := NewJSONPatch([]byte(test.patch))
+		if err != nil {
+			t.Fatalf("%d: %q", idx, err)
+		}
+		actual, err := p.Apply([]byte(test.doc))
+		if err != nil {
+			t.Fatalf("%d: %q", idx, err)
+		}
+		if string(actual) != test.expected {
+			t.Errorf("%d: expected '%s', got '%s'", idx, test.expected, actual)
+		}
+	}
+}
+
+func TestJSONPatchErrors(t *testing.T) {
+	tests := []struct {
+		doc      string
+		patch    string
+		expected string
+	}{
+		{`{}`, `[{"op":"add","path":"/a"}]`, "Error parsing JSON Patch: operation 0 is missing 'value'"},
+		{`{}`, `[{"op":"move","path":"/a"}]`, "Error parsing JSON Patch: operation 0 is missing 'from'"},
+		{`{}`, `[{"op":"remove"}]`, "Error parsing JSON Patch: operation 0 is missing 'path'"},
+		{`{}`, `[{"op":"frobnicate","path":"/a"}]`, "Error parsing JSON Patch: operation 0: unknown op 'frobnicate'"},
+		{`{}`, `[{"op":"remove","path":"a"}]`, "Error parsing JSON Patch: operation 0: Error parsing JSON pointer: 'a'"},
+
+		// RFC 6902, Appendix A
+		{`{"foo":"bar"}`, `[{"op":"add","path":"/baz/bat","value":"qux"}]`, "Error applying operation 0 (add): Path '/baz' does not exist"},
+		{`{"baz":"qux"}`, `[{"op":"test","path":"/baz","value":"bar"}]`, "Error applying operation 0 (test): Value at '/baz' does not match"},
+		{`{"foo":["bar"]}`, `[{"op":"add","path":"/foo/2","value":"x"}]`, "Error applying operation 0 (add): Path '/foo/2' does not exist"},
+		{`{"/":9,"~1":10}`, `[{"op":"test","path":"/~01","value":"10"}]`, "Error applying operation 0 (test): Value at '/~01' does not match"},
+
+		{`{"a":1}`, `[{"op":"replace","path":"/b","value":2}]`, "Error applying operation 0 (replace): Path '/b' does not exist"},
+		{`{"a":1}`, `[{"op":"remove","path":""}]`, "Error applying operation 0 (remove): Cannot remove the whole document"},
+		{`{"a":{"b":1}}`, `[{"op":"move","from":"/a","path":"/a/c"}]`, "Error applying operation 0 (move): Cannot move '/a' into itself"},
+		{`{"a":[1]}`, `[{"op":"remove","path":"/a/-"}]`, "Error applying operation 0 (remove): Path '/a/-' does not exist"},
+		{`{"a":[1]}`, `[{"op":"remove","path":"/a/0"},{"op":"test","path":"/a","value":[1]}]`, "Error applying operation 1 (test): Value at '/a' does not match"},
+	}
+	for idx, test := range tests {
+		p, err := NewJSONPatch([]byte(test.patch))
+		if err == nil {
+			_, err = p.Apply([]byte(test.doc))
+		}
+		if err == nil || err.Error() != test.expected {
+			t.Errorf("%d: expected '%s', got '%v'", idx, test.expected, err)
+		}
+	}
+
+	if _, err := NewJSONPatch([]byte(`{"op":"remove","path":"/a"}`)); err == nil {
+		t.Error("expected error for a patch that is not an array")
+	}
+}
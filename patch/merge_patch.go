@@ -0,0 +1,66 @@
+// Copyright © 2017 Walter Scheper <walter.scheper@gmail.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package patch
+
+import (
+	"encoding/json"
+	"errors"
+)
+
+// MergePatch is an RFC 7396 JSON Merge Patch document. Members of the patch
+// replace the members of the target, recursively for objects, and members
+// whose value is null are removed.
+type MergePatch json.RawMessage
+
+// NewMergePatch parses data as a JSON Merge Patch.
+func NewMergePatch(data []byte) (MergePatch, error) {
+	if !json.Valid(data) {
+		return nil, errors.New("Error parsing JSON merge patch: invalid JSON")
+	}
+	return MergePatch(data), nil
+}
+
+// Apply applies the patch to doc, a JSON document, and returns the patched
+// document.
+func (p MergePatch) Apply(doc []byte) ([]byte, error) {
+	target, err := decode(doc)
+	if err != nil {
+		return nil, err
+	}
+	patch, err := decode(p)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(merge(target, patch))
+}
+
+func merge(target, patch interface{}) interface{} {
+	p, ok := patch.(map[string]interface{})
+	if !ok {
+		return patch
+	}
+	t, ok := target.(map[string]interface{})
+	if !ok {
+		t = map[string]interface{}{}
+	}
+	for k, v := range p {
+		if v == nil {
+			delete(t, k)
+		} else {
+			t[k] = merge(t[k], v)
+		}
+	}
+	return t
+}
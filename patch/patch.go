@@ -0,0 +1,172 @@
+// Copyright © 2017 Walter Scheper <walter.scheper@gmail.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package patch applies the bodies of PATCH requests to resources.
+package patch
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"io"
+	"io/ioutil"
+	"net/http"
+
+	"github.com/wfscheper/mtrest"
+	"github.com/wfscheper/mtrest/codec"
+	"github.com/wfscheper/mtrest/internal/fitness"
+	"github.com/wfscheper/mtrest/render"
+)
+
+var (
+	// ApplicationJSONPatch is the RFC 6902 media type for JSON Patch
+	// documents.
+	ApplicationJSONPatch = mtrest.MediaType{Type: "application", SubType: "json-patch+json", Params: map[string]string{}, Weight: 1.0}
+
+	// ApplicationMergePatchJSON is the RFC 7396 media type for JSON Merge
+	// Patch documents.
+	ApplicationMergePatchJSON = mtrest.MediaType{Type: "application", SubType: "merge-patch+json", Params: map[string]string{}, Weight: 1.0}
+
+	// ApplicationXMLPatch is the RFC 7351 media type for RFC 5261 XML patch
+	// documents.
+	ApplicationXMLPatch = mtrest.MediaType{Type: "application", SubType: "xml-patch+xml", Params: map[string]string{}, Weight: 1.0}
+)
+
+// MaxBytes is the largest patch Apply reads, in bytes. Larger patches are
+// refused with a *codec.TooLargeError. Zero or less means no limit.
+var MaxBytes int64 = 1 << 20
+
+type patcher interface {
+	Apply(doc []byte) ([]byte, error)
+}
+
+// format is a patch media type, and the representation its patches apply
+// to.
+type format struct {
+	mediaType *mtrest.MediaType
+	parse     func(data []byte) (patcher, error)
+	marshal   func(v interface{}) ([]byte, error)
+	unmarshal func(data []byte, v interface{}) error
+}
+
+var formats = []format{
+	{&ApplicationJSONPatch, func(data []byte) (patcher, error) { return NewJSONPatch(data) }, json.Marshal, unmarshalJSON},
+	{&ApplicationMergePatchJSON, func(data []byte) (patcher, error) { return NewMergePatch(data) }, json.Marshal, unmarshalJSON},
+	{&ApplicationXMLPatch, func(data []byte) (patcher, error) { return NewXMLPatch(data) }, xml.Marshal, xml.Unmarshal},
+}
+
+// MediaTypes returns the patch formats understood by Apply. A resource can
+// return them from its Consumes method for PATCH, so that the router
+// advertises them in Accept-Patch and answers other formats with 415.
+func MediaTypes() []*mtrest.MediaType {
+	types := make([]*mtrest.MediaType, len(formats))
+	for i, f := range formats {
+		types[i] = f.mediaType
+	}
+	return types
+}
+
+// match returns the format for the content type m. A media range is not a
+// content type, so wildcards match nothing.
+func match(m *mtrest.MediaType) (format, bool) {
+	if m.Type == "*" || m.SubType == "*" {
+		return format{}, false
+	}
+	if score := fitness.BestMatch(m, MediaTypes()); score != nil {
+		return formats[score.Index], true
+	}
+	return format{}, false
+}
+
+// Validator is implemented by representations that check their own
+// invariants. Apply validates the patched representation before accepting
+// it.
+type Validator interface {
+	Validate() error
+}
+
+// Apply applies the patch in the body of r to v, the current state of a
+// resource. The patch format is chosen by the request's Content-Type: JSON
+// Patch and JSON Merge Patch apply to the JSON representation of v, and XML
+// patches to its XML representation. v is only changed if the whole patch
+// succeeds. Failures are returned as a *render.Problem, a
+// *codec.UnsupportedMediaTypeError or a *codec.TooLargeError, ready for
+// render.Error: 413 if the patch is larger than MaxBytes, 400 if it is
+// malformed, 409 if it cannot be applied to the current state, such as
+// when a test operation fails, and 422 if the result is not a valid
+// representation of the resource.
+func Apply[T any](r *http.Request, v *T) error {
+	m, err := mtrest.NewMediaType(r.Header.Get("Content-Type"))
+	if err != nil {
+		return &codec.UnsupportedMediaTypeError{}
+	}
+	f, ok := match(m)
+	if !ok {
+		return &codec.UnsupportedMediaTypeError{MediaType: m}
+	}
+	data, err := read(r.Body)
+	if err != nil {
+		return err
+	}
+	p, err := f.parse(data)
+	if err != nil {
+		return render.NewProblem(http.StatusBadRequest, err.Error())
+	}
+	doc, err := f.marshal(v)
+	if err != nil {
+		return err
+	}
+	if doc, err = p.Apply(doc); err != nil {
+		return render.NewProblem(http.StatusConflict, err.Error())
+	}
+	var patched T
+	if err := f.unmarshal(doc, &patched); err != nil {
+		return render.NewProblem(http.StatusUnprocessableEntity, err.Error())
+	}
+	if validator, ok := interface{}(&patched).(Validator); ok {
+		if err := validator.Validate(); err != nil {
+			var p *render.Problem
+			if errors.As(err, &p) {
+				return p
+			}
+			return render.NewProblem(http.StatusUnprocessableEntity, err.Error())
+		}
+	}
+	*v = patched
+	return nil
+}
+
+// read reads a patch, up to MaxBytes.
+func read(body io.Reader) ([]byte, error) {
+	if MaxBytes <= 0 {
+		return ioutil.ReadAll(body)
+	}
+	data, err := ioutil.ReadAll(io.LimitReader(body, MaxBytes+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(data)) > MaxBytes {
+		return nil, &codec.TooLargeError{Limit: MaxBytes}
+	}
+	return data, nil
+}
+
+// unmarshalJSON decodes a patched JSON representation. Members that v does
+// not have make the result invalid.
+func unmarshalJSON(data []byte, v interface{}) error {
+	d := json.NewDecoder(bytes.NewReader(data))
+	d.DisallowUnknownFields()
+	return d.Decode(v)
+}
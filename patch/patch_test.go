@@ -0,0 +1,155 @@
+// Copyright © 2017 Walter Scheper <walter.scheper@gmail.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package patch
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/wfscheper/mtrest"
+	"github.com/wfscheper/mtrest/render"
+	"github.com/wfscheper/mtrest/router"
+)
+
+type widget struct {
+	Name  string   `json:"name" xml:"name"`
+	Count int      `json:"count" xml:"count"`
+	Tags  []string `json:"tags,omitempty" xml:"tag"`
+}
+
+func (w widget) Validate() error {
+	if w.Name == "" {
+		return errors.New("name is required")
+	}
+	if w.Count < 0 {
+		p := render.NewProblem(http.StatusUnprocessableEntity, "Invalid widget")
+		p.InvalidParams = []render.InvalidParam{{Name: "count", Reason: "must not be negative"}}
+		return p
+	}
+	return nil
+}
+
+type resource struct {
+	current *widget
+}
+
+func (res resource) Get(w http.ResponseWriter, r *http.Request) {
+	render.Render(w, r, http.StatusOK, res.current)
+}
+
+func (res resource) Patch(w http.ResponseWriter, r *http.Request) {
+	if err := Apply(r, res.current); err != nil {
+		render.Error(w, r, err)
+		return
+	}
+	render.Render(w, r, http.StatusOK, res.current)
+}
+
+func (res resource) Consumes(method string) []*mtrest.MediaType {
+	if method == http.MethodPatch {
+		return MediaTypes()
+	}
+	return nil
+}
+
+func TestApply(t *testing.T) {
+	tests := []struct {
+		contentType string
+		body        string
+		status      int
+		expected    string
+	}{
+		{"application/json-patch+json", `[{"op":"replace","path":"/name","value":"b"},{"op":"add","path":"/tags/-","value":"new"}]`, 200, `{"name":"b","count":1,"tags":["old","new"]}`},
+		{"application/json-patch+json", `[{"op":"test","path":"/count","value":1.0},{"op":"remove","path":"/tags"}]`, 200, `{"name":"a","count":1}`},
+		{"application/json-patch+json", `[{"op":"replace","path":"/name"}]`, 400, `"detail":"Error parsing JSON Patch: operation 0 is missing 'value'"`},
+		{"application/json-patch+json", `[{"op":"test","path":"/count","value":2},{"op":"replace","path":"/name","value":"b"}]`, 409, `"detail":"Error applying operation 0 (test): Value at '/count' does not match"`},
+		{"application/json-patch+json", `[{"op":"add","path":"/colour","value":"red"}]`, 422, `"status":422`},
+		{"application/json-patch+json", `[{"op":"replace","path":"/count","value":"many"}]`, 422, `"status":422`},
+		{"application/json-patch+json", `[{"op":"replace","path":"/name","value":""}]`, 422, `"detail":"name is required"`},
+		{"application/json-patch+json", `[{"op":"replace","path":"/count","value":-1}]`, 422, `"invalid-params":[{"name":"count","reason":"must not be negative"}]`},
+		{"application/merge-patch+json", `{"name":"b","tags":null}`, 200, `{"name":"b","count":1}`},
+		{"application/merge-patch+json", `{"count":"many"}`, 422, `"status":422`},
+		{"application/merge-patch+json", `{"name":`, 400, `"detail":"Error parsing JSON merge patch: invalid JSON"`},
+		{"application/xml-patch+xml", `<diff><replace sel="/widget/name/text()">b</replace><add sel="/widget"><tag>new</tag></add></diff>`, 200, `{"name":"b","count":1,"tags":["old","new"]}`},
+		{"application/xml-patch+xml", `<diff><remove sel="/widget/tag"/></diff>`, 200, `{"name":"a","count":1}`},
+		{"application/xml-patch+xml", `<diff><remove sel="/widget/colour"/></diff>`, 409, `"detail":"Error applying operation 0 (remove): Selector '/widget/colour' matches no node"`},
+		{"application/xml-patch+xml", `<diff><replace sel="/widget/count/text()">-2</replace></diff>`, 422, `"invalid-params"`},
+		{"application/xml-patch+xml", `<diff><move sel="/widget"/></diff>`, 400, `"detail":"Error parsing XML patch: operation 0: unknown operation 'move'"`},
+		{"application/json", `{"name":"b"}`, 415, ""},
+		{"application/*", `{"name":"b"}`, 415, ""},
+	}
+	for idx, test := range tests {
+		current := &widget{"a", 1, []string{"old"}}
+		mux := router.New()
+		mux.Mount("/widgets/{id}", resource{current})
+
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest("PATCH", "/widgets/1", strings.NewReader(test.body))
+		r.Header.Set("Content-Type", test.contentType)
+		mux.ServeHTTP(w, r)
+		if w.Code != test.status {
+			t.Errorf("%d: expected %d, got %d: %s", idx, test.status, w.Code, w.Body)
+		}
+		if !strings.Contains(w.Body.String(), test.expected) {
+			t.Errorf("%d: expected body to contain '%s', got '%s'", idx, test.expected, w.Body)
+		}
+		if test.status != 200 && (current.Name != "a" || current.Count != 1 || len(current.Tags) != 1) {
+			t.Errorf("%d: expected resource to be unchanged, got %+v", idx, current)
+		}
+	}
+
+	mux := router.New()
+	mux.Mount("/widgets/{id}", resource{&widget{}})
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, httptest.NewRequest("OPTIONS", "/widgets/1", nil))
+	expected := "application/json-patch+json, application/merge-patch+json, application/xml-patch+xml"
+	if actual := w.Header().Get("Accept-Patch"); actual != expected {
+		t.Errorf("expected Accept-Patch '%s', got '%s'", expected, actual)
+	}
+
+	var v widget
+	r := httptest.NewRequest("PATCH", "/", strings.NewReader(`{}`))
+	r.Header.Set("Content-Type", "application/json")
+	if err := Apply(r, &v); err == nil || err.Error() != "Unsupported media type: 'application/json'" {
+		t.Errorf("expected unsupported media type, got %v", err)
+	}
+}
+
+func TestApplyMaxBytes(t *testing.T) {
+	defer func(max int64) { MaxBytes = max }(MaxBytes)
+	MaxBytes = 16
+	tests := []struct {
+		body   string
+		status int
+	}{
+		{`{"name":"b"}`, 200},
+		{`{"name":"bbbbb"}`, 200},
+		{`{"name":"bbbbbb"}`, 413},
+	}
+	for idx, test := range tests {
+		mux := router.New()
+		mux.Mount("/widgets/{id}", resource{&widget{"a", 1, nil}})
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest("PATCH", "/widgets/1", strings.NewReader(test.body))
+		r.Header.Set("Content-Type", "application/merge-patch+json")
+		mux.ServeHTTP(w, r)
+		if w.Code != test.status {
+			t.Errorf("%d: expected %d, got %d: %s", idx, test.status, w.Code, w.Body)
+		}
+	}
+}
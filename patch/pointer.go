@@ -0,0 +1,112 @@
+// Copyright © 2017 Walter Scheper <walter.scheper@gmail.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package patch
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+var (
+	escaper   = strings.NewReplacer("~", "~0", "/", "~1")
+	unescaper = strings.NewReplacer("~1", "/", "~0", "~")
+)
+
+// Pointer is an RFC 6901 JSON Pointer, split into its reference tokens. The
+// empty Pointer refers to the whole document.
+type Pointer []string
+
+// NewPointer parses s as a JSON Pointer.
+func NewPointer(s string) (Pointer, error) {
+	if s == "" {
+		return Pointer{}, nil
+	}
+	if s[0] != '/' {
+		return nil, fmt.Errorf("Error parsing JSON pointer: '%s'", s)
+	}
+	tokens := strings.Split(s[1:], "/")
+	for i, token := range tokens {
+		for j := 0; j < len(token); j++ {
+			if token[j] == '~' && (j+1 == len(token) || (token[j+1] != '0' && token[j+1] != '1')) {
+				return nil, fmt.Errorf("Error parsing JSON pointer: '%s'", s)
+			}
+		}
+		tokens[i] = unescaper.Replace(token)
+	}
+	return tokens, nil
+}
+
+func (p Pointer) String() string {
+	var b strings.Builder
+	for _, token := range p {
+		b.WriteByte('/')
+		b.WriteString(escaper.Replace(token))
+	}
+	return b.String()
+}
+
+// HasPrefix reports whether prefix refers to p or one of its ancestors.
+func (p Pointer) HasPrefix(prefix Pointer) bool {
+	if len(prefix) > len(p) {
+		return false
+	}
+	for i, token := range prefix {
+		if p[i] != token {
+			return false
+		}
+	}
+	return true
+}
+
+// Get returns the value that p refers to in doc, a document decoded by
+// encoding/json into interface{}.
+func (p Pointer) Get(doc interface{}) (interface{}, error) {
+	for i, token := range p {
+		switch v := doc.(type) {
+		case map[string]interface{}:
+			var ok bool
+			if doc, ok = v[token]; !ok {
+				return nil, fmt.Errorf("Path '%s' does not exist", p[:i+1])
+			}
+		case []interface{}:
+			n, err := index(token, len(v), false)
+			if err != nil {
+				return nil, fmt.Errorf("Path '%s' does not exist", p[:i+1])
+			}
+			doc = v[n]
+		default:
+			return nil, fmt.Errorf("Path '%s' does not exist", p[:i+1])
+		}
+	}
+	return doc, nil
+}
+
+// index parses token as an index into an array of length n. If end is true
+// the token "-", and the index n, refer to the position after the last
+// element.
+func index(token string, n int, end bool) (int, error) {
+	if end && token == "-" {
+		return n, nil
+	}
+	if token == "" || (len(token) > 1 && token[0] == '0') || strings.Trim(token, "0123456789") != "" {
+		return 0, fmt.Errorf("Invalid array index: '%s'", token)
+	}
+	i, err := strconv.Atoi(token)
+	if err != nil || i > n || (i == n && !end) {
+		return 0, fmt.Errorf("Invalid array index: '%s'", token)
+	}
+	return i, nil
+}
@@ -0,0 +1,92 @@
+// Copyright © 2017 Walter Scheper <walter.scheper@gmail.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package patch
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+func TestNewPointer(t *testing.T) {
+	tests := []struct {
+		s        string
+		expected Pointer
+		err      bool
+	}{
+		{"", Pointer{}, false},
+		{"/", Pointer{""}, false},
+		{"/foo/0", Pointer{"foo", "0"}, false},
+		{"/a~1b/m~0n", Pointer{"a/b", "m~n"}, false},
+		{"/~01", Pointer{"~1"}, false},
+		{"foo", nil, true},
+		{"/a~", nil, true},
+		{"/a~2", nil, true},
+	}
+	for idx, test := range tests {
+		actual, err := NewPointer(test.s)
+		if test.err {
+			if err == nil || err.Error() != "Error parsing JSON pointer: '"+test.s+"'" {
+				t.Errorf("%d: expected error, got %v", idx, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("%d: %q", idx, err)
+		}
+		if !reflect.DeepEqual(actual, test.expected) {
+			t.Errorf("%d: expected %q, got %q", idx, test.expected, actual)
+		}
+		if actual.String() != test.s {
+			t.Errorf("%d: expected '%s', got '%s'", idx, test.s, actual)
+		}
+	}
+}
+
+func TestPointerGet(t *testing.T) {
+	doc, _ := decode([]byte(`{"foo":["bar","baz"],"":0,"a/b":1,"m~n":8,"k":{"01":2}}`))
+	tests := []struct {
+		s        string
+		expected string
+	}{
+		{"/foo/0", "bar"},
+		{"/foo/1", "baz"},
+		{"/", "0"},
+		{"/a~1b", "1"},
+		{"/m~0n", "8"},
+		{"/k/01", "2"},
+		{"/foo/2", ""},
+		{"/foo/01", ""},
+		{"/foo/-", ""},
+		{"/foo/0/x", ""},
+		{"/missing", ""},
+	}
+	for idx, test := range tests {
+		p, _ := NewPointer(test.s)
+		actual, err := p.Get(doc)
+		if test.expected == "" {
+			if err == nil {
+				t.Errorf("%d: expected error, got %v", idx, actual)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("%d: %q", idx, err)
+		}
+		if fmt.Sprint(actual) != test.expected {
+			t.Errorf("%d: expected %s, got %v", idx, test.expected, actual)
+		}
+	}
+}
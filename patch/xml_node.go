@@ -0,0 +1,460 @@
+// Copyright © 2017 Walter Scheper <walter.scheper@gmail.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package patch
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+type nodeKind int
+
+const (
+	documentNode nodeKind = iota
+	elementNode
+	textNode
+	commentNode
+	procInstNode
+	directiveNode
+)
+
+// node is a node of an XML document. Names are kept as written, with the
+// prefix in Space, so that documents are written back as they were read.
+type node struct {
+	kind     nodeKind
+	name     xml.Name
+	attrs    []xml.Attr
+	data     string
+	parent   *node
+	children []*node
+}
+
+// parseXML parses data into a document node.
+func parseXML(data []byte) (*node, error) {
+	d := xml.NewDecoder(bytes.NewReader(data))
+	doc := &node{kind: documentNode}
+	cur := doc
+	for {
+		tok, err := d.RawToken()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		switch tok := tok.(type) {
+		case xml.StartElement:
+			n := &node{kind: elementNode, name: tok.Name, attrs: append([]xml.Attr(nil), tok.Attr...)}
+			cur.insert(len(cur.children), n)
+			cur = n
+		case xml.EndElement:
+			if cur.kind != elementNode || cur.name != tok.Name {
+				return nil, fmt.Errorf("unexpected end element </%s>", qname(tok.Name))
+			}
+			cur = cur.parent
+		case xml.CharData:
+			cur.insert(len(cur.children), &node{kind: textNode, data: string(tok)})
+		case xml.Comment:
+			cur.insert(len(cur.children), &node{kind: commentNode, data: string(tok)})
+		case xml.ProcInst:
+			cur.insert(len(cur.children), &node{kind: procInstNode, name: xml.Name{Local: tok.Target}, data: string(tok.Inst)})
+		case xml.Directive:
+			cur.insert(len(cur.children), &node{kind: directiveNode, data: string(tok)})
+		}
+	}
+	if cur != doc || doc.root() == nil {
+		return nil, errors.New("unexpected EOF")
+	}
+	return doc, nil
+}
+
+// root returns the root element of a document node.
+func (n *node) root() *node {
+	for _, c := range n.children {
+		if c.kind == elementNode {
+			return c
+		}
+	}
+	return nil
+}
+
+// insert inserts c as the child of n at i, merging adjacent text nodes.
+func (n *node) insert(i int, c *node) {
+	if c.kind == textNode {
+		if i > 0 && n.children[i-1].kind == textNode {
+			n.children[i-1].data += c.data
+			return
+		}
+		if i < len(n.children) && n.children[i].kind == textNode {
+			n.children[i].data = c.data + n.children[i].data
+			return
+		}
+	}
+	c.parent = n
+	n.children = append(n.children, nil)
+	copy(n.children[i+1:], n.children[i:])
+	n.children[i] = c
+}
+
+// remove removes n from its parent, merging the text nodes either side.
+func (n *node) remove() {
+	p := n.parent
+	i := n.index()
+	p.children = append(p.children[:i], p.children[i+1:]...)
+	if i > 0 && i < len(p.children) && p.children[i-1].kind == textNode && p.children[i].kind == textNode {
+		p.children[i-1].data += p.children[i].data
+		p.children = append(p.children[:i], p.children[i+1:]...)
+	}
+	n.parent = nil
+}
+
+// index returns the position of n among its parent's children.
+func (n *node) index() int {
+	for i, c := range n.parent.children {
+		if c == n {
+			return i
+		}
+	}
+	return -1
+}
+
+// clone returns a deep copy of n, without a parent.
+func (n *node) clone() *node {
+	c := &node{kind: n.kind, name: n.name, attrs: append([]xml.Attr(nil), n.attrs...), data: n.data}
+	for _, child := range n.children {
+		cc := child.clone()
+		cc.parent = c
+		c.children = append(c.children, cc)
+	}
+	return c
+}
+
+// text returns the concatenated text content of n's children.
+func (n *node) text() string {
+	var b strings.Builder
+	for _, c := range n.children {
+		if c.kind == textNode {
+			b.WriteString(c.data)
+		}
+	}
+	return b.String()
+}
+
+// namespace returns the URI bound to prefix in the scope of n.
+func (n *node) namespace(prefix string) (string, bool) {
+	if prefix == "xml" {
+		return "http://www.w3.org/XML/1998/namespace", true
+	}
+	for ; n != nil; n = n.parent {
+		for _, a := range n.attrs {
+			if (prefix == "" && a.Name.Space == "" && a.Name.Local == "xmlns") || (a.Name.Space == "xmlns" && a.Name.Local == prefix) {
+				return a.Value, true
+			}
+		}
+	}
+	return "", prefix == ""
+}
+
+// attr returns the index of the attribute of n whose namespace and local
+// name are those of name, or -1.
+func (n *node) attr(name xml.Name) int {
+	for i, a := range n.attrs {
+		if a.Name.Space == "xmlns" || (a.Name.Space == "" && a.Name.Local == "xmlns") || (a.Name.Local != name.Local && name.Local != "*") {
+			continue
+		}
+		space := ""
+		if a.Name.Space != "" {
+			space, _ = n.namespace(a.Name.Space)
+		}
+		if name.Space == "*" || space == name.Space {
+			return i
+		}
+	}
+	return -1
+}
+
+// is reports whether n is an element with the namespace and local name of
+// name, either of which may be "*".
+func (n *node) is(name xml.Name) bool {
+	if n.kind != elementNode || (name.Local != "*" && n.name.Local != name.Local) {
+		return false
+	}
+	space, _ := n.namespace(n.name.Space)
+	return name.Space == "*" || space == name.Space
+}
+
+func (n *node) write(b *bytes.Buffer) {
+	switch n.kind {
+	case elementNode:
+		b.WriteString("<" + qname(n.name))
+		for _, a := range n.attrs {
+			b.WriteString(" " + qname(a.Name) + `="` + escape(a.Value, true) + `"`)
+		}
+		if len(n.children) == 0 {
+			b.WriteString("/>")
+			return
+		}
+		b.WriteByte('>')
+		for _, c := range n.children {
+			c.write(b)
+		}
+		b.WriteString("</" + qname(n.name) + ">")
+	case textNode:
+		b.WriteString(escape(n.data, false))
+	case commentNode:
+		b.WriteString("<!--" + n.data + "-->")
+	case procInstNode:
+		b.WriteString("<?" + n.name.Local + " " + n.data + "?>")
+	case directiveNode:
+		b.WriteString("<!" + n.data + ">")
+	default:
+		for _, c := range n.children {
+			c.write(b)
+		}
+	}
+}
+
+func qname(name xml.Name) string {
+	if name.Space == "" {
+		return name.Local
+	}
+	return name.Space + ":" + name.Local
+}
+
+func escape(s string, attr bool) string {
+	var b strings.Builder
+	for _, r := range s {
+		switch {
+		case r == '&':
+			b.WriteString("&amp;")
+		case r == '<':
+			b.WriteString("&lt;")
+		case r == '>':
+			b.WriteString("&gt;")
+		case attr && r == '"':
+			b.WriteString("&quot;")
+		case attr && (r == '\t' || r == '\n' || r == '\r'):
+			b.WriteString("&#x" + strconv.FormatInt(int64(r), 16) + ";")
+		case r == '\r':
+			b.WriteString("&#xD;")
+		default:
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+// selection is a node selected by a selector, or one of its attributes if
+// attr is not negative.
+type selection struct {
+	node *node
+	attr int
+}
+
+type stepKind int
+
+const (
+	elementStep stepKind = iota
+	attributeStep
+	textStep
+)
+
+type predicate struct {
+	position int
+	attr     xml.Name
+	value    string
+}
+
+type step struct {
+	kind       stepKind
+	name       xml.Name
+	predicates []predicate
+}
+
+// selector is the subset of XPath 1.0 supported in the sel attribute of
+// patch operations: absolute location paths of child element steps, which
+// may be followed by an attribute step or a text() step. Element and text()
+// steps may have positional predicates, and element steps may have
+// predicates comparing an attribute to a string literal, as in
+// /doc/item[@id='a']/@price or /doc/item[2]/text().
+type selector struct {
+	expr  string
+	steps []step
+}
+
+// parseSelector parses expr, resolving prefixes in the scope of n.
+func parseSelector(expr string, n *node) (*selector, error) {
+	s := &selector{expr: expr}
+	rest := expr
+	for rest != "" {
+		if rest[0] != '/' || (len(s.steps) > 0 && s.steps[len(s.steps)-1].kind != elementStep) {
+			return nil, fmt.Errorf("unsupported selector '%s'", expr)
+		}
+		rest = rest[1:]
+		var st step
+		var err error
+		switch {
+		case strings.HasPrefix(rest, "text()"):
+			st.kind, rest = textStep, rest[len("text()"):]
+		case strings.HasPrefix(rest, "@"):
+			st.kind = attributeStep
+			if st.name, rest, err = parseName(rest[1:], n); err != nil {
+				return nil, fmt.Errorf("%s in selector '%s'", err, expr)
+			}
+		default:
+			if st.name, rest, err = parseName(rest, n); err != nil {
+				return nil, fmt.Errorf("%s in selector '%s'", err, expr)
+			}
+		}
+		for strings.HasPrefix(rest, "[") && st.kind != attributeStep {
+			end := strings.IndexByte(rest, ']')
+			if end < 0 {
+				return nil, fmt.Errorf("unsupported selector '%s'", expr)
+			}
+			p, err := parsePredicate(rest[1:end], n)
+			if err != nil || (st.kind == textStep && p.position == 0) {
+				return nil, fmt.Errorf("unsupported selector '%s'", expr)
+			}
+			st.predicates, rest = append(st.predicates, p), rest[end+1:]
+		}
+		s.steps = append(s.steps, st)
+	}
+	if len(s.steps) == 0 {
+		return nil, fmt.Errorf("unsupported selector '%s'", expr)
+	}
+	return s, nil
+}
+
+// parseName parses the qualified name at the start of s. Unprefixed names
+// are in no namespace, as in XPath 1.0.
+func parseName(s string, n *node) (xml.Name, string, error) {
+	end := strings.IndexAny(s, "/[]=")
+	if end < 0 {
+		end = len(s)
+	}
+	name, rest := s[:end], s[end:]
+	if name == "" {
+		return xml.Name{}, "", errors.New("missing name")
+	}
+	if name == "*" {
+		return xml.Name{Space: "*", Local: "*"}, rest, nil
+	}
+	prefix, local := "", name
+	if i := strings.IndexByte(name, ':'); i >= 0 {
+		prefix, local = name[:i], name[i+1:]
+	}
+	if strings.ContainsAny(local, ":() '\"") {
+		return xml.Name{}, "", fmt.Errorf("invalid name '%s'", name)
+	}
+	if prefix == "" {
+		return xml.Name{Local: local}, rest, nil
+	}
+	space, ok := n.namespace(prefix)
+	if !ok {
+		return xml.Name{}, "", fmt.Errorf("undeclared prefix '%s'", prefix)
+	}
+	return xml.Name{Space: space, Local: local}, rest, nil
+}
+
+func parsePredicate(s string, n *node) (predicate, error) {
+	if i, err := strconv.Atoi(s); err == nil {
+		if i < 1 {
+			return predicate{}, errors.New("invalid position")
+		}
+		return predicate{position: i}, nil
+	}
+	eq := strings.IndexByte(s, '=')
+	if !strings.HasPrefix(s, "@") || eq < 0 {
+		return predicate{}, errors.New("unsupported predicate")
+	}
+	name, rest, err := parseName(s[1:eq], n)
+	if err != nil || rest != "" {
+		return predicate{}, errors.New("unsupported predicate")
+	}
+	value := s[eq+1:]
+	if len(value) < 2 || (value[0] != '\'' && value[0] != '"') || value[len(value)-1] != value[0] {
+		return predicate{}, errors.New("unsupported predicate")
+	}
+	return predicate{attr: name, value: value[1 : len(value)-1]}, nil
+}
+
+// selectOne returns the single node selected in doc.
+func (s *selector) selectOne(doc *node) (selection, error) {
+	context := []*node{doc}
+	for i, st := range s.steps {
+		if st.kind == attributeStep {
+			var selected []selection
+			for _, n := range context {
+				if a := n.attr(st.name); a >= 0 {
+					selected = append(selected, selection{n, a})
+				}
+			}
+			return s.one(selected)
+		}
+		var next []*node
+		for _, n := range context {
+			var candidates []*node
+			for _, c := range n.children {
+				if (st.kind == textStep && c.kind == textNode) || (st.kind == elementStep && c.is(st.name)) {
+					candidates = append(candidates, c)
+				}
+			}
+			for _, p := range st.predicates {
+				candidates = p.filter(candidates)
+			}
+			next = append(next, candidates...)
+		}
+		context = next
+		if i == len(s.steps)-1 {
+			selected := make([]selection, len(context))
+			for j, n := range context {
+				selected[j] = selection{n, -1}
+			}
+			return s.one(selected)
+		}
+	}
+	return selection{}, nil
+}
+
+func (s *selector) one(selected []selection) (selection, error) {
+	switch len(selected) {
+	case 0:
+		return selection{}, fmt.Errorf("Selector '%s' matches no node", s.expr)
+	case 1:
+		return selected[0], nil
+	}
+	return selection{}, fmt.Errorf("Selector '%s' matches more than one node", s.expr)
+}
+
+func (p predicate) filter(nodes []*node) []*node {
+	if p.position > 0 {
+		if p.position > len(nodes) {
+			return nil
+		}
+		return nodes[p.position-1 : p.position]
+	}
+	var matched []*node
+	for _, n := range nodes {
+		if a := n.attr(p.attr); a >= 0 && n.attrs[a].Value == p.value {
+			matched = append(matched, n)
+		}
+	}
+	return matched
+}
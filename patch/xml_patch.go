@@ -0,0 +1,241 @@
+// Copyright © 2017 Walter Scheper <walter.scheper@gmail.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package patch
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"strings"
+)
+
+// XMLPatch is an RFC 5261 XML patch document, as carried by the RFC 7351
+// application/xml-patch+xml media type: a root element containing add,
+// replace and remove operations. Selectors are limited to the subset of
+// XPath described by selector.
+type XMLPatch struct {
+	ops []xmlOperation
+}
+
+type xmlOperation struct {
+	op  string
+	sel *selector
+
+	// pos is the position of added nodes: before, after, prepend, or empty
+	// to append.
+	pos string
+
+	// attr is the name of the attribute to add, for type="@name".
+	attr xml.Name
+
+	// ws is the whitespace to remove with a node: before, after or both.
+	ws string
+
+	content *node
+}
+
+// NewXMLPatch parses data as an XML patch.
+func NewXMLPatch(data []byte) (*XMLPatch, error) {
+	doc, err := parseXML(data)
+	if err != nil {
+		return nil, fmt.Errorf("Error parsing XML patch: %s", err)
+	}
+	p := &XMLPatch{}
+	for _, n := range doc.root().children {
+		if n.kind != elementNode {
+			if n.kind == textNode && strings.TrimSpace(n.data) != "" {
+				return nil, fmt.Errorf("Error parsing XML patch: unexpected text '%s'", strings.TrimSpace(n.data))
+			}
+			continue
+		}
+		op, err := newXMLOperation(n)
+		if err != nil {
+			return nil, fmt.Errorf("Error parsing XML patch: operation %d: %s", len(p.ops), err)
+		}
+		p.ops = append(p.ops, op)
+	}
+	return p, nil
+}
+
+func newXMLOperation(n *node) (xmlOperation, error) {
+	op := xmlOperation{op: n.name.Local, content: n}
+	switch op.op {
+	case "add", "replace", "remove":
+	default:
+		return op, fmt.Errorf("unknown operation '%s'", qname(n.name))
+	}
+	var sel, typ string
+	for _, a := range n.attrs {
+		switch a.Name {
+		case xml.Name{Local: "sel"}:
+			sel = a.Value
+		case xml.Name{Local: "pos"}:
+			op.pos = a.Value
+		case xml.Name{Local: "type"}:
+			typ = a.Value
+		case xml.Name{Local: "ws"}:
+			op.ws = a.Value
+		}
+	}
+	if sel == "" {
+		return op, fmt.Errorf("%s is missing 'sel'", op.op)
+	}
+	var err error
+	if op.sel, err = parseSelector(sel, n); err != nil {
+		return op, err
+	}
+	switch op.pos {
+	case "", "before", "after", "prepend":
+	default:
+		return op, fmt.Errorf("invalid pos '%s'", op.pos)
+	}
+	switch op.ws {
+	case "", "before", "after", "both":
+	default:
+		return op, fmt.Errorf("invalid ws '%s'", op.ws)
+	}
+	if typ != "" {
+		if op.op != "add" || !strings.HasPrefix(typ, "@") {
+			return op, fmt.Errorf("unsupported type '%s'", typ)
+		}
+		name, rest, err := parseName(typ[1:], n)
+		if err != nil || rest != "" || name.Local == "*" || name.Space != "" {
+			return op, fmt.Errorf("unsupported type '%s'", typ)
+		}
+		op.attr = name
+	}
+	return op, nil
+}
+
+// Apply applies the patch to doc, an XML document, and returns the patched
+// document.
+func (p *XMLPatch) Apply(doc []byte) ([]byte, error) {
+	d, err := parseXML(doc)
+	if err != nil {
+		return nil, err
+	}
+	for i, op := range p.ops {
+		if err := op.apply(d); err != nil {
+			return nil, fmt.Errorf("Error applying operation %d (%s): %s", i, op.op, err)
+		}
+	}
+	var b bytes.Buffer
+	d.write(&b)
+	return b.Bytes(), nil
+}
+
+func (op xmlOperation) apply(doc *node) error {
+	s, err := op.sel.selectOne(doc)
+	if err != nil {
+		return err
+	}
+	switch op.op {
+	case "add":
+		return op.add(s)
+	case "replace":
+		return op.replace(s)
+	}
+	return op.remove(s)
+}
+
+func (op xmlOperation) add(s selection) error {
+	n := s.node
+	if s.attr >= 0 || n.kind != elementNode {
+		return fmt.Errorf("Selector '%s' does not select an element", op.sel.expr)
+	}
+	if op.attr.Local != "" {
+		if n.attr(op.attr) >= 0 {
+			return fmt.Errorf("Attribute '%s' already exists", op.attr.Local)
+		}
+		n.attrs = append(n.attrs, xml.Attr{Name: op.attr, Value: op.content.text()})
+		return nil
+	}
+	parent, i := n, len(n.children)
+	switch op.pos {
+	case "prepend":
+		i = 0
+	case "before", "after":
+		parent, i = n.parent, n.index()
+		if op.pos == "after" {
+			i++
+		}
+		if parent.kind == documentNode {
+			return fmt.Errorf("Cannot add nodes beside the root element")
+		}
+	}
+	for _, c := range op.content.children {
+		// text merged into a neighbouring text node takes no position
+		n := len(parent.children)
+		parent.insert(i, c.clone())
+		if len(parent.children) > n {
+			i++
+		}
+	}
+	return nil
+}
+
+func (op xmlOperation) replace(s selection) error {
+	n := s.node
+	switch {
+	case s.attr >= 0:
+		n.attrs[s.attr].Value = op.content.text()
+	case n.kind == textNode:
+		n.data = op.content.text()
+		if n.data == "" {
+			n.remove()
+		}
+	default:
+		var replacement *node
+		for _, c := range op.content.children {
+			if c.kind == elementNode {
+				if replacement != nil {
+					return fmt.Errorf("Replacement for '%s' must be a single element", op.sel.expr)
+				}
+				replacement = c
+			}
+		}
+		if replacement == nil {
+			return fmt.Errorf("Replacement for '%s' must be a single element", op.sel.expr)
+		}
+		parent, i := n.parent, n.index()
+		parent.children[i] = replacement.clone()
+		parent.children[i].parent = parent
+	}
+	return nil
+}
+
+func (op xmlOperation) remove(s selection) error {
+	n := s.node
+	if s.attr >= 0 {
+		n.attrs = append(n.attrs[:s.attr], n.attrs[s.attr+1:]...)
+		return nil
+	}
+	if n.kind == elementNode && n.parent.kind == documentNode {
+		return fmt.Errorf("Cannot remove the root element")
+	}
+	parent, i := n.parent, n.index()
+	if (op.ws == "after" || op.ws == "both") && i+1 < len(parent.children) && isSpace(parent.children[i+1]) {
+		parent.children[i+1].remove()
+	}
+	if (op.ws == "before" || op.ws == "both") && i > 0 && isSpace(parent.children[i-1]) {
+		parent.children[i-1].remove()
+	}
+	n.remove()
+	return nil
+}
+
+func isSpace(n *node) bool {
+	return n.kind == textNode && strings.TrimSpace(n.data) == ""
+}
@@ -0,0 +1,124 @@
+// Copyright © 2017 Walter Scheper <walter.scheper@gmail.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package patch
+
+import (
+	"testing"
+)
+
+func TestXMLPatch(t *testing.T) {
+	doc := `<?xml version="1.0"?>
+<doc xmlns:p="urn:p">
+  <note id="a">This is a sample document</note>
+  <note id="b" lang="en">Second</note>
+  <p:item>1</p:item>
+</doc>`
+	tests := []struct {
+		patch    string
+		expected string
+	}{
+		{`<diff><add sel="/doc"><foo id="ert4773">This is a new child</foo></add></diff>`, `<?xml version="1.0"?>
+<doc xmlns:p="urn:p">
+  <note id="a">This is a sample document</note>
+  <note id="b" lang="en">Second</note>
+  <p:item>1</p:item>
+<foo id="ert4773">This is a new child</foo></doc>`},
+		{`<diff><add sel="/doc" pos="prepend"><first/></add></diff>`, `<?xml version="1.0"?>
+<doc xmlns:p="urn:p"><first/>
+  <note id="a">This is a sample document</note>
+  <note id="b" lang="en">Second</note>
+  <p:item>1</p:item>
+</doc>`},
+		{`<diff><add sel="/doc/note[2]" pos="before"><new/>
+  </add></diff>`, `<?xml version="1.0"?>
+<doc xmlns:p="urn:p">
+  <note id="a">This is a sample document</note>
+  <new/>
+  <note id="b" lang="en">Second</note>
+  <p:item>1</p:item>
+</doc>`},
+		{`<diff><add sel="/doc/note[@id='a']" type="@lang">fr</add><replace sel="/doc/note[@id='b']/@lang">de</replace></diff>`, `<?xml version="1.0"?>
+<doc xmlns:p="urn:p">
+  <note id="a" lang="fr">This is a sample document</note>
+  <note id="b" lang="de">Second</note>
+  <p:item>1</p:item>
+</doc>`},
+		{`<diff><replace sel="/doc/note[1]"><note id="c">Replaced &amp; "quoted"</note></replace><replace sel="/doc/note[2]/text()">2nd</replace></diff>`, `<?xml version="1.0"?>
+<doc xmlns:p="urn:p">
+  <note id="c">Replaced &amp; "quoted"</note>
+  <note id="b" lang="en">2nd</note>
+  <p:item>1</p:item>
+</doc>`},
+		{`<diff xmlns:x="urn:p"><remove sel="/doc/note[@id='a']" ws="after"/><remove sel="/doc/note/@lang"/><replace sel="/doc/x:item/text()">2</replace></diff>`, `<?xml version="1.0"?>
+<doc xmlns:p="urn:p">
+  <note id="b">Second</note>
+  <p:item>2</p:item>
+</doc>`},
+		{`<diff><remove sel="/doc/*[3]" ws="before"/></diff>`, `<?xml version="1.0"?>
+<doc xmlns:p="urn:p">
+  <note id="a">This is a sample document</note>
+  <note id="b" lang="en">Second</note>
+</doc>`},
+	}
+	for idx, test := range tests {
+		p, err := NewXMLPatch([]byte(test.patch))
+		if err != nil {
+			t.Fatalf("%d: %q", idx, err)
+		}
+		actual, err := p.Apply([]byte(doc))
+		if err != nil {
+			t.Fatalf("%d: %q", idx, err)
+		}
+		if string(actual) != test.expected {
+			t.Errorf("%d: expected '%s', got '%s'", idx, test.expected, actual)
+		}
+	}
+}
+
+func TestXMLPatchErrors(t *testing.T) {
+	doc := `<doc><note id="a"/><note id="b"/></doc>`
+	tests := []struct {
+		patch    string
+		expected string
+	}{
+		{`<diff><add sel="/doc">`, "Error parsing XML patch: unexpected EOF"},
+		{`<diff>text</diff>`, "Error parsing XML patch: unexpected text 'text'"},
+		{`<diff><add/></diff>`, "Error parsing XML patch: operation 0: add is missing 'sel'"},
+		{`<diff><add sel="doc"/></diff>`, "Error parsing XML patch: operation 0: unsupported selector 'doc'"},
+		{`<diff><add sel="//note"/></diff>`, "Error parsing XML patch: operation 0: missing name in selector '//note'"},
+		{`<diff><add sel="/doc/@id/x"/></diff>`, "Error parsing XML patch: operation 0: unsupported selector '/doc/@id/x'"},
+		{`<diff><add sel="/doc/note[last()]"/></diff>`, "Error parsing XML patch: operation 0: unsupported selector '/doc/note[last()]'"},
+		{`<diff><add sel="/x:doc"/></diff>`, "Error parsing XML patch: operation 0: undeclared prefix 'x' in selector '/x:doc'"},
+		{`<diff><add sel="/doc" pos="inside"/></diff>`, "Error parsing XML patch: operation 0: invalid pos 'inside'"},
+		{`<diff><add sel="/doc" type="namespace::x"/></diff>`, "Error parsing XML patch: operation 0: unsupported type 'namespace::x'"},
+
+		{`<diff><add sel="/doc/note"/></diff>`, "Error applying operation 0 (add): Selector '/doc/note' matches more than one node"},
+		{`<diff><add sel="/doc/note[3]"/></diff>`, "Error applying operation 0 (add): Selector '/doc/note[3]' matches no node"},
+		{`<diff><add sel="/doc/note[1]" type="@id">c</add></diff>`, "Error applying operation 0 (add): Attribute 'id' already exists"},
+		{`<diff><add sel="/doc" pos="after"><x/></add></diff>`, "Error applying operation 0 (add): Cannot add nodes beside the root element"},
+		{`<diff><replace sel="/doc/note[1]"><a/><b/></replace></diff>`, "Error applying operation 0 (replace): Replacement for '/doc/note[1]' must be a single element"},
+		{`<diff><remove sel="/doc"/></diff>`, "Error applying operation 0 (remove): Cannot remove the root element"},
+		{`<diff><remove sel="/doc/note[1]"/><remove sel="/doc/note[@id='a']"/></diff>`, "Error applying operation 1 (remove): Selector '/doc/note[@id='a']' matches no node"},
+	}
+	for idx, test := range tests {
+		p, err := NewXMLPatch([]byte(test.patch))
+		if err == nil {
+			_, err = p.Apply([]byte(doc))
+		}
+		if err == nil || err.Error() != test.expected {
+			t.Errorf("%d: expected '%s', got '%v'", idx, test.expected, err)
+		}
+	}
+}
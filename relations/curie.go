@@ -0,0 +1,121 @@
+// Copyright © 2017 Walter Scheper <walter.scheper@gmail.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package relations
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/wfscheper/mtrest/uritemplate"
+)
+
+// Curie is a named prefix that abbreviates extension relation types. Href is
+// a URI template with a single variable, rel, such as
+// http://example.com/docs/rels/{rel}.
+type Curie struct {
+	Name string
+	Href string
+
+	uri *uritemplate.Template
+}
+
+var (
+	mu     sync.RWMutex
+	curies = map[string]Curie{}
+)
+
+// RegisterCurie makes the prefix name available to Expand and Compact.
+// Registering a name a second time replaces the previous Curie. Every
+// hypermedia format shares the registered CURIEs, so a relation type is
+// written the same way whichever format a response is rendered in.
+func RegisterCurie(name, href string) error {
+	if name == "" || strings.ContainsAny(name, ":/") || IsRegistered(name) {
+		return fmt.Errorf("Invalid CURIE name: '%s'", name)
+	}
+	uri, err := uritemplate.New(href)
+	if err != nil {
+		return err
+	}
+	if names := uri.Names(); len(names) != 1 || names[0] != "rel" {
+		return fmt.Errorf("Invalid CURIE href: '%s' must have a single variable named rel", href)
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	curies[name] = Curie{Name: name, Href: href, uri: uri}
+	return nil
+}
+
+// UnregisterCurie removes the prefix name.
+func UnregisterCurie(name string) {
+	mu.Lock()
+	defer mu.Unlock()
+	delete(curies, name)
+}
+
+// Curies returns the registered CURIEs sorted by name, for formats such as
+// HAL that list them in the response body.
+func Curies() []Curie {
+	mu.RLock()
+	defer mu.RUnlock()
+	list := make([]Curie, 0, len(curies))
+	for _, c := range curies {
+		list = append(list, c)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Name < list[j].Name })
+	return list
+}
+
+// Expand returns the full relation type for rel. A CURIE with a registered
+// prefix, such as acme:widgets, is expanded into an absolute URI, and a
+// registered relation type is returned in lower case. Anything else is
+// returned unchanged.
+func Expand(rel string) string {
+	if IsRegistered(rel) {
+		return strings.ToLower(rel)
+	}
+	i := strings.IndexByte(rel, ':')
+	if i < 0 {
+		return rel
+	}
+	mu.RLock()
+	c, ok := curies[rel[:i]]
+	mu.RUnlock()
+	if !ok {
+		return rel
+	}
+	href, err := c.uri.Expand(uritemplate.Values{"rel": rel[i+1:]})
+	if err != nil {
+		return rel
+	}
+	return href
+}
+
+// Compact is the inverse of Expand. It returns rel as a CURIE if it matches
+// the href of a registered prefix, and a registered relation type in lower
+// case. Anything else is returned unchanged. When several prefixes match,
+// the first by name is used.
+func Compact(rel string) string {
+	if IsRegistered(rel) {
+		return strings.ToLower(rel)
+	}
+	for _, c := range Curies() {
+		if vars, ok := c.uri.Match(rel); ok && vars["rel"] != "" {
+			return c.Name + ":" + vars["rel"]
+		}
+	}
+	return rel
+}
@@ -0,0 +1,88 @@
+// Copyright © 2017 Walter Scheper <walter.scheper@gmail.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package relations
+
+import (
+	"testing"
+)
+
+func TestExpandCompact(t *testing.T) {
+	if err := RegisterCurie("acme", "http://example.com/rels/{rel}"); err != nil {
+		t.Fatal(err)
+	}
+	defer UnregisterCurie("acme")
+	if err := RegisterCurie("docs", "http://example.com/docs{?rel}"); err != nil {
+		t.Fatal(err)
+	}
+	defer UnregisterCurie("docs")
+
+	tests := []struct {
+		compact, expanded string
+	}{
+		{"self", "self"},
+		{"acme:widgets", "http://example.com/rels/widgets"},
+		{"acme:big%20widgets", "http://example.com/rels/big%2520widgets"},
+		{"docs:widgets", "http://example.com/docs?rel=widgets"},
+		{"other:widgets", "other:widgets"},
+		{"http://example.net/rels/widgets", "http://example.net/rels/widgets"},
+	}
+	for idx, test := range tests {
+		if actual := Expand(test.compact); actual != test.expanded {
+			t.Errorf("%d: expected '%s', got '%s'", idx, test.expanded, actual)
+		}
+		if actual := Compact(test.expanded); actual != test.compact {
+			t.Errorf("%d: expected '%s', got '%s'", idx, test.compact, actual)
+		}
+	}
+
+	if actual := Expand("NEXT"); actual != "next" {
+		t.Errorf("expected 'next', got '%s'", actual)
+	}
+	if actual := Compact("http://example.com/rels/"); actual != "http://example.com/rels/" {
+		t.Errorf("expected 'http://example.com/rels/', got '%s'", actual)
+	}
+}
+
+func TestRegisterCurie(t *testing.T) {
+	tests := []struct {
+		name, href, expected string
+	}{
+		{"", "http://example.com/{rel}", "Invalid CURIE name: ''"},
+		{"a:b", "http://example.com/{rel}", "Invalid CURIE name: 'a:b'"},
+		{"self", "http://example.com/{rel}", "Invalid CURIE name: 'self'"},
+		{"acme", "http://example.com/", "Invalid CURIE href: 'http://example.com/' must have a single variable named rel"},
+		{"acme", "http://example.com/{name}", "Invalid CURIE href: 'http://example.com/{name}' must have a single variable named rel"},
+		{"acme", "http://example.com/{rel", "Error parsing URI template: unclosed expression at 19"},
+	}
+	for idx, test := range tests {
+		err := RegisterCurie(test.name, test.href)
+		if err == nil || err.Error() != test.expected {
+			t.Errorf("%d: expected '%s', got %v", idx, test.expected, err)
+		}
+	}
+
+	if err := RegisterCurie("b", "http://example.com/b/{rel}"); err != nil {
+		t.Fatal(err)
+	}
+	defer UnregisterCurie("b")
+	if err := RegisterCurie("a", "http://example.com/a/{rel}"); err != nil {
+		t.Fatal(err)
+	}
+	defer UnregisterCurie("a")
+	list := Curies()
+	if len(list) != 2 || list[0].Name != "a" || list[1].Href != "http://example.com/b/{rel}" {
+		t.Errorf("unexpected curies: %+v", list)
+	}
+}
@@ -0,0 +1,297 @@
+// Copyright © 2017 Walter Scheper <walter.scheper@gmail.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package relations defines the link relation types registered with IANA and
+// helps applications work with extension relation types, including the
+// compact URIs (CURIEs) used by HAL and other hypermedia formats.
+package relations
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// Relation types from the IANA Link Relations registry, as defined by
+// RFC 8288, section 6.2.2.
+const (
+	About                  = "about"
+	ACL                    = "acl"
+	Alternate              = "alternate"
+	AMPHTML                = "amphtml"
+	Appendix               = "appendix"
+	AppleTouchIcon         = "apple-touch-icon"
+	AppleTouchStartupImage = "apple-touch-startup-image"
+	Archives               = "archives"
+	Author                 = "author"
+	BlockedBy              = "blocked-by"
+	Bookmark               = "bookmark"
+	Canonical              = "canonical"
+	Chapter                = "chapter"
+	CiteAs                 = "cite-as"
+	Collection             = "collection"
+	Contents               = "contents"
+	ConvertedFrom          = "convertedfrom"
+	Copyright              = "copyright"
+	CreateForm             = "create-form"
+	Current                = "current"
+	DescribedBy            = "describedby"
+	Describes              = "describes"
+	Disclosure             = "disclosure"
+	DNSPrefetch            = "dns-prefetch"
+	Duplicate              = "duplicate"
+	Edit                   = "edit"
+	EditForm               = "edit-form"
+	EditMedia              = "edit-media"
+	Enclosure              = "enclosure"
+	External               = "external"
+	First                  = "first"
+	Glossary               = "glossary"
+	Help                   = "help"
+	Hosts                  = "hosts"
+	Hub                    = "hub"
+	Icon                   = "icon"
+	Index                  = "index"
+	IntervalAfter          = "intervalafter"
+	IntervalBefore         = "intervalbefore"
+	IntervalContains       = "intervalcontains"
+	IntervalDisjoint       = "intervaldisjoint"
+	IntervalDuring         = "intervalduring"
+	IntervalEquals         = "intervalequals"
+	IntervalFinishedBy     = "intervalfinishedby"
+	IntervalFinishes       = "intervalfinishes"
+	IntervalIn             = "intervalin"
+	IntervalMeets          = "intervalmeets"
+	IntervalMetBy          = "intervalmetby"
+	IntervalOverlappedBy   = "intervaloverlappedby"
+	IntervalOverlaps       = "intervaloverlaps"
+	IntervalStartedBy      = "intervalstartedby"
+	IntervalStarts         = "intervalstarts"
+	Item                   = "item"
+	Last                   = "last"
+	LatestVersion          = "latest-version"
+	License                = "license"
+	Linkset                = "linkset"
+	LRDD                   = "lrdd"
+	Manifest               = "manifest"
+	MaskIcon               = "mask-icon"
+	Me                     = "me"
+	MediaFeed              = "media-feed"
+	Memento                = "memento"
+	Micropub               = "micropub"
+	ModulePreload          = "modulepreload"
+	Monitor                = "monitor"
+	MonitorGroup           = "monitor-group"
+	Next                   = "next"
+	NextArchive            = "next-archive"
+	NoFollow               = "nofollow"
+	NoOpener               = "noopener"
+	NoReferrer             = "noreferrer"
+	Opener                 = "opener"
+	OpenID2LocalID         = "openid2.local_id"
+	OpenID2Provider        = "openid2.provider"
+	Original               = "original"
+	P3Pv1                  = "p3pv1"
+	Payment                = "payment"
+	Pingback               = "pingback"
+	Preconnect             = "preconnect"
+	PredecessorVersion     = "predecessor-version"
+	Prefetch               = "prefetch"
+	Preload                = "preload"
+	Prerender              = "prerender"
+	Prev                   = "prev"
+	PrevArchive            = "prev-archive"
+	Preview                = "preview"
+	Previous               = "previous"
+	PrivacyPolicy          = "privacy-policy"
+	Profile                = "profile"
+	Publication            = "publication"
+	Related                = "related"
+	Replies                = "replies"
+	Restconf               = "restconf"
+	RuleInput              = "ruleinput"
+	Search                 = "search"
+	Section                = "section"
+	Self                   = "self"
+	Service                = "service"
+	ServiceDesc            = "service-desc"
+	ServiceDoc             = "service-doc"
+	ServiceMeta            = "service-meta"
+	SIPTrunkingCapability  = "sip-trunking-capability"
+	Sponsored              = "sponsored"
+	Start                  = "start"
+	Status                 = "status"
+	Stylesheet             = "stylesheet"
+	Subsection             = "subsection"
+	SuccessorVersion       = "successor-version"
+	Sunset                 = "sunset"
+	Tag                    = "tag"
+	TermsOfService         = "terms-of-service"
+	TimeGate               = "timegate"
+	TimeMap                = "timemap"
+	Type                   = "type"
+	UGC                    = "ugc"
+	Up                     = "up"
+	VersionHistory         = "version-history"
+	Via                    = "via"
+	Webmention             = "webmention"
+	WorkingCopy            = "working-copy"
+	WorkingCopyOf          = "working-copy-of"
+)
+
+var registered = map[string]bool{
+	About:                  true,
+	ACL:                    true,
+	Alternate:              true,
+	AMPHTML:                true,
+	Appendix:               true,
+	AppleTouchIcon:         true,
+	AppleTouchStartupImage: true,
+	Archives:               true,
+	Author:                 true,
+	BlockedBy:              true,
+	Bookmark:               true,
+	Canonical:              true,
+	Chapter:                true,
+	CiteAs:                 true,
+	Collection:             true,
+	Contents:               true,
+	ConvertedFrom:          true,
+	Copyright:              true,
+	CreateForm:             true,
+	Current:                true,
+	DescribedBy:            true,
+	Describes:              true,
+	Disclosure:             true,
+	DNSPrefetch:            true,
+	Duplicate:              true,
+	Edit:                   true,
+	EditForm:               true,
+	EditMedia:              true,
+	Enclosure:              true,
+	External:               true,
+	First:                  true,
+	Glossary:               true,
+	Help:                   true,
+	Hosts:                  true,
+	Hub:                    true,
+	Icon:                   true,
+	Index:                  true,
+	IntervalAfter:          true,
+	IntervalBefore:         true,
+	IntervalContains:       true,
+	IntervalDisjoint:       true,
+	IntervalDuring:         true,
+	IntervalEquals:         true,
+	IntervalFinishedBy:     true,
+	IntervalFinishes:       true,
+	IntervalIn:             true,
+	IntervalMeets:          true,
+	IntervalMetBy:          true,
+	IntervalOverlappedBy:   true,
+	IntervalOverlaps:       true,
+	IntervalStartedBy:      true,
+	IntervalStarts:         true,
+	Item:                   true,
+	Last:                   true,
+	LatestVersion:          true,
+	License:                true,
+	Linkset:                true,
+	LRDD:                   true,
+	Manifest:               true,
+	MaskIcon:               true,
+	Me:                     true,
+	MediaFeed:              true,
+	Memento:                true,
+	Micropub:               true,
+	ModulePreload:          true,
+	Monitor:                true,
+	MonitorGroup:           true,
+	Next:                   true,
+	NextArchive:            true,
+	NoFollow:               true,
+	NoOpener:               true,
+	NoReferrer:             true,
+	Opener:                 true,
+	OpenID2LocalID:         true,
+	OpenID2Provider:        true,
+	Original:               true,
+	P3Pv1:                  true,
+	Payment:                true,
+	Pingback:               true,
+	Preconnect:             true,
+	PredecessorVersion:     true,
+	Prefetch:               true,
+	Preload:                true,
+	Prerender:              true,
+	Prev:                   true,
+	PrevArchive:            true,
+	Preview:                true,
+	Previous:               true,
+	PrivacyPolicy:          true,
+	Profile:                true,
+	Publication:            true,
+	Related:                true,
+	Replies:                true,
+	Restconf:               true,
+	RuleInput:              true,
+	Search:                 true,
+	Section:                true,
+	Self:                   true,
+	Service:                true,
+	ServiceDesc:            true,
+	ServiceDoc:             true,
+	ServiceMeta:            true,
+	SIPTrunkingCapability:  true,
+	Sponsored:              true,
+	Start:                  true,
+	Status:                 true,
+	Stylesheet:             true,
+	Subsection:             true,
+	SuccessorVersion:       true,
+	Sunset:                 true,
+	Tag:                    true,
+	TermsOfService:         true,
+	TimeGate:               true,
+	TimeMap:                true,
+	Type:                   true,
+	UGC:                    true,
+	Up:                     true,
+	VersionHistory:         true,
+	Via:                    true,
+	Webmention:             true,
+	WorkingCopy:            true,
+	WorkingCopyOf:          true,
+}
+
+// IsRegistered reports whether rel is a relation type from the IANA registry.
+// Registered relation types are compared case-insensitively.
+func IsRegistered(rel string) bool {
+	return registered[strings.ToLower(rel)]
+}
+
+// Validate returns an error if rel is neither a registered relation type nor
+// an extension relation type. Extension relation types must be absolute URIs;
+// a CURIE whose prefix has been registered with RegisterCurie is expanded
+// before it is checked.
+func Validate(rel string) error {
+	if IsRegistered(rel) {
+		return nil
+	}
+	u, err := url.Parse(Expand(rel))
+	if err != nil || !u.IsAbs() || strings.ContainsAny(rel, " \t") {
+		return fmt.Errorf("Invalid link relation: '%s'", rel)
+	}
+	return nil
+}
@@ -0,0 +1,66 @@
+// Copyright © 2017 Walter Scheper <walter.scheper@gmail.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package relations
+
+import (
+	"testing"
+)
+
+func TestIsRegistered(t *testing.T) {
+	tests := []struct {
+		rel      string
+		expected bool
+	}{
+		{Self, true},
+		{"Next", true},
+		{EditForm, true},
+		{OpenID2LocalID, true},
+		{"widgets", false},
+		{"http://example.com/rels/widgets", false},
+	}
+	for idx, test := range tests {
+		if actual := IsRegistered(test.rel); actual != test.expected {
+			t.Errorf("%d: expected %t for '%s', got %t", idx, test.expected, test.rel, actual)
+		}
+	}
+}
+
+func TestValidate(t *testing.T) {
+	if err := RegisterCurie("acme", "http://example.com/rels/{rel}"); err != nil {
+		t.Fatal(err)
+	}
+	defer UnregisterCurie("acme")
+
+	tests := []struct {
+		rel, expected string
+	}{
+		{"self", ""},
+		{"PREV", ""},
+		{"http://example.com/rels/widgets", ""},
+		{"urn:example:widgets", ""},
+		{"acme:widgets", ""},
+		{"widgets", "Invalid link relation: 'widgets'"},
+		{"/rels/widgets", "Invalid link relation: '/rels/widgets'"},
+		{"http://example.com/a b", "Invalid link relation: 'http://example.com/a b'"},
+	}
+	for idx, test := range tests {
+		err := Validate(test.rel)
+		if test.expected == "" && err != nil {
+			t.Errorf("%d: expected no error, got %q", idx, err)
+		} else if test.expected != "" && (err == nil || err.Error() != test.expected) {
+			t.Errorf("%d: expected '%s', got %v", idx, test.expected, err)
+		}
+	}
+}
@@ -0,0 +1,35 @@
+// Copyright © 2017 Walter Scheper <walter.scheper@gmail.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package render
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+
+	"github.com/wfscheper/mtrest"
+	"github.com/wfscheper/mtrest/headers"
+)
+
+// ETag returns a strong entity tag for data, the body of a representation
+// encoded as m. The media type is part of the tag, so the JSON and XML
+// variants of a resource never share a tag even if their bodies happen to
+// be identical.
+func ETag(m *mtrest.MediaType, data []byte) headers.ETag {
+	h := sha256.New()
+	h.Write([]byte(m.Canonical().String()))
+	h.Write([]byte{0})
+	h.Write(data)
+	return headers.ETag{Tag: base64.RawURLEncoding.EncodeToString(h.Sum(nil)[:18])}
+}
@@ -0,0 +1,81 @@
+// Copyright © 2017 Walter Scheper <walter.scheper@gmail.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package render
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"github.com/wfscheper/mtrest"
+)
+
+func TestETag(t *testing.T) {
+	data := []byte(`{"name":"a"}`)
+	a := ETag(&mtrest.ApplicationJSON, data)
+	if a.Weak || a.Tag == "" {
+		t.Errorf("expected a strong entity tag, got %s", a)
+	}
+	if b := ETag(&mtrest.ApplicationJSON, data); b != a {
+		t.Errorf("expected %s, got %s", a, b)
+	}
+	m, _ := mtrest.NewMediaType("Application/JSON")
+	if b := ETag(m, data); b != a {
+		t.Errorf("expected equivalent media types to share a tag, got %s and %s", a, b)
+	}
+	if b := ETag(&mtrest.ApplicationYAML, data); b == a {
+		t.Errorf("expected variants to get different tags, got %s", b)
+	}
+	if b := ETag(&mtrest.ApplicationJSON, []byte(`{"name":"b"}`)); b == a {
+		t.Errorf("expected different bodies to get different tags, got %s", b)
+	}
+}
+
+func TestRenderETag(t *testing.T) {
+	tests := []struct {
+		method, accept string
+		status         int
+		set            bool
+	}{
+		{"GET", "application/json", 200, true},
+		{"HEAD", "application/json", 200, true},
+		{"POST", "application/json", 200, false},
+		{"GET", "application/json", 201, false},
+	}
+	for idx, test := range tests {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(test.method, "/", nil)
+		r.Header.Set("Accept", test.accept)
+		Render(w, r, test.status, widget{"a"})
+		if actual := w.Header().Get("ETag") != ""; actual != test.set {
+			t.Errorf("%d: expected ETag set %t, got %t", idx, test.set, actual)
+		}
+	}
+
+	w := httptest.NewRecorder()
+	w.Header().Set("ETag", `"v1"`)
+	Render(w, httptest.NewRequest("GET", "/", nil), 200, widget{"a"})
+	if actual := w.Header().Get("ETag"); actual != `"v1"` {
+		t.Errorf(`expected '"v1"', got '%s'`, actual)
+	}
+
+	json, xml := httptest.NewRecorder(), httptest.NewRecorder()
+	r := httptest.NewRequest("GET", "/", nil)
+	Render(json, r, 200, widget{"a"})
+	r.Header.Set("Accept", "application/xml")
+	Render(xml, r, 200, widget{"a"})
+	if json.Header().Get("ETag") == xml.Header().Get("ETag") {
+		t.Error("expected JSON and XML variants to have different ETags")
+	}
+}
@@ -0,0 +1,145 @@
+// Copyright © 2017 Walter Scheper <walter.scheper@gmail.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package render
+
+import (
+	"encoding/xml"
+	"strings"
+
+	"github.com/wfscheper/mtrest/codec"
+	"github.com/wfscheper/mtrest/headers"
+	"github.com/wfscheper/mtrest/relations"
+)
+
+// Link is a hypermedia link in a response body.
+type Link struct {
+	Rel   string
+	Href  string
+	Title string
+}
+
+// Links is a list of hypermedia links. In every body format, relation types
+// are written in the compact form given by relations.Compact, along with the
+// CURIEs needed to expand them.
+type Links []Link
+
+type halLink struct {
+	Href  string `json:"href" yaml:"href"`
+	Title string `json:"title,omitempty" yaml:"title,omitempty"`
+}
+
+type halCurie struct {
+	Name      string `json:"name" yaml:"name"`
+	Href      string `json:"href" yaml:"href"`
+	Templated bool   `json:"templated" yaml:"templated"`
+}
+
+// MarshalJSON implements json.Marshaler, writing l as a HAL _links object.
+// Relation types with more than one link map to an array.
+func (l Links) MarshalJSON() ([]byte, error) {
+	return codec.MarshalJSON(l.hal())
+}
+
+// MarshalYAML implements yaml.Marshaler, using the same structure as
+// MarshalJSON.
+func (l Links) MarshalYAML() (interface{}, error) {
+	return l.hal(), nil
+}
+
+// MarshalXML implements xml.Marshaler, writing each link as an element with
+// rel, href and title attributes. CURIEs are written as links with the
+// relation type curies.
+func (l Links) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	rels, curies := l.compact()
+	for i, link := range l {
+		el := xml.StartElement{Name: start.Name, Attr: []xml.Attr{
+			{Name: xml.Name{Local: "rel"}, Value: rels[i]},
+			{Name: xml.Name{Local: "href"}, Value: link.Href},
+		}}
+		if link.Title != "" {
+			el.Attr = append(el.Attr, xml.Attr{Name: xml.Name{Local: "title"}, Value: link.Title})
+		}
+		if err := e.EncodeElement("", el); err != nil {
+			return err
+		}
+	}
+	for _, c := range curies {
+		el := xml.StartElement{Name: start.Name, Attr: []xml.Attr{
+			{Name: xml.Name{Local: "rel"}, Value: "curies"},
+			{Name: xml.Name{Local: "name"}, Value: c.Name},
+			{Name: xml.Name{Local: "href"}, Value: c.Href},
+			{Name: xml.Name{Local: "templated"}, Value: "true"},
+		}}
+		if err := e.EncodeElement("", el); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Header returns l as Link header values. CURIEs are not allowed in Link
+// headers, so relation types are written in full.
+func (l Links) Header() headers.Links {
+	links := make(headers.Links, len(l))
+	for i, link := range l {
+		links[i] = headers.Link{Target: link.Href, Rel: []string{relations.Expand(link.Rel)}, Title: link.Title}
+	}
+	return links
+}
+
+func (l Links) hal() map[string]interface{} {
+	rels, curies := l.compact()
+	m := make(map[string]interface{}, len(l))
+	for i, link := range l {
+		hl := halLink{Href: link.Href, Title: link.Title}
+		switch v := m[rels[i]].(type) {
+		case nil:
+			m[rels[i]] = hl
+		case halLink:
+			m[rels[i]] = []halLink{v, hl}
+		case []halLink:
+			m[rels[i]] = append(v, hl)
+		}
+	}
+	if len(curies) > 0 {
+		list := make([]halCurie, len(curies))
+		for i, c := range curies {
+			list[i] = halCurie{Name: c.Name, Href: c.Href, Templated: true}
+		}
+		m["curies"] = list
+	}
+	return m
+}
+
+// compact returns the compact relation type of each link, and the CURIEs
+// they use.
+func (l Links) compact() ([]string, []relations.Curie) {
+	rels := make([]string, len(l))
+	used := map[string]bool{}
+	for i, link := range l {
+		expanded := relations.Expand(link.Rel)
+		rels[i] = relations.Compact(expanded)
+		if rels[i] != expanded {
+			used[rels[i][:strings.IndexByte(rels[i], ':')]] = true
+		}
+	}
+	var curies []relations.Curie
+	for _, c := range relations.Curies() {
+		if used[c.Name] {
+			curies = append(curies, c)
+		}
+	}
+	return rels, curies
+}
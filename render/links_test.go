@@ -0,0 +1,93 @@
+// Copyright © 2017 Walter Scheper <walter.scheper@gmail.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package render
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"testing"
+
+	"github.com/wfscheper/mtrest/relations"
+	yaml "gopkg.in/yaml.v2"
+)
+
+func TestLinks(t *testing.T) {
+	if err := relations.RegisterCurie("acme", "http://example.com/rels/{rel}"); err != nil {
+		t.Fatal(err)
+	}
+	defer relations.UnregisterCurie("acme")
+
+	links := Links{
+		{Rel: "self", Href: "/widgets"},
+		{Rel: "Item", Href: "/widgets/1"},
+		{Rel: "item", Href: "/widgets/2", Title: "Two"},
+		{Rel: "http://example.com/rels/parts", Href: "/parts"},
+		{Rel: "acme:owner", Href: "/owner"},
+	}
+
+	data, err := json.Marshal(links)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := `{"acme:owner":{"href":"/owner"},"acme:parts":{"href":"/parts"},` +
+		`"curies":[{"name":"acme","href":"http://example.com/rels/{rel}","templated":true}],` +
+		`"item":[{"href":"/widgets/1"},{"href":"/widgets/2","title":"Two"}],"self":{"href":"/widgets"}}`
+	if string(data) != expected {
+		t.Errorf("expected '%s', got '%s'", expected, data)
+	}
+
+	data, err = yaml.Marshal(links)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var fromYAML map[string]interface{}
+	if err := yaml.Unmarshal(data, &fromYAML); err != nil {
+		t.Fatal(err)
+	}
+	if len(fromYAML) != 5 || fromYAML["curies"] == nil {
+		t.Errorf("unexpected YAML: %s", data)
+	}
+
+	doc := struct {
+		XMLName xml.Name `xml:"doc"`
+		Links   Links    `xml:"link"`
+	}{Links: links[3:]}
+	data, err = xml.Marshal(doc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected = `<doc><link rel="acme:parts" href="/parts"></link><link rel="acme:owner" href="/owner"></link>` +
+		`<link rel="curies" name="acme" href="http://example.com/rels/{rel}" templated="true"></link></doc>`
+	if string(data) != expected {
+		t.Errorf("expected '%s', got '%s'", expected, data)
+	}
+
+	header := links.Header().String()
+	expected = `</widgets>; rel="self", </widgets/1>; rel="item", </widgets/2>; rel="item"; title="Two", ` +
+		`</parts>; rel="http://example.com/rels/parts", </owner>; rel="http://example.com/rels/owner"`
+	if header != expected {
+		t.Errorf("expected '%s', got '%s'", expected, header)
+	}
+}
+
+func TestLinksWithoutCuries(t *testing.T) {
+	data, err := json.Marshal(Links{{Rel: "http://example.net/rels/other", Href: "/other"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := `{"http://example.net/rels/other":{"href":"/other"}}`; string(data) != expected {
+		t.Errorf("expected '%s', got '%s'", expected, data)
+	}
+}
@@ -0,0 +1,67 @@
+// Copyright © 2017 Walter Scheper <walter.scheper@gmail.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package render
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/wfscheper/mtrest/headers"
+)
+
+// Preferences returns the preferences of the Prefer headers of r. Malformed
+// headers are ignored.
+func Preferences(r *http.Request) headers.Prefer {
+	p, _ := headers.NewPrefer(strings.Join(r.Header["Prefer"], ","))
+	return p
+}
+
+// Return writes the result of a write request, such as a PUT or POST, as
+// the client prefers. With return=minimal, no body is written, and a 200 OK
+// status becomes 204 No Content. With return=representation, or no return
+// preference, v is rendered like Render. The preference honored is listed in
+// the Preference-Applied header.
+func Return(w http.ResponseWriter, r *http.Request, status int, v interface{}) error {
+	headers.AddVary(w.Header(), "Prefer")
+	switch Preferences(r).Return() {
+	case "minimal":
+		w.Header().Add("Preference-Applied", "return=minimal")
+		if status == http.StatusOK {
+			status = http.StatusNoContent
+		}
+		w.WriteHeader(status)
+		return nil
+	case "representation":
+		// The preference is only applied if the representation is
+		// written, and not a 406 Not Acceptable problem in its place.
+		w = &applyWriter{ResponseWriter: w, status: status, applied: "return=representation"}
+	}
+	return Render(w, r, status, v)
+}
+
+// applyWriter adds a Preference-Applied header to a response when it is
+// written with status.
+type applyWriter struct {
+	http.ResponseWriter
+	status  int
+	applied string
+}
+
+func (w *applyWriter) WriteHeader(status int) {
+	if status == w.status {
+		w.Header().Add("Preference-Applied", w.applied)
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
@@ -0,0 +1,67 @@
+// Copyright © 2017 Walter Scheper <walter.scheper@gmail.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package render
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"github.com/wfscheper/mtrest/headers"
+)
+
+func TestReturn(t *testing.T) {
+	tests := []struct {
+		prefer  string
+		accept  string
+		status  int
+		code    int
+		body    string
+		applied string
+	}{
+		{"", "", 200, 200, `{"name":"a"}`, ""},
+		{"return=minimal", "", 200, 204, "", "return=minimal"},
+		{"return=minimal", "", 201, 201, "", "return=minimal"},
+		{"return=representation", "", 201, 201, `{"name":"a"}`, "return=representation"},
+		{"respond-async, return=Representation", "", 200, 200, `{"name":"a"}`, "return=representation"},
+		{"return=representation", "text/csv", 201, 406, `{"status":406,"title":"Not Acceptable"}`, ""},
+		{"return=everything", "", 200, 200, `{"name":"a"}`, ""},
+		{`return="minimal`, "", 200, 200, `{"name":"a"}`, ""},
+	}
+	for idx, test := range tests {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest("PUT", "/widgets/1", nil)
+		if test.prefer != "" {
+			r.Header.Set("Prefer", test.prefer)
+		}
+		if test.accept != "" {
+			r.Header.Set("Accept", test.accept)
+		}
+		if err := Return(w, r, test.status, widget{"a"}); err != nil {
+			t.Fatalf("%d: %q", idx, err)
+		}
+		if w.Code != test.code {
+			t.Errorf("%d: expected status %d, got %d", idx, test.code, w.Code)
+		}
+		if actual := w.Body.String(); actual != test.body {
+			t.Errorf("%d: expected body '%s', got '%s'", idx, test.body, actual)
+		}
+		if actual := w.Header().Get("Preference-Applied"); actual != test.applied {
+			t.Errorf("%d: expected Preference-Applied '%s', got '%s'", idx, test.applied, actual)
+		}
+		if !headers.Varies(w.Header(), "Prefer") {
+			t.Errorf("%d: expected Vary to list Prefer, got %q", idx, w.Header()["Vary"])
+		}
+	}
+}
@@ -0,0 +1,201 @@
+// Copyright © 2017 Walter Scheper <walter.scheper@gmail.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package render
+
+import (
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+
+	"github.com/wfscheper/mtrest"
+	"github.com/wfscheper/mtrest/codec"
+)
+
+var (
+	// ApplicationProblemJSON is the RFC 7807 media type for problem details
+	// in JSON.
+	ApplicationProblemJSON = mtrest.MediaType{Type: "application", SubType: "problem+json", Params: map[string]string{}, Weight: 1.0}
+
+	// ApplicationProblemXML is the RFC 7807 media type for problem details
+	// in XML.
+	ApplicationProblemXML = mtrest.MediaType{Type: "application", SubType: "problem+xml", Params: map[string]string{}, Weight: 1.0}
+)
+
+// Problem is an RFC 7807 problem details object. It implements error, so
+// handlers can return problems from deep within their call stack.
+type Problem struct {
+	Type     string
+	Title    string
+	Status   int
+	Detail   string
+	Instance string
+
+	// InvalidParams lists the request parameters that failed validation.
+	InvalidParams []InvalidParam
+
+	// Extensions holds any other members of the problem. They may not
+	// replace the members above.
+	Extensions map[string]interface{}
+}
+
+// InvalidParam describes a request parameter that failed validation.
+type InvalidParam struct {
+	Name   string `json:"name" xml:"name" yaml:"name"`
+	Reason string `json:"reason" xml:"reason" yaml:"reason"`
+}
+
+// NewProblem returns a Problem for status, titled with its status text.
+func NewProblem(status int, detail string) *Problem {
+	return &Problem{
+		Title:  http.StatusText(status),
+		Status: status,
+		Detail: detail,
+	}
+}
+
+func (p *Problem) Error() string {
+	if p.Detail == "" {
+		return p.Title
+	}
+	return p.Title + ": " + p.Detail
+}
+
+// Offers implements Offerer.
+func (p *Problem) Offers() []*mtrest.MediaType {
+	return []*mtrest.MediaType{&ApplicationProblemJSON, &ApplicationProblemXML}
+}
+
+// MarshalJSON implements json.Marshaler.
+func (p *Problem) MarshalJSON() ([]byte, error) {
+	m := make(map[string]interface{}, len(p.Extensions)+6)
+	for k, v := range p.Extensions {
+		m[k] = v
+	}
+	for k, v := range p.members() {
+		m[k] = v
+	}
+	return codec.MarshalJSON(m)
+}
+
+// MarshalYAML implements yaml.Marshaler, using the same members as
+// MarshalJSON.
+func (p *Problem) MarshalYAML() (interface{}, error) {
+	m := make(map[string]interface{}, len(p.Extensions)+6)
+	for k, v := range p.Extensions {
+		m[k] = v
+	}
+	for k, v := range p.members() {
+		m[k] = v
+	}
+	return m, nil
+}
+
+// MarshalXML implements xml.Marshaler, using the format from RFC 7807,
+// appendix A. Extensions with scalar values are written as elements of the
+// same name; others are left out.
+func (p *Problem) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	start = xml.StartElement{Name: xml.Name{Space: "urn:ietf:rfc:7807", Local: "problem"}}
+	if err := e.EncodeToken(start); err != nil {
+		return err
+	}
+	members := p.members()
+	for _, k := range []string{"type", "title", "status", "detail", "instance"} {
+		if v, ok := members[k]; ok {
+			if err := e.EncodeElement(v, xml.StartElement{Name: xml.Name{Local: k}}); err != nil {
+				return err
+			}
+		}
+	}
+	if len(p.InvalidParams) > 0 {
+		params := struct {
+			Params []InvalidParam `xml:"i"`
+		}{p.InvalidParams}
+		if err := e.EncodeElement(params, xml.StartElement{Name: xml.Name{Local: "invalid-params"}}); err != nil {
+			return err
+		}
+	}
+	for _, k := range sortedKeys(p.Extensions) {
+		if _, ok := members[k]; ok {
+			continue
+		}
+		switch v := p.Extensions[k].(type) {
+		case string, bool, int, int64, float64, fmt.Stringer:
+			if err := e.EncodeElement(fmt.Sprint(v), xml.StartElement{Name: xml.Name{Local: k}}); err != nil {
+				return err
+			}
+		}
+	}
+	return e.EncodeToken(start.End())
+}
+
+// members returns the standard members of p that are set.
+func (p *Problem) members() map[string]interface{} {
+	m := map[string]interface{}{}
+	if p.Type != "" && p.Type != "about:blank" {
+		m["type"] = p.Type
+	}
+	if p.Title != "" {
+		m["title"] = p.Title
+	}
+	if p.Status != 0 {
+		m["status"] = p.Status
+	}
+	if p.Detail != "" {
+		m["detail"] = p.Detail
+	}
+	if p.Instance != "" {
+		m["instance"] = p.Instance
+	}
+	if len(p.InvalidParams) > 0 {
+		m["invalid-params"] = p.InvalidParams
+	}
+	return m
+}
+
+// Error writes err as a problem. A *Problem is written as is, a
+// codec.UnsupportedMediaTypeError becomes a 415 Unsupported Media Type
+// problem, and a codec.TooLargeError a 413 Payload Too Large problem. Any
+// other error becomes a 500 Internal Server Error problem that does not
+// reveal the error's message.
+func Error(w http.ResponseWriter, r *http.Request, err error) error {
+	var p *Problem
+	var unsupported *codec.UnsupportedMediaTypeError
+	var tooLarge *codec.TooLargeError
+	switch {
+	case errors.As(err, &p):
+	case errors.As(err, &unsupported):
+		p = NewProblem(http.StatusUnsupportedMediaType, unsupported.Error())
+	case errors.As(err, &tooLarge):
+		p = NewProblem(http.StatusRequestEntityTooLarge, tooLarge.Error())
+	default:
+		p = NewProblem(http.StatusInternalServerError, "")
+	}
+	status := p.Status
+	if status == 0 {
+		status = http.StatusInternalServerError
+	}
+	return Render(w, r, status, p)
+}
+
+func sortedKeys(m map[string]interface{}) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
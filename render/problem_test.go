@@ -0,0 +1,122 @@
+// Copyright © 2017 Walter Scheper <walter.scheper@gmail.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package render
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/wfscheper/mtrest"
+	"github.com/wfscheper/mtrest/codec"
+	yaml "gopkg.in/yaml.v2"
+)
+
+func TestProblemMarshalJSON(t *testing.T) {
+	p := &Problem{
+		Type:     "https://example.com/probs/out-of-credit",
+		Title:    "You do not have enough credit.",
+		Status:   403,
+		Detail:   "Your current balance is 30, but that costs 50.",
+		Instance: "/account/12345/msgs/abc",
+		Extensions: map[string]interface{}{
+			"balance": 30,
+			"status":  "ignored",
+		},
+	}
+	data, err := json.Marshal(p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := `{"balance":30,"detail":"Your current balance is 30, but that costs 50.","instance":"/account/12345/msgs/abc","status":403,"title":"You do not have enough credit.","type":"https://example.com/probs/out-of-credit"}`
+	if string(data) != expected {
+		t.Errorf("expected '%s', got '%s'", expected, data)
+	}
+
+	p = NewProblem(400, "")
+	p.Type = "about:blank"
+	p.InvalidParams = []InvalidParam{{"age", "must be a positive integer"}}
+	data, _ = json.Marshal(p)
+	expected = `{"invalid-params":[{"name":"age","reason":"must be a positive integer"}],"status":400,"title":"Bad Request"}`
+	if string(data) != expected {
+		t.Errorf("expected '%s', got '%s'", expected, data)
+	}
+}
+
+func TestProblemMarshalXML(t *testing.T) {
+	p := NewProblem(400, "Bad age")
+	p.InvalidParams = []InvalidParam{{"age", "must be a positive integer"}}
+	p.Extensions = map[string]interface{}{"balance": 30, "tags": []string{"a"}, "title": "ignored"}
+	data, err := xml.Marshal(p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := `<problem xmlns="urn:ietf:rfc:7807"><title>Bad Request</title><status>400</status><detail>Bad age</detail>` +
+		`<invalid-params><i><name>age</name><reason>must be a positive integer</reason></i></invalid-params><balance>30</balance></problem>`
+	if string(data) != expected {
+		t.Errorf("expected '%s', got '%s'", expected, data)
+	}
+}
+
+func TestProblemError(t *testing.T) {
+	if actual := NewProblem(404, "").Error(); actual != "Not Found" {
+		t.Errorf("expected 'Not Found', got '%s'", actual)
+	}
+	var err error = NewProblem(409, "Widget exists")
+	if actual := fmt.Sprint(err); actual != "Conflict: Widget exists" {
+		t.Errorf("expected 'Conflict: Widget exists', got '%s'", actual)
+	}
+}
+
+func TestErrorUnsupportedMediaType(t *testing.T) {
+	m, _ := mtrest.NewMediaType("text/csv")
+	w := httptest.NewRecorder()
+	Error(w, httptest.NewRequest("GET", "/", nil), fmt.Errorf("binding: %w", &codec.UnsupportedMediaTypeError{MediaType: m}))
+	if w.Code != 415 {
+		t.Errorf("expected status 415, got %d", w.Code)
+	}
+	expected := `{"detail":"Unsupported media type: 'text/csv'","status":415,"title":"Unsupported Media Type"}`
+	if actual := w.Body.String(); actual != expected {
+		t.Errorf("expected '%s', got '%s'", expected, actual)
+	}
+}
+
+func TestErrorTooLarge(t *testing.T) {
+	w := httptest.NewRecorder()
+	Error(w, httptest.NewRequest("GET", "/", nil), fmt.Errorf("binding: %w", &codec.TooLargeError{Limit: 8}))
+	if w.Code != 413 {
+		t.Errorf("expected status 413, got %d", w.Code)
+	}
+	expected := `{"detail":"A request body may be at most 8 bytes","status":413,"title":"Request Entity Too Large"}`
+	if actual := w.Body.String(); actual != expected {
+		t.Errorf("expected '%s', got '%s'", expected, actual)
+	}
+}
+
+func TestProblemMarshalYAML(t *testing.T) {
+	p := NewProblem(400, "")
+	p.InvalidParams = []InvalidParam{{"age", "must be a positive integer"}}
+	p.Extensions = map[string]interface{}{"balance": 30}
+	data, err := yaml.Marshal(p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := "balance: 30\ninvalid-params:\n- name: age\n  reason: must be a positive integer\nstatus: 400\ntitle: Bad Request\n"
+	if string(data) != expected {
+		t.Errorf("expected '%s', got '%s'", expected, data)
+	}
+}
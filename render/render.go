@@ -0,0 +1,97 @@
+// Copyright © 2017 Walter Scheper <walter.scheper@gmail.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package render writes response bodies in the media type negotiated with
+// the client, and reports errors as RFC 7807 problem details.
+package render
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/wfscheper/mtrest"
+	"github.com/wfscheper/mtrest/codec"
+	"github.com/wfscheper/mtrest/headers"
+)
+
+// Offers lists the media types Render offers for values that do not
+// implement Offerer, in order of preference.
+var Offers = []*mtrest.MediaType{
+	&mtrest.ApplicationJSON,
+	&mtrest.ApplicationXML,
+	&mtrest.ApplicationYAML,
+}
+
+// Offerer is implemented by values that can only be rendered in particular
+// media types.
+type Offerer interface {
+	Offers() []*mtrest.MediaType
+}
+
+// Negotiate returns the offer that best matches the Accept header of r, or
+// nil if none is acceptable. A request without a valid Accept header accepts
+// the first offer.
+func Negotiate(r *http.Request, offers []*mtrest.MediaType) *mtrest.MediaType {
+	if len(offers) == 0 {
+		return nil
+	}
+	accept := strings.Join(r.Header["Accept"], ",")
+	if strings.TrimSpace(accept) == "" {
+		return offers[0]
+	}
+	accepts, err := headers.NewAccepts(accept)
+	if err != nil {
+		return offers[0]
+	}
+	return accepts.BestMatch(offers)
+}
+
+// Render writes v with status, encoded in the media type negotiated from
+// the Accept header of r. If no media type is acceptable, Render writes a
+// 406 Not Acceptable problem instead. Problems themselves are always
+// rendered, falling back to their first offer. Successful responses to GET
+// and HEAD requests are given an ETag computed by ETag, unless the handler
+// has already set one.
+func Render(w http.ResponseWriter, r *http.Request, status int, v interface{}) error {
+	offers := Offers
+	if o, ok := v.(Offerer); ok {
+		offers = o.Offers()
+	}
+	m := Negotiate(r, offers)
+	if m == nil {
+		if _, ok := v.(*Problem); !ok {
+			headers.AddVary(w.Header(), "Accept")
+			return Render(w, r, http.StatusNotAcceptable, NewProblem(http.StatusNotAcceptable, ""))
+		}
+		m = offers[0]
+	}
+	c, ok := codec.For(m)
+	if !ok {
+		return &codec.UnsupportedMediaTypeError{MediaType: m}
+	}
+	data, err := c.Marshal(v)
+	if err != nil {
+		return err
+	}
+	if len(offers) > 1 {
+		headers.AddVary(w.Header(), "Accept")
+	}
+	w.Header().Set("Content-Type", m.String())
+	if status == http.StatusOK && (r.Method == http.MethodGet || r.Method == http.MethodHead) && w.Header().Get("ETag") == "" {
+		w.Header().Set("ETag", ETag(m, data).String())
+	}
+	w.WriteHeader(status)
+	_, err = w.Write(data)
+	return err
+}
@@ -0,0 +1,122 @@
+// Copyright © 2017 Walter Scheper <walter.scheper@gmail.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package render
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/wfscheper/mtrest"
+)
+
+type widget struct {
+	Name string `json:"name" xml:"name" yaml:"name"`
+}
+
+type jsonOnly struct {
+	Name string `json:"name"`
+}
+
+func (jsonOnly) Offers() []*mtrest.MediaType {
+	return []*mtrest.MediaType{&mtrest.ApplicationJSON}
+}
+
+func TestRender(t *testing.T) {
+	tests := []struct {
+		title, accept string
+		v             interface{}
+		status        int
+		contentType   string
+		body          string
+		vary          string
+	}{
+		{"No Accept header", "", widget{"a"}, 200, "application/json", `{"name":"a"}`, "Accept"},
+		{"Exact match", "application/xml", widget{"a"}, 200, "application/xml", `<widget><name>a</name></widget>`, "Accept"},
+		{"Weighted", "application/json; q=0.5, application/yaml", widget{"a"}, 200, "application/yaml", "name: a\n", "Accept"},
+		{"Invalid Accept header", "application/", widget{"a"}, 200, "application/json", `{"name":"a"}`, "Accept"},
+		{"Not acceptable", "text/html", widget{"a"}, 406, "application/problem+json", `{"status":406,"title":"Not Acceptable"}`, "Accept"},
+		{"Single offer", "", jsonOnly{"a"}, 200, "application/json", `{"name":"a"}`, ""},
+		{"Problem", "application/problem+xml", NewProblem(404, ""), 404, "application/problem+xml",
+			`<problem xmlns="urn:ietf:rfc:7807"><title>Not Found</title><status>404</status></problem>`, "Accept"},
+		{"Problem falls back to JSON", "text/html", NewProblem(404, ""), 404, "application/problem+json", `{"status":404,"title":"Not Found"}`, "Accept"},
+	}
+	for idx, test := range tests {
+		r := httptest.NewRequest("GET", "/", nil)
+		if test.accept != "" {
+			r.Header.Set("Accept", test.accept)
+		}
+		w := httptest.NewRecorder()
+		if err := Render(w, r, test.status, test.v); err != nil {
+			t.Fatalf("%d: (%s) %q", idx, test.title, err)
+		}
+		if w.Code != test.status {
+			t.Errorf("%d: (%s) expected status %d, got %d", idx, test.title, test.status, w.Code)
+		}
+		if actual := w.Header().Get("Content-Type"); actual != test.contentType {
+			t.Errorf("%d: (%s) expected Content-Type '%s', got '%s'", idx, test.title, test.contentType, actual)
+		}
+		if actual := w.Body.String(); actual != test.body {
+			t.Errorf("%d: (%s) expected body '%s', got '%s'", idx, test.title, test.body, actual)
+		}
+		if actual := w.Header().Get("Vary"); actual != test.vary {
+			t.Errorf("%d: (%s) expected Vary '%s', got '%s'", idx, test.title, test.vary, actual)
+		}
+	}
+}
+
+func TestRenderVary(t *testing.T) {
+	w := httptest.NewRecorder()
+	w.Header().Set("Vary", "Accept-Encoding, accept")
+	if err := Render(w, httptest.NewRequest("GET", "/", nil), 200, widget{"a"}); err != nil {
+		t.Fatal(err)
+	}
+	if actual := w.Header()["Vary"]; len(actual) != 1 {
+		t.Errorf("expected a single Vary header, got %q", actual)
+	}
+}
+
+func TestRenderMarshalError(t *testing.T) {
+	w := httptest.NewRecorder()
+	err := Render(w, httptest.NewRequest("GET", "/", nil), 200, func() {})
+	if err == nil {
+		t.Error("expected error, got nil")
+	}
+}
+
+func TestError(t *testing.T) {
+	tests := []struct {
+		err    error
+		status int
+		body   string
+	}{
+		{NewProblem(http.StatusConflict, "Widget exists"), 409, `{"detail":"Widget exists","status":409,"title":"Conflict"}`},
+		{errors.New("database is on fire"), 500, `{"status":500,"title":"Internal Server Error"}`},
+		{&Problem{Title: "Odd"}, 500, `{"title":"Odd"}`},
+	}
+	for idx, test := range tests {
+		w := httptest.NewRecorder()
+		if err := Error(w, httptest.NewRequest("GET", "/", nil), test.err); err != nil {
+			t.Fatalf("%d: %q", idx, err)
+		}
+		if w.Code != test.status {
+			t.Errorf("%d: expected status %d, got %d", idx, test.status, w.Code)
+		}
+		if actual := w.Body.String(); actual != test.body {
+			t.Errorf("%d: expected body '%s', got '%s'", idx, test.body, actual)
+		}
+	}
+}
@@ -0,0 +1,236 @@
+// Copyright © 2017 Walter Scheper <walter.scheper@gmail.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package render
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/wfscheper/mtrest"
+	"github.com/wfscheper/mtrest/codec"
+	"github.com/wfscheper/mtrest/headers"
+)
+
+var (
+	// ApplicationNDJSON is the media type for newline-delimited JSON.
+	ApplicationNDJSON = mtrest.MediaType{Type: "application", SubType: "x-ndjson", Params: map[string]string{}, Weight: 1.0}
+
+	// ApplicationJSONSeq is the RFC 7464 media type for JSON text sequences.
+	ApplicationJSONSeq = mtrest.MediaType{Type: "application", SubType: "json-seq", Params: map[string]string{}, Weight: 1.0}
+
+	// TextEventStream is the media type for Server-Sent Events.
+	TextEventStream = mtrest.MediaType{Type: "text", SubType: "event-stream", Params: map[string]string{}, Weight: 1.0}
+)
+
+// StreamOffers lists the media types Stream offers, in order of preference.
+// application/json is answered with a JSON array, which Stream has to
+// buffer.
+var StreamOffers = []*mtrest.MediaType{
+	&ApplicationNDJSON,
+	&ApplicationJSONSeq,
+	&TextEventStream,
+	&mtrest.ApplicationJSON,
+}
+
+// FlushInterval is how often Stream flushes items that have been written to
+// the response. Server-Sent Events are flushed as soon as they are written.
+var FlushInterval = 200 * time.Millisecond
+
+// Event is an item of a stream with the id and event fields of a
+// Server-Sent Event. Formats other than text/event-stream only write Data.
+// ID and Name must not contain line breaks.
+type Event struct {
+	ID   string
+	Name string
+	Data interface{}
+}
+
+// StreamFunc produces the items of a stream, passing each one to emit. emit
+// fails once ctx is done, which it is when the client goes away, and fn
+// should then stop and return the error.
+type StreamFunc func(ctx context.Context, emit func(item interface{}) error) error
+
+// Stream writes the items produced by fn as they are produced, in the media
+// type negotiated from StreamOffers: one JSON text per line for
+// application/x-ndjson, RFC 7464 records for application/json-seq, or one
+// event per item for text/event-stream. Clients that only accept
+// application/json get a JSON array, once fn has finished.
+//
+// If fn fails before emitting anything, the error is rendered as a problem.
+// Once the response has started, the status can no longer change: the
+// stream ends early, and text/event-stream clients are sent the problem in
+// an event called error.
+func Stream(w http.ResponseWriter, r *http.Request, fn StreamFunc) error {
+	m := Negotiate(r, StreamOffers)
+	if m == nil {
+		headers.AddVary(w.Header(), "Accept")
+		return Render(w, r, http.StatusNotAcceptable, NewProblem(http.StatusNotAcceptable, ""))
+	}
+	ctx := r.Context()
+	if strings.EqualFold(m.Type+"/"+m.SubType, "application/json") {
+		items := []interface{}{}
+		err := fn(ctx, func(item interface{}) error {
+			if e, ok := item.(Event); ok {
+				item = e.Data
+			}
+			items = append(items, item)
+			return ctx.Err()
+		})
+		if err != nil {
+			return Error(w, r, err)
+		}
+		headers.AddVary(w.Header(), "Accept")
+		return Render(w, r, http.StatusOK, jsonArray(items))
+	}
+
+	s := &stream{w: w, mediaType: m, sse: TextEventStream.Includes(m)}
+	s.flusher, _ = w.(http.Flusher)
+	done := make(chan struct{})
+	var wg sync.WaitGroup
+	if s.flusher != nil && !s.sse {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			s.flushEvery(FlushInterval, done)
+		}()
+	}
+	err := fn(ctx, func(item interface{}) error {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		return s.emit(item)
+	})
+	close(done)
+	wg.Wait()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err != nil && !s.started {
+		return Error(w, r, err)
+	}
+	if !s.started {
+		s.start()
+	}
+	if err != nil && s.sse && !errors.Is(err, ctx.Err()) {
+		var p *Problem
+		if !errors.As(err, &p) {
+			p = NewProblem(http.StatusInternalServerError, "")
+		}
+		if data, merr := codec.MarshalJSON(p); merr == nil {
+			s.w.Write([]byte("event: error\ndata: " + string(data) + "\n\n"))
+		}
+	}
+	s.flush()
+	return err
+}
+
+// jsonArray is the buffered fallback of Stream, which is only offered as
+// application/json.
+type jsonArray []interface{}
+
+// Offers implements Offerer.
+func (jsonArray) Offers() []*mtrest.MediaType {
+	return []*mtrest.MediaType{&mtrest.ApplicationJSON}
+}
+
+// stream writes the items of a streamed response.
+type stream struct {
+	mu        sync.Mutex
+	w         http.ResponseWriter
+	flusher   http.Flusher
+	mediaType *mtrest.MediaType
+	sse       bool
+	started   bool
+	dirty     bool
+}
+
+// start writes the response headers. The caller holds s.mu.
+func (s *stream) start() {
+	headers.AddVary(s.w.Header(), "Accept")
+	s.w.Header().Set("Content-Type", s.mediaType.String())
+	if s.sse {
+		s.w.Header().Set("Cache-Control", "no-cache")
+	}
+	s.w.WriteHeader(http.StatusOK)
+	s.started = true
+}
+
+func (s *stream) emit(item interface{}) error {
+	var b strings.Builder
+	event, isEvent := item.(Event)
+	if isEvent {
+		item = event.Data
+	}
+	data, err := codec.MarshalJSON(item)
+	if err != nil {
+		return err
+	}
+	switch {
+	case s.sse:
+		if isEvent && event.ID != "" {
+			b.WriteString("id: " + event.ID + "\n")
+		}
+		if isEvent && event.Name != "" {
+			b.WriteString("event: " + event.Name + "\n")
+		}
+		b.WriteString("data: " + string(data) + "\n\n")
+	case ApplicationJSONSeq.Includes(s.mediaType):
+		b.WriteString("\x1e" + string(data) + "\n")
+	default:
+		b.WriteString(string(data) + "\n")
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.started {
+		s.start()
+	}
+	if _, err := s.w.Write([]byte(b.String())); err != nil {
+		return err
+	}
+	s.dirty = true
+	if s.sse {
+		s.flush()
+	}
+	return nil
+}
+
+// flush flushes anything written since the last flush. The caller holds
+// s.mu.
+func (s *stream) flush() {
+	if s.dirty && s.flusher != nil {
+		s.flusher.Flush()
+	}
+	s.dirty = false
+}
+
+func (s *stream) flushEvery(interval time.Duration, done <-chan struct{}) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			s.mu.Lock()
+			s.flush()
+			s.mu.Unlock()
+		case <-done:
+			return
+		}
+	}
+}
@@ -0,0 +1,139 @@
+// Copyright © 2017 Walter Scheper <walter.scheper@gmail.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package render
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func items(n int) StreamFunc {
+	return func(ctx context.Context, emit func(interface{}) error) error {
+		for i := 1; i <= n; i++ {
+			var item interface{} = map[string]int{"id": i}
+			if i == 2 {
+				item = Event{ID: "2", Name: "update", Data: item}
+			}
+			if err := emit(item); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+}
+
+func TestStream(t *testing.T) {
+	tests := []struct {
+		accept      string
+		fn          StreamFunc
+		status      int
+		contentType string
+		expected    string
+	}{
+		{"", items(2), 200, "application/x-ndjson", "{\"id\":1}\n{\"id\":2}\n"},
+		{"application/json-seq", items(2), 200, "application/json-seq", "\x1e{\"id\":1}\n\x1e{\"id\":2}\n"},
+		{"text/event-stream", items(2), 200, "text/event-stream", "data: {\"id\":1}\n\nid: 2\nevent: update\ndata: {\"id\":2}\n\n"},
+		{"application/json", items(2), 200, "application/json", `[{"id":1},{"id":2}]`},
+		{"application/json", items(0), 200, "application/json", `[]`},
+		{"application/x-ndjson", items(0), 200, "application/x-ndjson", ""},
+		{"text/csv", items(2), 406, "application/problem+json", `{"status":406,"title":"Not Acceptable"}`},
+		{"application/x-ndjson", func(ctx context.Context, emit func(interface{}) error) error {
+			return NewProblem(http.StatusServiceUnavailable, "Try again later")
+		}, 503, "application/problem+json", `{"detail":"Try again later","status":503,"title":"Service Unavailable"}`},
+		{"text/event-stream", func(ctx context.Context, emit func(interface{}) error) error {
+			emit(1)
+			return errors.New("lost connection to database")
+		}, 200, "text/event-stream", "data: 1\n\nevent: error\ndata: {\"status\":500,\"title\":\"Internal Server Error\"}\n\n"},
+		{"application/x-ndjson", func(ctx context.Context, emit func(interface{}) error) error {
+			emit(1)
+			return errors.New("lost connection to database")
+		}, 200, "application/x-ndjson", "1\n"},
+	}
+	for idx, test := range tests {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest("GET", "/widgets", nil)
+		if test.accept != "" {
+			r.Header.Set("Accept", test.accept)
+		}
+		Stream(w, r, test.fn)
+		if w.Code != test.status {
+			t.Errorf("%d: expected %d, got %d", idx, test.status, w.Code)
+		}
+		if actual := w.Header().Get("Content-Type"); actual != test.contentType {
+			t.Errorf("%d: expected Content-Type '%s', got '%s'", idx, test.contentType, actual)
+		}
+		if w.Body.String() != test.expected {
+			t.Errorf("%d: expected %q, got %q", idx, test.expected, w.Body)
+		}
+		if w.Header().Get("Vary") != "Accept" {
+			t.Errorf("%d: expected Vary: Accept, got %q", idx, w.Header())
+		}
+	}
+}
+
+// flushRecorder signals each flush, which happens on another goroutine.
+type flushRecorder struct {
+	*httptest.ResponseRecorder
+	flushed chan struct{}
+}
+
+func (w flushRecorder) Flush() {
+	select {
+	case w.flushed <- struct{}{}:
+	default:
+	}
+}
+
+func TestStreamFlushes(t *testing.T) {
+	defer func(interval time.Duration) { FlushInterval = interval }(FlushInterval)
+	FlushInterval = time.Millisecond
+
+	w := flushRecorder{httptest.NewRecorder(), make(chan struct{}, 1)}
+	r := httptest.NewRequest("GET", "/widgets", nil)
+	Stream(w, r, func(ctx context.Context, emit func(interface{}) error) error {
+		emit(1)
+		select {
+		case <-w.flushed:
+		case <-time.After(time.Second):
+			t.Error("expected the stream to be flushed")
+		}
+		return nil
+	})
+}
+
+func TestStreamCancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest("GET", "/widgets", nil).WithContext(ctx)
+	emitted := 0
+	err := Stream(w, r, func(ctx context.Context, emit func(interface{}) error) error {
+		for i := 0; ; i++ {
+			if i == 3 {
+				cancel()
+			}
+			if err := emit(i); err != nil {
+				return err
+			}
+			emitted++
+		}
+	})
+	if err != context.Canceled || emitted != 3 || w.Body.String() != "0\n1\n2\n" {
+		t.Errorf("expected 3 items and context.Canceled, got %d items, %q and %v", emitted, w.Body, err)
+	}
+}
@@ -0,0 +1,144 @@
+// Copyright © 2017 Walter Scheper <walter.scheper@gmail.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package router
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/wfscheper/mtrest/headers"
+	"github.com/wfscheper/mtrest/uritemplate"
+)
+
+// LinkBuilder generates hrefs for the named routes of a Router, so that links
+// and Location headers always match where resources are mounted.
+type LinkBuilder struct {
+	// Scheme and Host are used for absolute hrefs.
+	Scheme string
+	Host   string
+
+	// Prefix is prepended to the path of every href, for routers mounted
+	// below the root of a site.
+	Prefix string
+
+	// Absolute makes Href return absolute URIs rather than absolute paths.
+	Absolute bool
+
+	mux *Router
+}
+
+// Links returns a LinkBuilder for the router that r was dispatched to. The
+// builder produces absolute hrefs using the scheme and host of r. If the
+// router trusts forwarded headers, the Forwarded header, or failing that the
+// X-Forwarded-Proto, X-Forwarded-Host and X-Forwarded-Prefix headers, take
+// precedence.
+func Links(r *http.Request) *LinkBuilder {
+	var mux *Router
+	if m := fromContext(r); m != nil {
+		mux = m.mux
+	}
+	return mux.Links(r)
+}
+
+// Links returns a LinkBuilder for mux, using the scheme and host of r.
+func (mux *Router) Links(r *http.Request) *LinkBuilder {
+	lb := &LinkBuilder{Scheme: "http", Host: r.Host, Absolute: true, mux: mux}
+	if r.TLS != nil {
+		lb.Scheme = "https"
+	}
+	if mux == nil || !mux.TrustForwarded {
+		return lb
+	}
+
+	if f, err := headers.NewForwarded(strings.Join(r.Header["Forwarded"], ",")); err == nil && len(f) > 0 {
+		if f[0].Proto != "" {
+			lb.Scheme = f[0].Proto
+		}
+		if f[0].Host != "" {
+			lb.Host = f[0].Host
+		}
+	} else {
+		if v := firstValue(r.Header.Get("X-Forwarded-Proto")); v != "" {
+			lb.Scheme = strings.ToLower(v)
+		}
+		if v := firstValue(r.Header.Get("X-Forwarded-Host")); v != "" {
+			lb.Host = v
+		}
+	}
+	if v := strings.Trim(firstValue(r.Header.Get("X-Forwarded-Prefix")), "/"); v != "" {
+		lb.Prefix = "/" + v
+	}
+	return lb
+}
+
+// firstValue returns the first of a comma-separated list of values, which is
+// the one added by the proxy closest to the client.
+func firstValue(s string) string {
+	if i := strings.IndexByte(s, ','); i >= 0 {
+		s = s[:i]
+	}
+	return strings.TrimSpace(s)
+}
+
+// Href expands the template of the route called name with vars.
+func (lb *LinkBuilder) Href(name string, vars uritemplate.Values) (string, error) {
+	rt, err := lb.route(name)
+	if err != nil {
+		return "", err
+	}
+	uri, err := rt.uri.Expand(vars)
+	if err != nil {
+		return "", err
+	}
+	return lb.base() + uri, nil
+}
+
+// Template returns the unexpanded template of the route called name, with
+// the same base as Href, for use in templated links.
+func (lb *LinkBuilder) Template(name string) (string, error) {
+	rt, err := lb.route(name)
+	if err != nil {
+		return "", err
+	}
+	return lb.base() + rt.Template, nil
+}
+
+// URL returns u, a URL on the router's site such as a request URL, with the
+// same base as Href. The scheme and host of u are ignored.
+func (lb *LinkBuilder) URL(u *url.URL) string {
+	s := lb.base() + u.EscapedPath()
+	if u.RawQuery != "" {
+		s += "?" + u.RawQuery
+	}
+	return s
+}
+
+func (lb *LinkBuilder) route(name string) (*Route, error) {
+	if lb.mux != nil {
+		if rt, ok := lb.mux.names[name]; ok {
+			return rt, nil
+		}
+	}
+	return nil, fmt.Errorf("Unknown route: '%s'", name)
+}
+
+func (lb *LinkBuilder) base() string {
+	if lb.Absolute {
+		return lb.Scheme + "://" + lb.Host + lb.Prefix
+	}
+	return lb.Prefix
+}
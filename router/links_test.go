@@ -0,0 +1,150 @@
+// Copyright © 2017 Walter Scheper <walter.scheper@gmail.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package router
+
+import (
+	"crypto/tls"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/wfscheper/mtrest/uritemplate"
+)
+
+func TestLinks(t *testing.T) {
+	tests := []struct {
+		title    string
+		trust    bool
+		tls      bool
+		headers  map[string]string
+		expected string
+	}{
+		{"Request host", false, false, nil, "http://example.com/widgets/42"},
+		{"TLS", false, true, nil, "https://example.com/widgets/42"},
+		{"Untrusted forwarded headers", false, false, map[string]string{
+			"Forwarded":          "proto=https;host=api.example.org",
+			"X-Forwarded-Prefix": "/v1",
+		}, "http://example.com/widgets/42"},
+		{"Forwarded", true, false, map[string]string{
+			"Forwarded": "for=192.0.2.60;proto=https;host=api.example.org, for=10.0.0.1;proto=http;host=internal",
+		}, "https://api.example.org/widgets/42"},
+		{"Forwarded wins over X-Forwarded", true, false, map[string]string{
+			"Forwarded":        "proto=https;host=api.example.org",
+			"X-Forwarded-Host": "other.example.org",
+		}, "https://api.example.org/widgets/42"},
+		{"X-Forwarded", true, false, map[string]string{
+			"X-Forwarded-Proto":  "HTTPS, http",
+			"X-Forwarded-Host":   "api.example.org:8443, internal",
+			"X-Forwarded-Prefix": "/v1/",
+		}, "https://api.example.org:8443/v1/widgets/42"},
+		{"Root X-Forwarded-Prefix", true, false, map[string]string{
+			"X-Forwarded-Prefix": "/",
+		}, "http://example.com/widgets/42"},
+		{"Empty X-Forwarded-Prefix", true, false, map[string]string{
+			"X-Forwarded-Prefix": "//, /v1",
+		}, "http://example.com/widgets/42"},
+		{"Malformed Forwarded falls back to X-Forwarded", true, false, map[string]string{
+			"Forwarded":         "proto",
+			"X-Forwarded-Proto": "https",
+		}, "https://example.com/widgets/42"},
+	}
+	for idx, test := range tests {
+		mux := New()
+		mux.TrustForwarded = test.trust
+		var actual string
+		mux.Mount("/widgets/{id}", handlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			var err error
+			if actual, err = Links(r).Href("widget", uritemplate.Values{"id": Vars(r)["id"]}); err != nil {
+				t.Errorf("%d: (%s) %q", idx, test.title, err)
+			}
+		}), Name("widget"))
+
+		r := httptest.NewRequest("GET", "http://example.com/widgets/42", nil)
+		if test.tls {
+			r.TLS = &tls.ConnectionState{}
+		} else {
+			r.TLS = nil
+		}
+		for k, v := range test.headers {
+			r.Header.Set(k, v)
+		}
+		mux.ServeHTTP(httptest.NewRecorder(), r)
+		if actual != test.expected {
+			t.Errorf("%d: (%s) expected '%s', got '%s'", idx, test.title, test.expected, actual)
+		}
+	}
+}
+
+func TestLinkBuilder(t *testing.T) {
+	mux := New()
+	mux.Mount("/widgets{?page}", readOnly{}, Name("widgets"))
+	mux.Mount("/widgets/{id}", readOnly{}, Name("widget"))
+
+	lb := mux.Links(httptest.NewRequest("GET", "http://example.com/", nil))
+	lb.Absolute = false
+	tests := []struct {
+		name     string
+		vars     uritemplate.Values
+		expected string
+	}{
+		{"widgets", nil, "/widgets"},
+		{"widgets", uritemplate.Values{"page": 2}, "/widgets?page=2"},
+		{"widget", uritemplate.Values{"id": "a b"}, "/widgets/a%20b"},
+	}
+	for idx, test := range tests {
+		actual, err := lb.Href(test.name, test.vars)
+		if err != nil {
+			t.Fatalf("%d: %q", idx, err)
+		}
+		if actual != test.expected {
+			t.Errorf("%d: expected '%s', got '%s'", idx, test.expected, actual)
+		}
+	}
+
+	lb.Prefix = "/api"
+	if actual, _ := lb.Template("widgets"); actual != "/api/widgets{?page}" {
+		t.Errorf("expected '/api/widgets{?page}', got '%s'", actual)
+	}
+	lb.Absolute = true
+	if actual, _ := lb.Template("widgets"); actual != "http://example.com/api/widgets{?page}" {
+		t.Errorf("expected 'http://example.com/api/widgets{?page}', got '%s'", actual)
+	}
+	u, _ := url.Parse("http://internal/widgets/a%2Fb?page=2")
+	if actual := lb.URL(u); actual != "http://example.com/api/widgets/a%2Fb?page=2" {
+		t.Errorf("expected 'http://example.com/api/widgets/a%%2Fb?page=2', got '%s'", actual)
+	}
+
+	if _, err := lb.Href("missing", nil); err == nil || err.Error() != "Unknown route: 'missing'" {
+		t.Errorf("expected 'Unknown route: 'missing'', got '%v'", err)
+	}
+	if _, err := lb.Template("missing"); err == nil {
+		t.Error("expected error, got nil")
+	}
+	if _, err := Links(httptest.NewRequest("GET", "/", nil)).Href("widget", nil); err == nil {
+		t.Error("expected error outside of a router, got nil")
+	}
+}
+
+func TestDuplicateNamePanics(t *testing.T) {
+	mux := &Router{}
+	mux.Mount("/a", readOnly{}, Name("a"))
+	defer func() {
+		if recover() == nil {
+			t.Error("expected Mount to panic")
+		}
+	}()
+	mux.Mount("/b", readOnly{}, Name("a"))
+}
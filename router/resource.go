@@ -0,0 +1,125 @@
+// Copyright © 2017 Walter Scheper <walter.scheper@gmail.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package router
+
+import (
+	"net/http"
+
+	"github.com/wfscheper/mtrest"
+	"github.com/wfscheper/mtrest/codec"
+)
+
+// Resource is anything that can be mounted on a Router. A Resource supports
+// an HTTP method by implementing the matching interface: Getter, Putter,
+// Poster, Patcher or Deleter. HEAD and OPTIONS are handled by the Router.
+type Resource interface{}
+
+// Getter is implemented by resources that support GET, and so HEAD.
+type Getter interface {
+	Get(w http.ResponseWriter, r *http.Request)
+}
+
+// Putter is implemented by resources that support PUT.
+type Putter interface {
+	Put(w http.ResponseWriter, r *http.Request)
+}
+
+// Poster is implemented by resources that support POST.
+type Poster interface {
+	Post(w http.ResponseWriter, r *http.Request)
+}
+
+// Patcher is implemented by resources that support PATCH.
+type Patcher interface {
+	Patch(w http.ResponseWriter, r *http.Request)
+}
+
+// Deleter is implemented by resources that support DELETE.
+type Deleter interface {
+	Delete(w http.ResponseWriter, r *http.Request)
+}
+
+// Consumer is implemented by resources that restrict the media types they
+// accept in request bodies. Consumes returns the media types, or media
+// ranges, accepted by method. A nil result accepts any media type.
+//
+// Resources that do not implement Consumer accept the request bodies that
+// Bind can decode: those with a media type the codec package has a Codec
+// for.
+type Consumer interface {
+	Consumes(method string) []*mtrest.MediaType
+}
+
+// handler returns the function that handles method for res, or nil if res
+// does not support method.
+func handler(res Resource, method string) http.HandlerFunc {
+	switch method {
+	case http.MethodGet, http.MethodHead:
+		if h, ok := res.(Getter); ok {
+			return h.Get
+		}
+	case http.MethodPut:
+		if h, ok := res.(Putter); ok {
+			return h.Put
+		}
+	case http.MethodPost:
+		if h, ok := res.(Poster); ok {
+			return h.Post
+		}
+	case http.MethodPatch:
+		if h, ok := res.(Patcher); ok {
+			return h.Patch
+		}
+	case http.MethodDelete:
+		if h, ok := res.(Deleter); ok {
+			return h.Delete
+		}
+	}
+	return nil
+}
+
+// allowed returns the methods supported by res, in a fixed order.
+func allowed(res Resource) []string {
+	var methods []string
+	for _, method := range []string{http.MethodGet, http.MethodHead, http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete} {
+		if handler(res, method) != nil {
+			methods = append(methods, method)
+		}
+	}
+	return append(methods, http.MethodOptions)
+}
+
+// consumes returns the media types res accepts for method. For resources
+// that are not Consumers, these are the media types of the registered
+// codecs.
+func consumes(res Resource, method string) []*mtrest.MediaType {
+	if c, ok := res.(Consumer); ok {
+		return c.Consumes(method)
+	}
+	if !hasBody(method) {
+		return nil
+	}
+	var types []*mtrest.MediaType
+	for _, encoding := range codec.Encodings() {
+		types = append(types, &mtrest.MediaType{Type: "application", SubType: encoding, Params: map[string]string{}, Weight: 1.0})
+	}
+	return types
+}
+
+// hasBody reports whether requests with method carry a body the resource
+// consumes.
+func hasBody(method string) bool {
+	return method == http.MethodPost || method == http.MethodPut || method == http.MethodPatch
+}
@@ -0,0 +1,265 @@
+// Copyright © 2017 Walter Scheper <walter.scheper@gmail.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package router dispatches requests to resources by path and method.
+package router
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/wfscheper/mtrest"
+	"github.com/wfscheper/mtrest/codec"
+	"github.com/wfscheper/mtrest/uritemplate"
+)
+
+type contextKey int
+
+const routeKey contextKey = iota
+
+// Router mounts resources at path templates and dispatches requests to them
+// by method. It answers OPTIONS requests itself, and responds 405 Method Not
+// Allowed to methods a resource does not support.
+type Router struct {
+	routes []*Route
+	names  map[string]*Route
+
+	// NotFound handles requests that match no route. It defaults to
+	// http.NotFound.
+	NotFound http.Handler
+
+	// TrustForwarded makes links generated by Links honor the Forwarded and
+	// X-Forwarded-* headers. Only set it if the router sits behind proxies
+	// that set, or strip, those headers.
+	TrustForwarded bool
+}
+
+// New returns an empty Router.
+func New() *Router {
+	return &Router{}
+}
+
+// Route is a Resource mounted on a Router.
+type Route struct {
+	Name     string
+	Template string
+	Resource Resource
+	Binder   codec.Binder
+
+	uri        *uritemplate.Template
+	query      bool
+	middleware []func(http.Handler) http.Handler
+}
+
+// Option configures a Route.
+type Option func(*Route)
+
+// Name names the route, so that links to it can be generated by a
+// LinkBuilder.
+func Name(name string) Option {
+	return func(rt *Route) {
+		rt.Name = name
+	}
+}
+
+// WithBinder sets the Binder used by Bind for requests to the route, for
+// example to enable content sniffing.
+func WithBinder(b codec.Binder) Option {
+	return func(rt *Route) {
+		rt.Binder = b
+	}
+}
+
+// With wraps the route's method handlers in middleware, such as a
+// conditional.Policy. The first middleware is the outermost. Requests the
+// router answers itself, such as OPTIONS, do not pass through it.
+func With(middleware ...func(http.Handler) http.Handler) Option {
+	return func(rt *Route) {
+		rt.middleware = append(rt.middleware, middleware...)
+	}
+}
+
+// Mount adds res to the router at template, an RFC 6570 URI template of up to
+// level 3. The template is matched against the request path, and against the
+// query string too if the template has a query component. Routes are matched
+// in the order they were mounted. Mount panics if template is invalid, or if
+// the route's name is already in use.
+func (mux *Router) Mount(template string, res Resource, opts ...Option) *Route {
+	rt := &Route{
+		Template: template,
+		Resource: res,
+		Binder:   codec.DefaultBinder,
+		uri:      uritemplate.MustNew(template),
+		query:    strings.Contains(template, "?") || strings.Contains(template, "{&"),
+	}
+	for _, opt := range opts {
+		opt(rt)
+	}
+	if rt.Name != "" {
+		if _, ok := mux.names[rt.Name]; ok {
+			panic("router: duplicate route name '" + rt.Name + "'")
+		}
+		if mux.names == nil {
+			mux.names = map[string]*Route{}
+		}
+		mux.names[rt.Name] = rt
+	}
+	mux.routes = append(mux.routes, rt)
+	return rt
+}
+
+func (mux *Router) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	rt, vars := mux.match(r.URL)
+	if rt == nil {
+		if mux.NotFound != nil {
+			mux.NotFound.ServeHTTP(w, r)
+		} else {
+			http.NotFound(w, r)
+		}
+		return
+	}
+	r = r.WithContext(context.WithValue(r.Context(), routeKey, &match{mux, rt, vars}))
+
+	if r.Method == http.MethodOptions {
+		rt.setAllowHeaders(w)
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+	h := handler(rt.Resource, r.Method)
+	if h == nil {
+		rt.setAllowHeaders(w)
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+		return
+	}
+	if !rt.consumes(r) {
+		rt.setAllowHeaders(w)
+		http.Error(w, http.StatusText(http.StatusUnsupportedMediaType), http.StatusUnsupportedMediaType)
+		return
+	}
+	var next http.Handler = http.HandlerFunc(h)
+	for i := len(rt.middleware) - 1; i >= 0; i-- {
+		next = rt.middleware[i](next)
+	}
+	next.ServeHTTP(w, r)
+}
+
+func (mux *Router) match(u *url.URL) (*Route, map[string]string) {
+	for _, rt := range mux.routes {
+		uri := u.EscapedPath()
+		if rt.query && u.RawQuery != "" {
+			uri += "?" + u.RawQuery
+		}
+		if vars, ok := rt.uri.Match(uri); ok {
+			return rt, vars
+		}
+	}
+	return nil, nil
+}
+
+// setAllowHeaders sets the Allow header, and the Accept-Post and Accept-Patch
+// headers if the resource restricts the media types those methods consume.
+func (rt *Route) setAllowHeaders(w http.ResponseWriter) {
+	methods := allowed(rt.Resource)
+	w.Header().Set("Allow", strings.Join(methods, ", "))
+	for _, method := range methods {
+		var header string
+		switch method {
+		case http.MethodPost:
+			header = "Accept-Post"
+		case http.MethodPatch:
+			header = "Accept-Patch"
+		default:
+			continue
+		}
+		if types := consumes(rt.Resource, method); len(types) > 0 {
+			w.Header().Set(header, formatMediaTypes(types))
+		}
+	}
+}
+
+// consumes reports whether the resource accepts the request's Content-Type.
+// Requests without a Content-Type, or with a generic one when the route's
+// Binder sniffs, are left for the Binder to judge. Resources that are not
+// Consumers accept any media type with a Codec.
+func (rt *Route) consumes(r *http.Request) bool {
+	types := consumes(rt.Resource, r.Method)
+	contentType := r.Header.Get("Content-Type")
+	if len(types) == 0 || contentType == "" {
+		return true
+	}
+	m, err := mtrest.NewMediaType(contentType)
+	if err != nil {
+		return false
+	}
+	if rt.Binder.Sniff && mtrest.ApplicationOctetStream.Includes(m) {
+		return true
+	}
+	if _, ok := rt.Resource.(Consumer); !ok {
+		_, ok := codec.For(m)
+		return ok
+	}
+	for _, t := range types {
+		if t.Includes(m) {
+			return true
+		}
+	}
+	return false
+}
+
+func formatMediaTypes(types []*mtrest.MediaType) string {
+	s := make([]string, len(types))
+	for i, t := range types {
+		s[i] = t.String()
+	}
+	return strings.Join(s, ", ")
+}
+
+type match struct {
+	mux   *Router
+	route *Route
+	vars  map[string]string
+}
+
+func fromContext(r *http.Request) *match {
+	m, _ := r.Context().Value(routeKey).(*match)
+	return m
+}
+
+// Vars returns the path variables matched by the route that r was dispatched
+// to, or nil if r was not dispatched by a Router.
+func Vars(r *http.Request) map[string]string {
+	if m := fromContext(r); m != nil {
+		return m.vars
+	}
+	return nil
+}
+
+// CurrentRoute returns the Route that r was dispatched to, or nil.
+func CurrentRoute(r *http.Request) *Route {
+	if m := fromContext(r); m != nil {
+		return m.route
+	}
+	return nil
+}
+
+// Bind decodes the body of r into v using the Binder of the route r was
+// dispatched to, or codec.DefaultBinder.
+func Bind(r *http.Request, v interface{}) error {
+	if rt := CurrentRoute(r); rt != nil {
+		return rt.Binder.Bind(r, v)
+	}
+	return codec.Bind(r, v)
+}
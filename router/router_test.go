@@ -0,0 +1,248 @@
+// Copyright © 2017 Walter Scheper <walter.scheper@gmail.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package router
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/wfscheper/mtrest"
+	"github.com/wfscheper/mtrest/codec"
+)
+
+type readOnly struct{}
+
+func (readOnly) Get(w http.ResponseWriter, r *http.Request) {
+	fmt.Fprintf(w, "get %s", Vars(r)["id"])
+}
+
+type widgets struct {
+	readOnly
+	consumes []*mtrest.MediaType
+}
+
+func (widgets) Post(w http.ResponseWriter, r *http.Request) {
+	var v map[string]interface{}
+	if err := Bind(r, &v); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	fmt.Fprintf(w, "post %v", v["name"])
+}
+
+func (widgets) Patch(w http.ResponseWriter, r *http.Request) {
+	fmt.Fprint(w, "patch")
+}
+
+func (widgets) Delete(w http.ResponseWriter, r *http.Request) {
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (res widgets) Consumes(method string) []*mtrest.MediaType {
+	if method == http.MethodPost || method == http.MethodPatch {
+		return res.consumes
+	}
+	return nil
+}
+
+// notes binds request bodies without restricting their media types.
+type notes struct{}
+
+func (notes) Post(w http.ResponseWriter, r *http.Request) {
+	widgets{}.Post(w, r)
+}
+
+func mediaTypes(t *testing.T, types ...string) []*mtrest.MediaType {
+	var m []*mtrest.MediaType
+	for _, s := range types {
+		mt, err := mtrest.NewMediaType(s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		m = append(m, mt)
+	}
+	return m
+}
+
+func TestRouter(t *testing.T) {
+	mux := New()
+	mux.Mount("/things/{id}", readOnly{})
+	mux.Mount("/search{?id}", readOnly{})
+	mux.Mount("/files{+id}", readOnly{})
+	mux.Mount("/widgets", widgets{consumes: mediaTypes(t, "application/json", "application/vnd.foo+json")})
+	mux.Mount("/sniffed", widgets{consumes: mediaTypes(t, "application/json")}, WithBinder(codec.Binder{Sniff: true}))
+	mux.Mount("/notes", notes{})
+
+	var registered []string
+	for _, encoding := range codec.Encodings() {
+		registered = append(registered, "application/"+encoding)
+	}
+
+	tests := []struct {
+		title, method, path, contentType, body string
+		status                                 int
+		expected                               string
+		headers                                map[string]string
+	}{
+		{"GET with path variable", "GET", "/things/42", "", "", 200, "get 42", nil},
+		{"HEAD uses Get", "HEAD", "/things/42", "", "", 200, "get 42", nil},
+		{"Escaped path variable", "GET", "/things/a%2Fb", "", "", 200, "get a/b", nil},
+		{"Query variable", "GET", "/search?x=1&id=7", "", "", 200, "get 7", nil},
+		{"Missing query variable", "GET", "/search", "", "", 200, "get ", nil},
+		{"Reserved expansion", "GET", "/files/a/b", "", "", 200, "get /a/b", nil},
+		{"Query ignored without query template", "GET", "/things/42?id=7", "", "", 200, "get 42", nil},
+		{"Empty path variable", "GET", "/things/", "", "", 404, "404 page not found\n", nil},
+		{"Unknown path", "GET", "/nothing", "", "", 404, "404 page not found\n", nil},
+		{"Extra segments", "GET", "/things/42/parts", "", "", 404, "404 page not found\n", nil},
+		{"Unsupported method", "POST", "/things/42", "", "", 405, "Method Not Allowed\n", map[string]string{
+			"Allow":       "GET, HEAD, OPTIONS",
+			"Accept-Post": "",
+		}},
+		{"OPTIONS on read-only resource", "OPTIONS", "/things/42", "", "", 204, "", map[string]string{
+			"Allow": "GET, HEAD, OPTIONS",
+		}},
+		{"OPTIONS with consumed media types", "OPTIONS", "/widgets", "", "", 204, "", map[string]string{
+			"Allow":        "GET, HEAD, POST, PATCH, DELETE, OPTIONS",
+			"Accept-Post":  "application/json, application/vnd.foo+json",
+			"Accept-Patch": "application/json, application/vnd.foo+json",
+		}},
+		{"POST with consumed media type", "POST", "/widgets", "application/json", `{"name":"a"}`, 200, "post a", nil},
+		{"POST with consumed vendor media type", "POST", "/widgets", "application/vnd.foo+json; version=1", `{"name":"b"}`, 200, "post b", nil},
+		{"POST with unsupported media type", "POST", "/widgets", "application/yaml", "name: a", 415, "Unsupported Media Type\n", map[string]string{
+			"Accept-Post": "application/json, application/vnd.foo+json",
+		}},
+		{"POST with malformed media type", "POST", "/widgets", "application/", "", 415, "Unsupported Media Type\n", nil},
+		{"POST with octet stream and no sniffing", "POST", "/widgets", "application/octet-stream", `{}`, 415, "Unsupported Media Type\n", nil},
+		{"POST with octet stream and sniffing", "POST", "/sniffed", "application/octet-stream", `{"name":"c"}`, 200, "post c", nil},
+		{"POST without content type and sniffing", "POST", "/sniffed", "", `{"name":"d"}`, 200, "post d", nil},
+		{"POST without content type", "POST", "/widgets", "", `{"name":"d"}`, 400, "Unsupported media type\n", nil},
+		{"DELETE", "DELETE", "/widgets", "", "", 204, "", nil},
+		{"OPTIONS without Consumer", "OPTIONS", "/notes", "", "", 204, "", map[string]string{
+			"Allow":       "POST, OPTIONS",
+			"Accept-Post": strings.Join(registered, ", "),
+		}},
+		{"POST without Consumer", "POST", "/notes", "application/vnd.foo+json", `{"name":"e"}`, 200, "post e", nil},
+		{"POST without Consumer or codec", "POST", "/notes", "text/plain", "name", 415, "Unsupported Media Type\n", map[string]string{
+			"Accept-Post": strings.Join(registered, ", "),
+		}},
+	}
+	for idx, test := range tests {
+		r := httptest.NewRequest(test.method, test.path, strings.NewReader(test.body))
+		if test.contentType != "" {
+			r.Header.Set("Content-Type", test.contentType)
+		}
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, r)
+		if w.Code != test.status {
+			t.Errorf("%d: (%s) expected status %d, got %d", idx, test.title, test.status, w.Code)
+		}
+		if actual := w.Body.String(); actual != test.expected {
+			t.Errorf("%d: (%s) expected body %q, got %q", idx, test.title, test.expected, actual)
+		}
+		for k, v := range test.headers {
+			if actual := w.Header().Get(k); actual != v {
+				t.Errorf("%d: (%s) expected %s '%s', got '%s'", idx, test.title, k, v, actual)
+			}
+		}
+	}
+}
+
+func TestRouterNotFound(t *testing.T) {
+	mux := New()
+	mux.NotFound = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusTeapot)
+	})
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, httptest.NewRequest("GET", "/", nil))
+	if w.Code != http.StatusTeapot {
+		t.Errorf("expected %d, got %d", http.StatusTeapot, w.Code)
+	}
+}
+
+func TestRouterMatchOrder(t *testing.T) {
+	mux := New()
+	first := mux.Mount("/things/new", readOnly{})
+	mux.Mount("/things/{id}", readOnly{})
+
+	var actual *Route
+	mux.Mount("/check/{id}", handlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		actual = CurrentRoute(r)
+	}))
+	mux.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/check/1", nil))
+	if actual == nil || actual.Template != "/check/{id}" {
+		t.Errorf("expected /check/{id}, got %+v", actual)
+	}
+
+	if rt, vars := mux.match(&url.URL{Path: "/things/new"}); rt != first || len(vars) != 0 {
+		t.Errorf("expected first route, got %+v and %v", rt, vars)
+	}
+}
+
+func TestWith(t *testing.T) {
+	var order []string
+	mw := func(name string) func(http.Handler) http.Handler {
+		return func(next http.Handler) http.Handler {
+			return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				order = append(order, name)
+				next.ServeHTTP(w, r)
+			})
+		}
+	}
+	mux := New()
+	mux.Mount("/things/{id}", readOnly{}, With(mw("a")), With(mw("b"), mw("c")))
+
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, httptest.NewRequest("GET", "/things/1", nil))
+	if w.Body.String() != "get 1" || strings.Join(order, ",") != "a,b,c" {
+		t.Errorf("expected 'get 1' after a,b,c, got '%s' after %v", w.Body, order)
+	}
+
+	order = nil
+	mux.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("OPTIONS", "/things/1", nil))
+	mux.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("DELETE", "/things/1", nil))
+	if len(order) != 0 {
+		t.Errorf("expected middleware to be skipped, got %v", order)
+	}
+}
+
+type handlerFunc func(w http.ResponseWriter, r *http.Request)
+
+func (f handlerFunc) Get(w http.ResponseWriter, r *http.Request) { f(w, r) }
+
+func TestVarsOutsideRouter(t *testing.T) {
+	r := httptest.NewRequest("GET", "/", nil)
+	if Vars(r) != nil || CurrentRoute(r) != nil {
+		t.Error("expected nil vars and route")
+	}
+	r = httptest.NewRequest("POST", "/", strings.NewReader(`{"a":1}`))
+	r.Header.Set("Content-Type", "application/json")
+	var v map[string]int
+	if err := Bind(r, &v); err != nil || v["a"] != 1 {
+		t.Errorf("expected {a: 1}, got %v and %v", v, err)
+	}
+}
+
+func TestMountPanics(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("expected Mount to panic")
+		}
+	}()
+	New().Mount("/things/{id", readOnly{})
+}
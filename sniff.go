@@ -0,0 +1,464 @@
+// Copyright © 2017 Walter Scheper <walter.scheper@gmail.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mtrest
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"io"
+	"math"
+	"net/http"
+	"unicode/utf8"
+)
+
+// Confidence is how certain Sniff is of the media type it detected.
+type Confidence int
+
+const (
+	// ConfidenceNone means the media type could not be determined.
+	ConfidenceNone Confidence = iota
+	// ConfidenceLow means the data is consistent with the media type, but
+	// is either incomplete or could plausibly be something else.
+	ConfidenceLow
+	// ConfidenceMedium means the data is a complete, well-formed document of
+	// the media type.
+	ConfidenceMedium
+	// ConfidenceHigh means the data declares its media type, for example
+	// with an XML declaration or a magic number.
+	ConfidenceHigh
+)
+
+// A Detector examines data, which may be only a prefix of a document, and
+// returns the media type it believes the document has and how confident it
+// is. A Detector that does not recognize data returns nil and ConfidenceNone.
+type Detector func(data []byte) (*MediaType, Confidence)
+
+// Detectors are the detectors consulted by Sniff, in order of preference.
+var Detectors = []Detector{
+	DetectJSON,
+	DetectXML,
+	DetectCBOR,
+	DetectMsgpack,
+	DetectYAML,
+}
+
+// maxNesting limits how deeply the binary detectors descend into nested
+// arrays and maps.
+const maxNesting = 64
+
+var utf8BOM = []byte{0xef, 0xbb, 0xbf}
+
+// Sniff determines the media type of data, which may be only a prefix of a
+// document. Each of Detectors is consulted, as is http.DetectContentType, and
+// the most confident result wins; ties go to the earlier detector, with
+// http.DetectContentType last. If nothing recognizes data, Sniff returns
+// application/octet-stream and ConfidenceNone.
+func Sniff(data []byte) (*MediaType, Confidence) {
+	var best *MediaType
+	var confidence Confidence
+	for _, detect := range Detectors {
+		if m, c := detect(data); m != nil && c > confidence {
+			best, confidence = m, c
+		}
+	}
+	if m, c := detectHTTP(data); m != nil && c > confidence {
+		best, confidence = m, c
+	}
+	if best == nil {
+		return ApplicationOctetStream.Clone(), ConfidenceNone
+	}
+	return best, confidence
+}
+
+// detectHTTP wraps http.DetectContentType. Its signatures are mostly magic
+// numbers, so its results are trusted highly, except for its text/plain and
+// application/octet-stream fallbacks.
+func detectHTTP(data []byte) (*MediaType, Confidence) {
+	m, err := NewMediaType(http.DetectContentType(data))
+	if err != nil {
+		return nil, ConfidenceNone
+	}
+	switch {
+	case m.Type == "application" && m.SubType == "octet-stream":
+		return nil, ConfidenceNone
+	case m.Type == "text" && m.SubType == "plain":
+		return m, ConfidenceLow
+	default:
+		return m, ConfidenceHigh
+	}
+}
+
+// DetectJSON recognizes JSON objects and arrays. Scalar JSON values are too
+// ambiguous to detect.
+func DetectJSON(data []byte) (*MediaType, Confidence) {
+	data = bytes.TrimPrefix(data, utf8BOM)
+	data = bytes.TrimLeft(data, " \t\r\n")
+	if len(data) == 0 || (data[0] != '{' && data[0] != '[') {
+		return nil, ConfidenceNone
+	}
+	if json.Valid(data) {
+		return ApplicationJSON.Clone(), ConfidenceMedium
+	}
+	// a truncated document is fine, so long as what we have is well-formed
+	dec := json.NewDecoder(bytes.NewReader(data))
+	for {
+		if _, err := dec.Token(); err != nil {
+			if err == io.ErrUnexpectedEOF || err == io.EOF {
+				return ApplicationJSON.Clone(), ConfidenceLow
+			}
+			return nil, ConfidenceNone
+		}
+	}
+}
+
+// DetectXML recognizes XML documents. A document with an XML declaration is
+// detected with high confidence; otherwise the data must begin with a
+// well-formed element.
+func DetectXML(data []byte) (*MediaType, Confidence) {
+	data = bytes.TrimPrefix(data, utf8BOM)
+	data = bytes.TrimLeft(data, " \t\r\n")
+	if bytes.HasPrefix(data, []byte("<?xml")) {
+		return ApplicationXML.Clone(), ConfidenceHigh
+	}
+	if len(data) == 0 || data[0] != '<' {
+		return nil, ConfidenceNone
+	}
+	dec := xml.NewDecoder(bytes.NewReader(data))
+	depth, elements := 0, 0
+	for {
+		tok, err := dec.Token()
+		if err != nil {
+			if elements == 0 {
+				return nil, ConfidenceNone
+			}
+			if err == io.EOF && depth == 0 {
+				return ApplicationXML.Clone(), ConfidenceMedium
+			}
+			if err == io.EOF || err == io.ErrUnexpectedEOF {
+				return ApplicationXML.Clone(), ConfidenceLow
+			}
+			if serr, ok := err.(*xml.SyntaxError); ok && serr.Msg == "unexpected EOF" {
+				return ApplicationXML.Clone(), ConfidenceLow
+			}
+			return nil, ConfidenceNone
+		}
+		switch tok.(type) {
+		case xml.StartElement:
+			depth++
+			elements++
+		case xml.EndElement:
+			depth--
+		}
+	}
+}
+
+// DetectYAML recognizes YAML documents. YAML has very little syntax that
+// distinguishes it from plain text, so only a %YAML directive or a document
+// start marker is trusted; a leading mapping key or sequence entry is
+// detected with low confidence.
+func DetectYAML(data []byte) (*MediaType, Confidence) {
+	data = bytes.TrimPrefix(data, utf8BOM)
+	if !utf8.Valid(data) {
+		return nil, ConfidenceNone
+	}
+	for len(data) > 0 {
+		var line []byte
+		if i := bytes.IndexByte(data, '\n'); i >= 0 {
+			line, data = data[:i], data[i+1:]
+		} else {
+			line, data = data, nil
+		}
+		line = bytes.TrimRight(line, " \t\r")
+		switch {
+		case len(bytes.TrimSpace(line)) == 0, line[0] == '#':
+			continue
+		case bytes.HasPrefix(line, []byte("%YAML ")):
+			return ApplicationYAML.Clone(), ConfidenceHigh
+		case bytes.Equal(line, []byte("---")), bytes.HasPrefix(line, []byte("--- ")):
+			return ApplicationYAML.Clone(), ConfidenceMedium
+		case bytes.HasPrefix(line, []byte("- ")), isYAMLKey(line):
+			return ApplicationYAML.Clone(), ConfidenceLow
+		default:
+			return nil, ConfidenceNone
+		}
+	}
+	return nil, ConfidenceNone
+}
+
+// isYAMLKey reports whether line starts with a plain mapping key, such as
+// "name: value" or "name:".
+func isYAMLKey(line []byte) bool {
+	i := bytes.IndexByte(line, ':')
+	if i <= 0 || (i+1 < len(line) && line[i+1] != ' ') {
+		return false
+	}
+	for _, c := range line[:i] {
+		if !(c == '_' || c == '-' || c == '.' || c >= '0' && c <= '9' || c >= 'A' && c <= 'Z' || c >= 'a' && c <= 'z') {
+			return false
+		}
+	}
+	return true
+}
+
+// DetectCBOR recognizes CBOR documents whose top-level item is an array or a
+// map. The self-describe tag is detected with high confidence.
+func DetectCBOR(data []byte) (*MediaType, Confidence) {
+	if bytes.HasPrefix(data, []byte{0xd9, 0xd9, 0xf7}) {
+		return ApplicationCBOR.Clone(), ConfidenceHigh
+	}
+	if len(data) == 0 {
+		return nil, ConfidenceNone
+	}
+	if major := data[0] >> 5; major != 4 && major != 5 {
+		return nil, ConfidenceNone
+	}
+	return binaryConfidence(&ApplicationCBOR, data, skipCBOR)
+}
+
+// DetectMsgpack recognizes MessagePack documents whose top-level item is an
+// array or a map.
+func DetectMsgpack(data []byte) (*MediaType, Confidence) {
+	if len(data) == 0 {
+		return nil, ConfidenceNone
+	}
+	switch b := data[0]; {
+	case b >= 0x80 && b <= 0x9f, b >= 0xdc && b <= 0xdf:
+	default:
+		return nil, ConfidenceNone
+	}
+	return binaryConfidence(&ApplicationMsgpack, data, skipMsgpack)
+}
+
+var (
+	// errTruncated is returned by the skip functions when data ends part
+	// way through an item.
+	errTruncated = errors.New("truncated document")
+	// errMalformed is returned by the skip functions when data is not
+	// well-formed.
+	errMalformed = errors.New("malformed document")
+)
+
+// skipFunc returns the number of bytes in the item at the start of data.
+type skipFunc func(data []byte, depth int) (int, error)
+
+func binaryConfidence(m *MediaType, data []byte, skip skipFunc) (*MediaType, Confidence) {
+	n, err := skip(data, 0)
+	switch {
+	case err == errTruncated:
+		return m.Clone(), ConfidenceLow
+	case err != nil, n != len(data):
+		return nil, ConfidenceNone
+	default:
+		return m.Clone(), ConfidenceMedium
+	}
+}
+
+// readUint reads a big-endian unsigned integer of size bytes from data at off.
+func readUint(data []byte, off, size int) (uint64, error) {
+	if len(data) < off+size {
+		return 0, errTruncated
+	}
+	b := data[off : off+size]
+	switch size {
+	case 1:
+		return uint64(b[0]), nil
+	case 2:
+		return uint64(binary.BigEndian.Uint16(b)), nil
+	case 4:
+		return uint64(binary.BigEndian.Uint32(b)), nil
+	default:
+		return binary.BigEndian.Uint64(b), nil
+	}
+}
+
+// skipN skips count items starting at off, returning the new offset.
+func skipN(data []byte, off int, count uint64, depth int, skip skipFunc) (int, error) {
+	for ; count > 0; count-- {
+		if off >= len(data) {
+			return 0, errTruncated
+		}
+		n, err := skip(data[off:], depth+1)
+		if err != nil {
+			return 0, err
+		}
+		off += n
+	}
+	return off, nil
+}
+
+// skipBytes skips length bytes starting at off, returning the new offset.
+func skipBytes(data []byte, off int, length uint64) (int, error) {
+	if uint64(len(data)-off) < length {
+		return 0, errTruncated
+	}
+	return off + int(length), nil
+}
+
+// skipCBOR implements skipFunc for CBOR (RFC 7049).
+func skipCBOR(data []byte, depth int) (int, error) {
+	if depth > maxNesting {
+		return 0, errMalformed
+	}
+	if len(data) == 0 {
+		return 0, errTruncated
+	}
+	major, info := data[0]>>5, data[0]&0x1f
+	off, arg := 1, uint64(info)
+	switch {
+	case info == 24, info == 25, info == 26, info == 27:
+		size := 1 << (info - 24)
+		v, err := readUint(data, 1, size)
+		if err != nil {
+			return 0, err
+		}
+		off, arg = 1+size, v
+	case info >= 28 && info <= 30:
+		return 0, errMalformed
+	case info == 31:
+		if major == 0 || major == 1 || major == 6 {
+			return 0, errMalformed
+		}
+		if major == 7 {
+			// a break outside of an indefinite-length item
+			return 0, errMalformed
+		}
+		return skipCBORIndefinite(data, major, depth)
+	}
+	switch major {
+	case 0, 1, 7:
+		return off, nil
+	case 2:
+		return skipBytes(data, off, arg)
+	case 3:
+		end, err := skipBytes(data, off, arg)
+		if err != nil {
+			return 0, err
+		}
+		if !utf8.Valid(data[off:end]) {
+			return 0, errMalformed
+		}
+		return end, nil
+	case 4:
+		return skipN(data, off, arg, depth, skipCBOR)
+	case 5:
+		if arg > math.MaxUint64/2 {
+			return 0, errMalformed
+		}
+		return skipN(data, off, arg*2, depth, skipCBOR)
+	default: // 6, a tag followed by a single item
+		return skipN(data, off, 1, depth, skipCBOR)
+	}
+}
+
+// skipCBORIndefinite skips an indefinite-length string, array or map.
+func skipCBORIndefinite(data []byte, major byte, depth int) (int, error) {
+	off := 1
+	for {
+		if off >= len(data) {
+			return 0, errTruncated
+		}
+		if data[off] == 0xff {
+			return off + 1, nil
+		}
+		if (major == 2 || major == 3) && data[off]>>5 != major {
+			// string chunks must be definite strings of the same type
+			return 0, errMalformed
+		}
+		n, err := skipCBOR(data[off:], depth+1)
+		if err != nil {
+			return 0, err
+		}
+		off += n
+	}
+}
+
+// skipMsgpack implements skipFunc for MessagePack.
+func skipMsgpack(data []byte, depth int) (int, error) {
+	if depth > maxNesting {
+		return 0, errMalformed
+	}
+	if len(data) == 0 {
+		return 0, errTruncated
+	}
+	b := data[0]
+	switch {
+	case b <= 0x7f, b >= 0xe0, b == 0xc0, b == 0xc2, b == 0xc3:
+		return 1, nil
+	case b <= 0x8f:
+		return skipN(data, 1, uint64(b&0x0f)*2, depth, skipMsgpack)
+	case b <= 0x9f:
+		return skipN(data, 1, uint64(b&0x0f), depth, skipMsgpack)
+	case b <= 0xbf:
+		return skipBytes(data, 1, uint64(b&0x1f))
+	}
+	switch b {
+	case 0xc4, 0xd9: // bin 8, str 8
+		return skipSized(data, 1, 0)
+	case 0xc5, 0xda: // bin 16, str 16
+		return skipSized(data, 2, 0)
+	case 0xc6, 0xdb: // bin 32, str 32
+		return skipSized(data, 4, 0)
+	case 0xc7: // ext 8, the length is followed by a one byte type
+		return skipSized(data, 1, 1)
+	case 0xc8: // ext 16
+		return skipSized(data, 2, 1)
+	case 0xc9: // ext 32
+		return skipSized(data, 4, 1)
+	case 0xcc, 0xd0: // uint 8, int 8
+		return skipBytes(data, 1, 1)
+	case 0xcd, 0xd1: // uint 16, int 16
+		return skipBytes(data, 1, 2)
+	case 0xca, 0xce, 0xd2: // float 32, uint 32, int 32
+		return skipBytes(data, 1, 4)
+	case 0xcb, 0xcf, 0xd3: // float 64, uint 64, int 64
+		return skipBytes(data, 1, 8)
+	case 0xd4, 0xd5, 0xd6, 0xd7, 0xd8:
+		// fixext: a one byte type and 1, 2, 4, 8 or 16 bytes of data
+		return skipBytes(data, 1, 1+uint64(1)<<(b-0xd4))
+	case 0xdc: // array 16
+		return skipContainer(data, 2, 1, depth)
+	case 0xdd: // array 32
+		return skipContainer(data, 4, 1, depth)
+	case 0xde: // map 16
+		return skipContainer(data, 2, 2, depth)
+	case 0xdf: // map 32
+		return skipContainer(data, 4, 2, depth)
+	default: // 0xc1 is never used
+		return 0, errMalformed
+	}
+}
+
+// skipSized skips a MessagePack item whose length is given in the size bytes
+// following its format byte. The length does not count extra header bytes.
+func skipSized(data []byte, size int, extra uint64) (int, error) {
+	n, err := readUint(data, 1, size)
+	if err != nil {
+		return 0, err
+	}
+	return skipBytes(data, 1+size, n+extra)
+}
+
+// skipContainer skips a MessagePack array or map whose count is given in the
+// size bytes following its format byte. Each entry holds perEntry items.
+func skipContainer(data []byte, size int, perEntry uint64, depth int) (int, error) {
+	n, err := readUint(data, 1, size)
+	if err != nil {
+		return 0, err
+	}
+	return skipN(data, 1+size, n*perEntry, depth, skipMsgpack)
+}
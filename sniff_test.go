@@ -0,0 +1,132 @@
+// Copyright © 2017 Walter Scheper <walter.scheper@gmail.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mtrest
+
+import (
+	"testing"
+)
+
+func TestSniff(t *testing.T) {
+	tests := []struct {
+		title      string
+		data       []byte
+		expected   string
+		confidence Confidence
+	}{
+		{"Empty document", []byte{}, "text/plain; charset=utf-8", ConfidenceLow},
+		{"JSON object", []byte(`{"a": 1}`), "application/json", ConfidenceMedium},
+		{"JSON array with whitespace", []byte("\n  [1, 2, 3]\n"), "application/json", ConfidenceMedium},
+		{"JSON with BOM", []byte("\xef\xbb\xbf{}"), "application/json", ConfidenceMedium},
+		{"Truncated JSON", []byte(`{"a": [1, 2`), "application/json", ConfidenceLow},
+		{"Malformed JSON", []byte(`{"a" 1}`), "text/plain; charset=utf-8", ConfidenceLow},
+		{"JSON scalar", []byte(`"a"`), "text/plain; charset=utf-8", ConfidenceLow},
+		{"XML declaration", []byte(`<?xml version="1.0"?><a/>`), "application/xml", ConfidenceHigh},
+		{"XML element", []byte(`<widget><name>c</name></widget>`), "application/xml", ConfidenceMedium},
+		{"Truncated XML", []byte(`<widget><name>c</name`), "application/xml", ConfidenceLow},
+		{"HTML", []byte(`<!DOCTYPE html><html><body></body></html>`), "text/html; charset=utf-8", ConfidenceHigh},
+		{"YAML directive", []byte("%YAML 1.2\n---\na: 1\n"), "application/yaml", ConfidenceHigh},
+		{"YAML document marker", []byte("# comment\n---\na: 1\n"), "application/yaml", ConfidenceMedium},
+		{"YAML mapping", []byte("a: 1\nb: 2\n"), "application/yaml", ConfidenceLow},
+		{"YAML sequence", []byte("- a\n- b\n"), "application/yaml", ConfidenceLow},
+		{"Plain text", []byte("hello, world"), "text/plain; charset=utf-8", ConfidenceLow},
+		{"CBOR self-describe tag", []byte{0xd9, 0xd9, 0xf7, 0xa0}, "application/cbor", ConfidenceHigh},
+		{"CBOR map", []byte{0xa1, 0x61, 0x61, 0x01}, "application/cbor", ConfidenceMedium},
+		{"CBOR array", []byte{0x83, 0x01, 0x02, 0x03}, "application/cbor", ConfidenceMedium},
+		{"CBOR indefinite array", []byte{0x9f, 0x01, 0x02, 0xff}, "application/cbor", ConfidenceMedium},
+		{"MessagePack map", []byte{0x81, 0xa1, 0x61, 0x01}, "application/msgpack", ConfidenceMedium},
+		{"MessagePack array 16", []byte{0xdc, 0x00, 0x02, 0xc3, 0xcc, 0xff}, "application/msgpack", ConfidenceMedium},
+		{"Truncated MessagePack", []byte{0x82, 0xa1, 'a', 0xc3, 0xa1}, "application/msgpack", ConfidenceLow},
+		{"PNG", []byte("\x89PNG\x0D\x0A\x1A\x0A"), "image/png", ConfidenceHigh},
+		{"Unknown binary", []byte{0x00, 0x01, 0xc1}, "application/octet-stream", ConfidenceNone},
+	}
+	for idx, test := range tests {
+		m, c := Sniff(test.data)
+		if actual := m.String(); actual != test.expected {
+			t.Errorf("%d: (%s) expected '%s', got '%s'", idx, test.title, test.expected, actual)
+		}
+		if c != test.confidence {
+			t.Errorf("%d: (%s) expected confidence %d, got %d", idx, test.title, test.confidence, c)
+		}
+	}
+}
+
+func TestSniffReturnsCopies(t *testing.T) {
+	m, _ := Sniff([]byte(`{}`))
+	m.Params["version"] = "1"
+	if len(ApplicationJSON.Params) != 0 {
+		t.Errorf("expected ApplicationJSON to be unmodified, got %q", ApplicationJSON)
+	}
+}
+
+func Test_skipCBOR(t *testing.T) {
+	tests := []struct {
+		title    string
+		data     []byte
+		expected int
+		err      error
+	}{
+		{"Unsigned integer", []byte{0x17}, 1, nil},
+		{"One byte argument", []byte{0x18, 0xff}, 2, nil},
+		{"Eight byte argument", []byte{0x1b, 0, 0, 0, 0, 0, 0, 0, 1}, 9, nil},
+		{"Truncated argument", []byte{0x19, 0xff}, 0, errTruncated},
+		{"Reserved additional information", []byte{0x1c}, 0, errMalformed},
+		{"Byte string", []byte{0x42, 0x01, 0x02}, 3, nil},
+		{"Text string", []byte{0x62, 'h', 'i'}, 3, nil},
+		{"Invalid UTF-8 text string", []byte{0x61, 0xff}, 0, errMalformed},
+		{"Indefinite text string", []byte{0x7f, 0x61, 'a', 0x61, 'b', 0xff}, 6, nil},
+		{"Indefinite text string with byte chunk", []byte{0x7f, 0x41, 'a', 0xff}, 0, errMalformed},
+		{"Nested map", []byte{0xa1, 0x01, 0xa1, 0x02, 0x03}, 5, nil},
+		{"Tag", []byte{0xc1, 0x1a, 0, 0, 0, 1}, 6, nil},
+		{"Float", []byte{0xfb, 0, 0, 0, 0, 0, 0, 0, 0}, 9, nil},
+		{"Unexpected break", []byte{0xff}, 0, errMalformed},
+	}
+	for idx, test := range tests {
+		n, err := skipCBOR(test.data, 0)
+		if n != test.expected || err != test.err {
+			t.Errorf("%d: (%s) expected %d and %v, got %d and %v", idx, test.title, test.expected, test.err, n, err)
+		}
+	}
+}
+
+func Test_skipMsgpack(t *testing.T) {
+	tests := []struct {
+		title    string
+		data     []byte
+		expected int
+		err      error
+	}{
+		{"Positive fixint", []byte{0x7f}, 1, nil},
+		{"Negative fixint", []byte{0xe0}, 1, nil},
+		{"nil", []byte{0xc0}, 1, nil},
+		{"Never used", []byte{0xc1}, 0, errMalformed},
+		{"fixstr", []byte{0xa2, 'h', 'i'}, 3, nil},
+		{"str 8", []byte{0xd9, 0x02, 'h', 'i'}, 4, nil},
+		{"Truncated str 16", []byte{0xda, 0x00, 0x05, 'h'}, 0, errTruncated},
+		{"bin 8", []byte{0xc4, 0x01, 0x00}, 3, nil},
+		{"ext 8", []byte{0xc7, 0x01, 0x05, 0x00}, 4, nil},
+		{"fixext 4", []byte{0xd6, 0x05, 0, 0, 0, 0}, 6, nil},
+		{"float 64", []byte{0xcb, 0, 0, 0, 0, 0, 0, 0, 0}, 9, nil},
+		{"int 16", []byte{0xd1, 0xff, 0xff}, 3, nil},
+		{"fixarray", []byte{0x92, 0x01, 0x02}, 3, nil},
+		{"map 16", []byte{0xde, 0x00, 0x01, 0x01, 0x02}, 5, nil},
+		{"Truncated map 32", []byte{0xdf, 0x00, 0x00, 0x00, 0x02, 0x01, 0x02}, 0, errTruncated},
+	}
+	for idx, test := range tests {
+		n, err := skipMsgpack(test.data, 0)
+		if n != test.expected || err != test.err {
+			t.Errorf("%d: (%s) expected %d and %v, got %d and %v", idx, test.title, test.expected, test.err, n, err)
+		}
+	}
+}
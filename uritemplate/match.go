@@ -0,0 +1,182 @@
+// Copyright © 2017 Walter Scheper <walter.scheper@gmail.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package uritemplate
+
+import (
+	"bytes"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+const (
+	pctEncoded = `%[0-9A-Fa-f]{2}`
+	unreserved = `A-Za-z0-9\-._~`
+	reserved   = `:/?#\[\]@!$&'()*+,;=`
+)
+
+// matcher is a template compiled to a regular expression. Each capture group
+// holds either the value of one variable, or the whole expansion of a named
+// expression, which is split into variables after matching.
+type matcher struct {
+	re     *regexp.Regexp
+	groups []group
+	named  map[string]bool
+}
+
+type group struct {
+	name string
+	expr *expression
+}
+
+// Match matches uri against the template and returns the values of its
+// variables. Variables that are undefined in uri are omitted. Templates up
+// to level 3 can be matched; a template with an explode modifier never
+// matches, and a prefix modifier matches the truncated value.
+//
+// Matching is greedy from left to right, so that {x,y} matched against "a"
+// sets x. A simple string expansion, such as {id}, must match at least one
+// character, so that it never matches an empty path segment.
+func (t *Template) Match(uri string) (map[string]string, bool) {
+	t.once.Do(func() {
+		t.matcher = t.compile()
+	})
+	if t.matcher == nil {
+		return nil, false
+	}
+	m := t.matcher.re.FindStringSubmatchIndex(uri)
+	if m == nil {
+		return nil, false
+	}
+	vars := map[string]string{}
+	for i, g := range t.matcher.groups {
+		start, end := m[2*i+2], m[2*i+3]
+		if start < 0 {
+			continue
+		}
+		s := uri[start:end]
+		if g.expr != nil {
+			if !t.matcher.splitNamed(vars, g.expr.op, s) {
+				return nil, false
+			}
+			continue
+		}
+		v, err := url.PathUnescape(s)
+		if err != nil {
+			return nil, false
+		}
+		vars[g.name] = v
+	}
+	return vars, true
+}
+
+func (t *Template) compile() *matcher {
+	m := &matcher{named: map[string]bool{}}
+	var buf bytes.Buffer
+	buf.WriteByte('^')
+	for _, p := range t.parts {
+		if p.expr == nil {
+			buf.WriteString(regexp.QuoteMeta(encode(p.literal, true)))
+			continue
+		}
+		for _, v := range p.expr.vars {
+			if v.explode {
+				return nil
+			}
+		}
+		if p.expr.op.named {
+			m.compileNamed(&buf, p.expr)
+		} else {
+			m.compileUnnamed(&buf, p.expr)
+		}
+	}
+	buf.WriteByte('$')
+	m.re = regexp.MustCompile(buf.String())
+	return m
+}
+
+// compileUnnamed adds a capture group for each variable of e, nested so that
+// trailing variables may be undefined.
+func (m *matcher) compileUnnamed(buf *bytes.Buffer, e *expression) {
+	chars := unreserved
+	if e.op.allowReserved {
+		chars += reserved
+		if len(e.vars) > 1 {
+			// commas separate the values
+			chars = strings.Replace(chars, ",", "", 1)
+		}
+	}
+	if e.op.sep == "." {
+		chars = strings.Replace(chars, ".", "", 1)
+	}
+	value := `((?:[` + chars + `]|` + pctEncoded + `)*)`
+	for i, v := range e.vars {
+		if i == 0 {
+			buf.WriteString(`(?:` + regexp.QuoteMeta(e.op.first))
+			if e.op.char == 0 {
+				buf.WriteString(strings.Replace(value, ")*)", ")+)", 1))
+			} else {
+				buf.WriteString(value)
+			}
+		} else {
+			buf.WriteString(`(?:` + regexp.QuoteMeta(e.op.sep) + value)
+		}
+		m.groups = append(m.groups, group{name: v.name})
+	}
+	for i := len(e.vars) - 1; i >= 0; i-- {
+		if i == 0 && e.op.char == 0 {
+			buf.WriteString(`)`)
+		} else {
+			buf.WriteString(`)?`)
+		}
+	}
+}
+
+// compileNamed adds a single capture group for the whole expansion of e.
+func (m *matcher) compileNamed(buf *bytes.Buffer, e *expression) {
+	exclude := "#"
+	if e.op.char == ';' {
+		exclude = "/?#"
+	}
+	buf.WriteString(`(` + regexp.QuoteMeta(e.op.first) + `[^` + exclude + `]*)?`)
+	m.groups = append(m.groups, group{expr: e})
+	for _, v := range e.vars {
+		m.named[v.name] = true
+	}
+}
+
+// splitNamed splits the expansion s of a named expression into variables.
+// Parameters that do not name a variable of the template are ignored.
+func (m *matcher) splitNamed(vars map[string]string, op *operator, s string) bool {
+	s = strings.TrimPrefix(s, op.first)
+	if s == "" {
+		return true
+	}
+	for _, param := range strings.Split(s, op.sep) {
+		name, value := param, ""
+		if i := strings.IndexByte(param, '='); i >= 0 {
+			name, value = param[:i], param[i+1:]
+		}
+		if !m.named[name] {
+			continue
+		}
+		v, err := url.PathUnescape(value)
+		if err != nil {
+			return false
+		}
+		vars[name] = v
+	}
+	return true
+}
@@ -0,0 +1,102 @@
+// Copyright © 2017 Walter Scheper <walter.scheper@gmail.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package uritemplate
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestMatch(t *testing.T) {
+	tests := []struct {
+		template, uri string
+		expected      map[string]string
+	}{
+		// level 1
+		{"/users/{id}", "/users/42", map[string]string{"id": "42"}},
+		{"/users/{id}", "/users/a%20b", map[string]string{"id": "a b"}},
+		{"/users/{id}", "/users/", nil},
+		{"/users/{id}", "/users/42/repos", nil},
+		{"/users/{id}", "/people/42", nil},
+		{"/users/{id}/repos/{repo}", "/users/42/repos/mtrest", map[string]string{"id": "42", "repo": "mtrest"}},
+		{"/a b/{id}", "/a%20b/1", map[string]string{"id": "1"}},
+		{"/files/{name}.{ext}", "/files/readme.md", map[string]string{"name": "readme", "ext": "md"}},
+		// level 2
+		{"/static{+path}", "/static/css/site.css", map[string]string{"path": "/css/site.css"}},
+		{"/static{+path}", "/static", map[string]string{"path": ""}},
+		{"{+base}/items", "http://example.com/api/items", map[string]string{"base": "http://example.com/api"}},
+		{"/doc{#section}", "/doc#intro", map[string]string{"section": "intro"}},
+		{"/doc{#section}", "/doc", map[string]string{}},
+		// level 3
+		{"/map/{x,y}", "/map/1024,768", map[string]string{"x": "1024", "y": "768"}},
+		{"/map/{x,y}", "/map/1024", map[string]string{"x": "1024"}},
+		{"/files{/dir,name}", "/files/docs/readme", map[string]string{"dir": "docs", "name": "readme"}},
+		{"/files{/dir,name}", "/files/docs", map[string]string{"dir": "docs"}},
+		{"/files{/dir,name}", "/files", map[string]string{}},
+		{"www{.domain,tld}", "www.example.com", map[string]string{"domain": "example", "tld": "com"}},
+		{"/items{;color,size}", "/items;color=red;size=xl", map[string]string{"color": "red", "size": "xl"}},
+		{"/items{;color,size}", "/items;size=xl", map[string]string{"size": "xl"}},
+		{"/items{;color,size}", "/items;color", map[string]string{"color": ""}},
+		{"/search{?q,page}", "/search?q=a%20b&page=2", map[string]string{"q": "a b", "page": "2"}},
+		{"/search{?q,page}", "/search?page=2&other=1", map[string]string{"page": "2"}},
+		{"/search{?q,page}", "/search", map[string]string{}},
+		{"/search{?q}{&page}", "/search?q=go&page=3", map[string]string{"q": "go", "page": "3"}},
+		{"/search?fixed=yes{&q}", "/search?fixed=yes&q=go", map[string]string{"q": "go"}},
+		// level 4
+		{"/users/{id:3}", "/users/abc", map[string]string{"id": "abc"}},
+		{"/files{/path*}", "/files/a/b", nil},
+		// invalid escapes
+		{"/users/{id}", "/users/%zz", nil},
+		{"/search{?q}", "/search?q=%zz", nil},
+	}
+	for idx, test := range tests {
+		actual, ok := MustNew(test.template).Match(test.uri)
+		if ok != (test.expected != nil) {
+			t.Errorf("%d: (%s, %s) expected match %t, got %t", idx, test.template, test.uri, test.expected != nil, ok)
+		}
+		if test.expected != nil && !reflect.DeepEqual(test.expected, actual) {
+			t.Errorf("%d: (%s, %s) expected %v, got %v", idx, test.template, test.uri, test.expected, actual)
+		}
+	}
+}
+
+func TestMatchExpansion(t *testing.T) {
+	tests := []struct {
+		template string
+		values   Values
+	}{
+		{"/users/{id}", Values{"id": "a/b c"}},
+		{"/users/{id}/repos{/repo}{?page,per_page}", Values{"id": "42", "repo": "mtrest", "page": "2", "per_page": "10"}},
+		{"/static{+path}", Values{"path": "/a/b c/d"}},
+		{"/items{;color,size}", Values{"color": "red", "size": "xl"}},
+		{"/search{?q}", Values{"q": "a&b=c"}},
+	}
+	for idx, test := range tests {
+		tmpl := MustNew(test.template)
+		uri, err := tmpl.Expand(test.values)
+		if err != nil {
+			t.Fatalf("%d: %q", idx, err)
+		}
+		actual, ok := tmpl.Match(uri)
+		if !ok {
+			t.Fatalf("%d: expected %s to match %s", idx, uri, test.template)
+		}
+		for k, v := range test.values {
+			if actual[k] != v {
+				t.Errorf("%d: expected %s to be '%s', got '%s'", idx, k, v, actual[k])
+			}
+		}
+	}
+}
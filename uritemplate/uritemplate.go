@@ -0,0 +1,379 @@
+// Copyright © 2017 Walter Scheper <walter.scheper@gmail.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package uritemplate implements RFC 6570 URI Templates. Templates of all four
+// levels can be expanded, and templates up to level 3 can be matched against
+// a URI to recover the values of their variables.
+package uritemplate
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Values holds the variables used to expand a template. A value may be a
+// string, a []string list, a map[string]string associative array, whose
+// keys are expanded in sorted order, or a [][2]string associative array,
+// whose pairs are expanded in the order given. Any other non-nil value is
+// formatted with fmt.Sprint. A nil value, an empty list and an empty
+// associative array are all undefined.
+type Values map[string]interface{}
+
+// Template is a parsed URI template.
+type Template struct {
+	raw   string
+	parts []part
+
+	once    sync.Once
+	matcher *matcher
+}
+
+type part struct {
+	literal string
+	expr    *expression
+}
+
+type expression struct {
+	op   *operator
+	vars []varspec
+}
+
+type varspec struct {
+	name    string
+	prefix  int
+	explode bool
+}
+
+type operator struct {
+	char          byte
+	first, sep    string
+	named         bool
+	ifEmpty       string
+	allowReserved bool
+}
+
+// operators holds the expansion rules from RFC 6570, appendix A.
+var operators = map[byte]*operator{
+	0:   {0, "", ",", false, "", false},
+	'+': {'+', "", ",", false, "", true},
+	'.': {'.', ".", ".", false, "", false},
+	'/': {'/', "/", "/", false, "", false},
+	';': {';', ";", ";", true, "", false},
+	'?': {'?', "?", "&", true, "=", false},
+	'&': {'&', "&", "&", true, "=", false},
+	'#': {'#', "#", ",", false, "", true},
+}
+
+// New parses s as a URI template.
+func New(s string) (*Template, error) {
+	t := &Template{raw: s}
+	for len(s) > 0 {
+		i := strings.IndexAny(s, "{}")
+		if i < 0 {
+			t.parts = append(t.parts, part{literal: s})
+			break
+		}
+		if s[i] == '}' {
+			return nil, fmt.Errorf("Error parsing URI template: unexpected '}' at %d", len(t.raw)-len(s)+i)
+		}
+		if i > 0 {
+			t.parts = append(t.parts, part{literal: s[:i]})
+		}
+		j := strings.IndexByte(s[i:], '}')
+		if j < 0 {
+			return nil, fmt.Errorf("Error parsing URI template: unclosed expression at %d", len(t.raw)-len(s)+i)
+		}
+		expr, err := parseExpression(s[i+1 : i+j])
+		if err != nil {
+			return nil, err
+		}
+		t.parts = append(t.parts, part{expr: expr})
+		s = s[i+j+1:]
+	}
+	return t, nil
+}
+
+// MustNew is like New but panics if s cannot be parsed.
+func MustNew(s string) *Template {
+	t, err := New(s)
+	if err != nil {
+		panic(err)
+	}
+	return t
+}
+
+func parseExpression(s string) (*expression, error) {
+	if s == "" {
+		return nil, fmt.Errorf("Error parsing URI template: empty expression")
+	}
+	expr := &expression{op: operators[0]}
+	if op, ok := operators[s[0]]; ok && s[0] != 0 {
+		expr.op, s = op, s[1:]
+	} else if strings.IndexByte("=,!@|", s[0]) >= 0 {
+		return nil, fmt.Errorf("Error parsing URI template: reserved operator '%c'", s[0])
+	}
+	for _, spec := range strings.Split(s, ",") {
+		v := varspec{name: spec}
+		if strings.HasSuffix(spec, "*") {
+			v.name, v.explode = spec[:len(spec)-1], true
+		} else if i := strings.IndexByte(spec, ':'); i >= 0 {
+			n, err := strconv.Atoi(spec[i+1:])
+			if err != nil || n < 1 || n > 9999 || spec[i+1] == '0' {
+				return nil, fmt.Errorf("Error parsing URI template: invalid prefix '%s'", spec)
+			}
+			v.name, v.prefix = spec[:i], n
+		}
+		if !validName(v.name) {
+			return nil, fmt.Errorf("Error parsing URI template: invalid variable name '%s'", v.name)
+		}
+		expr.vars = append(expr.vars, v)
+	}
+	return expr, nil
+}
+
+// validName reports whether s is a varname: varchars, optionally separated by
+// single dots, where a varchar is ALPHA, DIGIT, "_" or a pct-encoded byte.
+func validName(s string) bool {
+	if s == "" || s[0] == '.' || s[len(s)-1] == '.' {
+		return false
+	}
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case isAlpha(c), isDigit(c), c == '_':
+		case c == '.':
+			if s[i+1] == '.' {
+				return false
+			}
+		case c == '%':
+			if i+2 >= len(s) || !isHex(s[i+1]) || !isHex(s[i+2]) {
+				return false
+			}
+			i += 2
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// String returns the template as it was parsed.
+func (t *Template) String() string {
+	return t.raw
+}
+
+// Names returns the names of the template's variables in the order they
+// first appear.
+func (t *Template) Names() []string {
+	var names []string
+	seen := map[string]bool{}
+	for _, p := range t.parts {
+		if p.expr == nil {
+			continue
+		}
+		for _, v := range p.expr.vars {
+			if !seen[v.name] {
+				names = append(names, v.name)
+				seen[v.name] = true
+			}
+		}
+	}
+	return names
+}
+
+// Expand expands the template using vars. It fails only if a prefix modifier
+// is applied to a list or associative array.
+func (t *Template) Expand(vars Values) (string, error) {
+	var buf bytes.Buffer
+	for _, p := range t.parts {
+		if p.expr == nil {
+			buf.WriteString(encode(p.literal, true))
+			continue
+		}
+		if err := p.expr.expand(&buf, vars); err != nil {
+			return "", err
+		}
+	}
+	return buf.String(), nil
+}
+
+func (e *expression) expand(buf *bytes.Buffer, vars Values) error {
+	first := true
+	for _, v := range e.vars {
+		value, ok := lookup(vars, v.name)
+		if !ok {
+			continue
+		}
+		if first {
+			buf.WriteString(e.op.first)
+			first = false
+		} else {
+			buf.WriteString(e.op.sep)
+		}
+		switch value := value.(type) {
+		case string:
+			e.expandString(buf, v, value)
+		case []string:
+			if v.prefix > 0 {
+				return fmt.Errorf("Error expanding URI template: prefix applied to list '%s'", v.name)
+			}
+			e.expandList(buf, v, value)
+		case [][2]string:
+			if v.prefix > 0 {
+				return fmt.Errorf("Error expanding URI template: prefix applied to associative array '%s'", v.name)
+			}
+			e.expandPairs(buf, v, value)
+		}
+	}
+	return nil
+}
+
+func (e *expression) expandString(buf *bytes.Buffer, v varspec, s string) {
+	if e.op.named {
+		buf.WriteString(v.name)
+		if s == "" {
+			buf.WriteString(e.op.ifEmpty)
+			return
+		}
+		buf.WriteByte('=')
+	}
+	if v.prefix > 0 {
+		if r := []rune(s); len(r) > v.prefix {
+			s = string(r[:v.prefix])
+		}
+	}
+	buf.WriteString(encode(s, e.op.allowReserved))
+}
+
+func (e *expression) expandList(buf *bytes.Buffer, v varspec, list []string) {
+	if !v.explode {
+		if e.op.named {
+			buf.WriteString(v.name + "=")
+		}
+		for i, s := range list {
+			if i > 0 {
+				buf.WriteByte(',')
+			}
+			buf.WriteString(encode(s, e.op.allowReserved))
+		}
+		return
+	}
+	for i, s := range list {
+		if i > 0 {
+			buf.WriteString(e.op.sep)
+		}
+		e.expandString(buf, varspec{name: v.name}, s)
+	}
+}
+
+func (e *expression) expandPairs(buf *bytes.Buffer, v varspec, pairs [][2]string) {
+	if !v.explode {
+		if e.op.named {
+			buf.WriteString(v.name + "=")
+		}
+		for i, kv := range pairs {
+			if i > 0 {
+				buf.WriteByte(',')
+			}
+			buf.WriteString(encode(kv[0], e.op.allowReserved))
+			buf.WriteByte(',')
+			buf.WriteString(encode(kv[1], e.op.allowReserved))
+		}
+		return
+	}
+	for i, kv := range pairs {
+		if i > 0 {
+			buf.WriteString(e.op.sep)
+		}
+		buf.WriteString(encode(kv[0], e.op.allowReserved))
+		if e.op.named && kv[1] == "" {
+			buf.WriteString(e.op.ifEmpty)
+			continue
+		}
+		buf.WriteByte('=')
+		buf.WriteString(encode(kv[1], e.op.allowReserved))
+	}
+}
+
+// lookup returns the value of name in vars, normalized to a string, a
+// []string or a [][2]string. It reports false if the value is undefined.
+func lookup(vars Values, name string) (interface{}, bool) {
+	switch value := vars[name].(type) {
+	case nil:
+		return nil, false
+	case string:
+		return value, true
+	case []string:
+		return value, len(value) > 0
+	case map[string]string:
+		keys := make([]string, 0, len(value))
+		for k := range value {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		pairs := make([][2]string, len(keys))
+		for i, k := range keys {
+			pairs[i] = [2]string{k, value[k]}
+		}
+		return pairs, len(pairs) > 0
+	case [][2]string:
+		return value, len(value) > 0
+	default:
+		return fmt.Sprint(value), true
+	}
+}
+
+// encode percent-encodes every byte of s that is not unreserved. If
+// allowReserved is true, reserved characters and existing pct-encoded
+// triplets are also left alone.
+func encode(s string, allowReserved bool) string {
+	var buf bytes.Buffer
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case isUnreserved(c), allowReserved && isReserved(c):
+			buf.WriteByte(c)
+		case allowReserved && c == '%' && i+2 < len(s) && isHex(s[i+1]) && isHex(s[i+2]):
+			buf.WriteString(s[i : i+3])
+			i += 2
+		default:
+			fmt.Fprintf(&buf, "%%%02X", c)
+		}
+	}
+	return buf.String()
+}
+
+func isAlpha(c byte) bool {
+	return c >= 'A' && c <= 'Z' || c >= 'a' && c <= 'z'
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+func isHex(c byte) bool {
+	return isDigit(c) || c >= 'A' && c <= 'F' || c >= 'a' && c <= 'f'
+}
+
+func isUnreserved(c byte) bool {
+	return isAlpha(c) || isDigit(c) || c == '-' || c == '.' || c == '_' || c == '~'
+}
+
+func isReserved(c byte) bool {
+	return strings.IndexByte(":/?#[]@!$&'()*+,;=", c) >= 0
+}
@@ -0,0 +1,271 @@
+// Copyright © 2017 Walter Scheper <walter.scheper@gmail.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package uritemplate
+
+import (
+	"reflect"
+	"testing"
+)
+
+// rfcValues are the variables used by the examples in RFC 6570, section 3.2.
+var rfcValues = Values{
+	"count":      []string{"one", "two", "three"},
+	"dom":        []string{"example", "com"},
+	"dub":        "me/too",
+	"hello":      "Hello World!",
+	"half":       "50%",
+	"var":        "value",
+	"who":        "fred",
+	"base":       "http://example.com/home/",
+	"path":       "/foo/bar",
+	"list":       []string{"red", "green", "blue"},
+	"keys":       [][2]string{{"semi", ";"}, {"dot", "."}, {"comma", ","}},
+	"v":          "6",
+	"x":          "1024",
+	"y":          "768",
+	"empty":      "",
+	"empty_keys": [][2]string{},
+	"undef":      nil,
+}
+
+func TestExpand(t *testing.T) {
+	tests := []struct {
+		template, expected string
+	}{
+		// 3.2.1 Variable Expansion
+		{"{count}", "one,two,three"},
+		{"{count*}", "one,two,three"},
+		{"{/count}", "/one,two,three"},
+		{"{/count*}", "/one/two/three"},
+		{"{;count}", ";count=one,two,three"},
+		{"{;count*}", ";count=one;count=two;count=three"},
+		{"{?count}", "?count=one,two,three"},
+		{"{?count*}", "?count=one&count=two&count=three"},
+		{"{&count*}", "&count=one&count=two&count=three"},
+		// 3.2.2 Simple String Expansion
+		{"{var}", "value"},
+		{"{hello}", "Hello%20World%21"},
+		{"{half}", "50%25"},
+		{"O{empty}X", "OX"},
+		{"O{undef}X", "OX"},
+		{"{x,y}", "1024,768"},
+		{"{x,hello,y}", "1024,Hello%20World%21,768"},
+		{"?{x,empty}", "?1024,"},
+		{"?{x,undef}", "?1024"},
+		{"?{undef,y}", "?768"},
+		{"{var:3}", "val"},
+		{"{var:30}", "value"},
+		{"{list}", "red,green,blue"},
+		{"{list*}", "red,green,blue"},
+		{"{keys}", "semi,%3B,dot,.,comma,%2C"},
+		{"{keys*}", "semi=%3B,dot=.,comma=%2C"},
+		// 3.2.3 Reserved Expansion
+		{"{+var}", "value"},
+		{"{+hello}", "Hello%20World!"},
+		{"{+half}", "50%25"},
+		{"{base}index", "http%3A%2F%2Fexample.com%2Fhome%2Findex"},
+		{"{+base}index", "http://example.com/home/index"},
+		{"O{+empty}X", "OX"},
+		{"O{+undef}X", "OX"},
+		{"{+path}/here", "/foo/bar/here"},
+		{"here?ref={+path}", "here?ref=/foo/bar"},
+		{"up{+path}{var}/here", "up/foo/barvalue/here"},
+		{"{+x,hello,y}", "1024,Hello%20World!,768"},
+		{"{+path,x}/here", "/foo/bar,1024/here"},
+		{"{+path:6}/here", "/foo/b/here"},
+		{"{+list}", "red,green,blue"},
+		{"{+list*}", "red,green,blue"},
+		{"{+keys}", "semi,;,dot,.,comma,,"},
+		{"{+keys*}", "semi=;,dot=.,comma=,"},
+		// 3.2.4 Fragment Expansion
+		{"{#var}", "#value"},
+		{"{#hello}", "#Hello%20World!"},
+		{"{#half}", "#50%25"},
+		{"foo{#empty}", "foo#"},
+		{"foo{#undef}", "foo"},
+		{"{#x,hello,y}", "#1024,Hello%20World!,768"},
+		{"{#path,x}/here", "#/foo/bar,1024/here"},
+		{"{#path:6}/here", "#/foo/b/here"},
+		{"{#list}", "#red,green,blue"},
+		{"{#list*}", "#red,green,blue"},
+		{"{#keys}", "#semi,;,dot,.,comma,,"},
+		{"{#keys*}", "#semi=;,dot=.,comma=,"},
+		// 3.2.5 Label Expansion with Dot-Prefix
+		{"{.who}", ".fred"},
+		{"{.who,who}", ".fred.fred"},
+		{"{.half,who}", ".50%25.fred"},
+		{"www{.dom*}", "www.example.com"},
+		{"X{.var}", "X.value"},
+		{"X{.empty}", "X."},
+		{"X{.undef}", "X"},
+		{"X{.var:3}", "X.val"},
+		{"X{.list}", "X.red,green,blue"},
+		{"X{.list*}", "X.red.green.blue"},
+		{"X{.keys}", "X.semi,%3B,dot,.,comma,%2C"},
+		{"X{.keys*}", "X.semi=%3B.dot=..comma=%2C"},
+		{"X{.empty_keys}", "X"},
+		{"X{.empty_keys*}", "X"},
+		// 3.2.6 Path Segment Expansion
+		{"{/who}", "/fred"},
+		{"{/who,who}", "/fred/fred"},
+		{"{/half,who}", "/50%25/fred"},
+		{"{/who,dub}", "/fred/me%2Ftoo"},
+		{"{/var}", "/value"},
+		{"{/var,empty}", "/value/"},
+		{"{/var,undef}", "/value"},
+		{"{/var,x}/here", "/value/1024/here"},
+		{"{/var:1,var}", "/v/value"},
+		{"{/list}", "/red,green,blue"},
+		{"{/list*}", "/red/green/blue"},
+		{"{/list*,path:4}", "/red/green/blue/%2Ffoo"},
+		{"{/keys}", "/semi,%3B,dot,.,comma,%2C"},
+		{"{/keys*}", "/semi=%3B/dot=./comma=%2C"},
+		// 3.2.7 Path-Style Parameter Expansion
+		{"{;who}", ";who=fred"},
+		{"{;half}", ";half=50%25"},
+		{"{;empty}", ";empty"},
+		{"{;v,empty,who}", ";v=6;empty;who=fred"},
+		{"{;v,bar,who}", ";v=6;who=fred"},
+		{"{;x,y}", ";x=1024;y=768"},
+		{"{;x,y,empty}", ";x=1024;y=768;empty"},
+		{"{;x,y,undef}", ";x=1024;y=768"},
+		{"{;hello:5}", ";hello=Hello"},
+		{"{;list}", ";list=red,green,blue"},
+		{"{;list*}", ";list=red;list=green;list=blue"},
+		{"{;keys}", ";keys=semi,%3B,dot,.,comma,%2C"},
+		{"{;keys*}", ";semi=%3B;dot=.;comma=%2C"},
+		// 3.2.8 Form-Style Query Expansion
+		{"{?who}", "?who=fred"},
+		{"{?half}", "?half=50%25"},
+		{"{?x,y}", "?x=1024&y=768"},
+		{"{?x,y,empty}", "?x=1024&y=768&empty="},
+		{"{?x,y,undef}", "?x=1024&y=768"},
+		{"{?var:3}", "?var=val"},
+		{"{?list}", "?list=red,green,blue"},
+		{"{?list*}", "?list=red&list=green&list=blue"},
+		{"{?keys}", "?keys=semi,%3B,dot,.,comma,%2C"},
+		{"{?keys*}", "?semi=%3B&dot=.&comma=%2C"},
+		// 3.2.9 Form-Style Query Continuation
+		{"{&who}", "&who=fred"},
+		{"{&half}", "&half=50%25"},
+		{"?fixed=yes{&x}", "?fixed=yes&x=1024"},
+		{"{&x,y,empty}", "&x=1024&y=768&empty="},
+		{"{&var:3}", "&var=val"},
+		{"{&list}", "&list=red,green,blue"},
+		{"{&list*}", "&list=red&list=green&list=blue"},
+		{"{&keys}", "&keys=semi,%3B,dot,.,comma,%2C"},
+		{"{&keys*}", "&semi=%3B&dot=.&comma=%2C"},
+		// Literals are encoded, and pct-encoded triplets preserved
+		{"/a b/{var}", "/a%20b/value"},
+		{"/a%20b/{var}", "/a%20b/value"},
+	}
+	for idx, test := range tests {
+		tmpl, err := New(test.template)
+		if err != nil {
+			t.Fatalf("%d: (%s) %q", idx, test.template, err)
+		}
+		actual, err := tmpl.Expand(rfcValues)
+		if err != nil {
+			t.Errorf("%d: (%s) %q", idx, test.template, err)
+		}
+		if actual != test.expected {
+			t.Errorf("%d: (%s) expected '%s', got '%s'", idx, test.template, test.expected, actual)
+		}
+	}
+}
+
+func TestExpandValues(t *testing.T) {
+	tests := []struct {
+		template string
+		values   Values
+		expected string
+	}{
+		{"{/id}", Values{"id": 42}, "/42"},
+		{"{?keys*}", Values{"keys": map[string]string{"b": "2", "a": "1"}}, "?a=1&b=2"},
+		{"{?list}", Values{"list": []string{}}, ""},
+		{"{hello:5}", Values{"hello": "héllo wörld"}, "h%C3%A9llo"},
+	}
+	for idx, test := range tests {
+		actual, err := MustNew(test.template).Expand(test.values)
+		if err != nil {
+			t.Fatalf("%d: %q", idx, err)
+		}
+		if actual != test.expected {
+			t.Errorf("%d: expected '%s', got '%s'", idx, test.expected, actual)
+		}
+	}
+}
+
+func TestExpandErrors(t *testing.T) {
+	tests := []struct {
+		template, expected string
+	}{
+		{"{list:3}", "Error expanding URI template: prefix applied to list 'list'"},
+		{"{keys:3}", "Error expanding URI template: prefix applied to associative array 'keys'"},
+	}
+	for idx, test := range tests {
+		_, err := MustNew(test.template).Expand(rfcValues)
+		if err == nil || err.Error() != test.expected {
+			t.Errorf("%d: expected '%s', got '%v'", idx, test.expected, err)
+		}
+	}
+}
+
+func TestNewErrors(t *testing.T) {
+	tests := []struct {
+		template, expected string
+	}{
+		{"{", "Error parsing URI template: unclosed expression at 0"},
+		{"/a/{b", "Error parsing URI template: unclosed expression at 3"},
+		{"/a}", "Error parsing URI template: unexpected '}' at 2"},
+		{"{}", "Error parsing URI template: empty expression"},
+		{"{=a}", "Error parsing URI template: reserved operator '='"},
+		{"{|a}", "Error parsing URI template: reserved operator '|'"},
+		{"{a b}", "Error parsing URI template: invalid variable name 'a b'"},
+		{"{a..b}", "Error parsing URI template: invalid variable name 'a..b'"},
+		{"{.a.}", "Error parsing URI template: invalid variable name 'a.'"},
+		{"{a,}", "Error parsing URI template: invalid variable name ''"},
+		{"{a%2}", "Error parsing URI template: invalid variable name 'a%2'"},
+		{"{a:0}", "Error parsing URI template: invalid prefix 'a:0'"},
+		{"{a:10000}", "Error parsing URI template: invalid prefix 'a:10000'"},
+		{"{a:b}", "Error parsing URI template: invalid prefix 'a:b'"},
+	}
+	for idx, test := range tests {
+		_, err := New(test.template)
+		if err == nil || err.Error() != test.expected {
+			t.Errorf("%d: expected '%s', got '%v'", idx, test.expected, err)
+		}
+	}
+}
+
+func TestMustNewPanics(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("expected MustNew to panic")
+		}
+	}()
+	MustNew("{")
+}
+
+func TestNames(t *testing.T) {
+	tmpl := MustNew("/users/{user}/repos{/repo,user}{?page,per_page}{&a.b%20c}")
+	expected := []string{"user", "repo", "page", "per_page", "a.b%20c"}
+	if actual := tmpl.Names(); !reflect.DeepEqual(expected, actual) {
+		t.Errorf("expected %v, got %v", expected, actual)
+	}
+	if actual := tmpl.String(); actual != "/users/{user}/repos{/repo,user}{?page,per_page}{&a.b%20c}" {
+		t.Errorf("expected the original template, got '%s'", actual)
+	}
+}